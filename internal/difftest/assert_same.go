@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
 
-func AssertSame[T any](t *testing.T, msg string, want, got T) {
+func AssertSame[T any](t testing.TB, msg string, want, got T) {
 	t.Helper()
 	d := diff(want, got)
 	if d != "" {
@@ -19,7 +21,7 @@ func AssertSame[T any](t *testing.T, msg string, want, got T) {
 //golint:ignore:asciicheck
 func diff(a, b any) string {
 	switch x := a.(type) {
-	case bool, int8, int16, int32, int64, uint8, uint16, uint32, uint64, float32, float64:
+	case bool, int, int8, int16, int32, int64, uint8, uint16, uint32, uint64, float32, float64:
 		return diffString(fmt.Sprint(a), fmt.Sprint(b))
 	case []bool:
 		y, _ := b.([]bool)
@@ -35,7 +37,7 @@ func diff(a, b any) string {
 		return diffSlices(x, y)
 	case []string:
 		y, _ := b.([]string)
-		return diffSlices(x, y)
+		return diffLines(x, y)
 	case []uint64:
 		y, _ := b.([]uint64)
 		return diffSlices(x, y)
@@ -71,6 +73,46 @@ func diffSlices[T any](a []T, b []T) string {
 	return diffString(string(aOut), string(bOut))
 }
 
+// diffLines returns a diff of the strings with one string per line, prefixed
+// by "-" if only in a, "+" if only in b, or " " if in both.
+func diffLines(a, b []string) string {
+	if slices.Equal(a, b) {
+		return ""
+	}
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	sb := strings.Builder{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&sb, "  %q\n", a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&sb, "- %q\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&sb, "+ %q\n", b[j])
+			j++
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
 func diffString(a, b string) string {
 	if a != b {
 		return fmt.Sprintf("- %s\n+ %s", a, b)
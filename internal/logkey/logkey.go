@@ -0,0 +1,28 @@
+// Package logkey defines the keys of the attrs that the log package adds to
+// records, for the handlers in other packages that render them specially.
+// The log package exports the keys as TraceIDKey, SpanIDKey,
+// TraceSampledKey, and ReadyKey.
+package logkey
+
+import "log/slog"
+
+// Keys for the trace correlation attrs added to records logged with a
+// context containing a span.
+const (
+	TraceID      = "trace_id"
+	SpanID       = "span_id"
+	TraceSampled = "trace_sampled"
+)
+
+// Ready is the key of the attr marking records logged by log.Ready.
+const Ready = "ready"
+
+// IsTrace reports whether the key is a trace correlation key.
+func IsTrace(key string) bool {
+	return key == TraceID || key == SpanID || key == TraceSampled
+}
+
+// IsReady reports whether the attr marks a record logged by log.Ready.
+func IsReady(attr slog.Attr) bool {
+	return attr.Key == Ready && attr.Value.Kind() == slog.KindBool && attr.Value.Bool()
+}
@@ -0,0 +1,161 @@
+// Package pbwire encodes and decodes the protobuf wire format, enough to
+// export telemetry without depending on a protobuf library.
+// https://protobuf.dev/programming-guides/encoding/
+package pbwire
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Type is a protobuf wire type.
+type Type uint8
+
+const (
+	VarintType  Type = 0
+	Fixed64Type Type = 1
+	BytesType   Type = 2
+	Fixed32Type Type = 5
+)
+
+// AppendTag appends the tag for a field number and wire type.
+func AppendTag(b []byte, num int, typ Type) []byte {
+	return binary.AppendUvarint(b, uint64(num)<<3|uint64(typ)) //nolint:gosec // field numbers are positive
+}
+
+// AppendVarint appends a varint field.
+func AppendVarint(b []byte, num int, v uint64) []byte {
+	b = AppendTag(b, num, VarintType)
+	return binary.AppendUvarint(b, v)
+}
+
+// AppendInt64 appends an int64 field, encoded as a varint of the two's
+// complement.
+func AppendInt64(b []byte, num int, v int64) []byte {
+	return AppendVarint(b, num, uint64(v)) //nolint:gosec // two's complement
+}
+
+// AppendBool appends a bool field.
+func AppendBool(b []byte, num int, v bool) []byte {
+	if v {
+		return AppendVarint(b, num, 1)
+	}
+	return AppendVarint(b, num, 0)
+}
+
+// AppendFixed64 appends a fixed64 field.
+func AppendFixed64(b []byte, num int, v uint64) []byte {
+	b = AppendTag(b, num, Fixed64Type)
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+// AppendFixed32 appends a fixed32 field.
+func AppendFixed32(b []byte, num int, v uint32) []byte {
+	b = AppendTag(b, num, Fixed32Type)
+	return binary.LittleEndian.AppendUint32(b, v)
+}
+
+// AppendDouble appends a double field.
+func AppendDouble(b []byte, num int, v float64) []byte {
+	return AppendFixed64(b, num, math.Float64bits(v))
+}
+
+// AppendString appends a string field.
+func AppendString(b []byte, num int, s string) []byte {
+	b = AppendTag(b, num, BytesType)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// AppendBytes appends a bytes field.
+func AppendBytes(b []byte, num int, v []byte) []byte {
+	b = AppendTag(b, num, BytesType)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// AppendMessageHeader appends the tag and length of an embedded message
+// field with n bytes of fields. Append the fields after it. Unlike
+// BeginMessage, never moves the fields, but needs the length up front.
+func AppendMessageHeader(b []byte, num, n int) []byte {
+	b = AppendTag(b, num, BytesType)
+	return binary.AppendUvarint(b, uint64(n)) //nolint:gosec // lengths are non-negative
+}
+
+// BeginMessage appends the tag of an embedded message field and reserves
+// room for its length. Append the message fields, then call EndMessage with
+// the returned position.
+func BeginMessage(b []byte, num int) ([]byte, int) {
+	b = AppendTag(b, num, BytesType)
+	return append(b, 0), len(b)
+}
+
+// EndMessage writes the length of the embedded message started at pos by
+// BeginMessage.
+func EndMessage(b []byte, pos int) []byte {
+	n := len(b) - pos - 1
+	if n < 0x80 {
+		b[pos] = byte(n)
+		return b
+	}
+	// Shift the message to make room for a longer varint.
+	var lenBuf [binary.MaxVarintLen64]byte
+	lenBytes := binary.PutUvarint(lenBuf[:], uint64(n))
+	b = append(b, lenBuf[:lenBytes-1]...)
+	copy(b[pos+lenBytes:], b[pos+1:pos+1+n])
+	copy(b[pos:], lenBuf[:lenBytes])
+	return b
+}
+
+var errTruncated = errors.New("truncated protobuf field")
+
+// Field is a decoded protobuf field.
+type Field struct {
+	Num  int
+	Type Type
+	// Varint is the value of varint, fixed32, and fixed64 fields.
+	Varint uint64
+	// Bytes is the value of bytes fields, like strings and messages.
+	Bytes []byte
+}
+
+// ConsumeField decodes the field at the start of b and returns the
+// remaining bytes.
+func ConsumeField(b []byte) (Field, []byte, error) {
+	tag, n := binary.Uvarint(b)
+	if n <= 0 {
+		return Field{}, nil, errTruncated
+	}
+	b = b[n:]
+	f := Field{Num: int(tag >> 3), Type: Type(tag & 7)} //nolint:gosec // field numbers fit in int
+	switch f.Type {
+	case VarintType:
+		f.Varint, n = binary.Uvarint(b)
+		if n <= 0 {
+			return Field{}, nil, errTruncated
+		}
+		return f, b[n:], nil
+	case Fixed64Type:
+		if len(b) < 8 {
+			return Field{}, nil, errTruncated
+		}
+		f.Varint = binary.LittleEndian.Uint64(b)
+		return f, b[8:], nil
+	case Fixed32Type:
+		if len(b) < 4 {
+			return Field{}, nil, errTruncated
+		}
+		f.Varint = uint64(binary.LittleEndian.Uint32(b))
+		return f, b[4:], nil
+	case BytesType:
+		size, n := binary.Uvarint(b)
+		if n <= 0 || uint64(len(b)-n) < size {
+			return Field{}, nil, errTruncated
+		}
+		f.Bytes = b[n : n+int(size)]   //nolint:gosec // checked against len(b)
+		return f, b[n+int(size):], nil //nolint:gosec // checked against len(b)
+	default:
+		return Field{}, nil, errors.New("unsupported protobuf wire type")
+	}
+}
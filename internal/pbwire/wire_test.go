@@ -0,0 +1,76 @@
+package pbwire
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestAppend_Consume(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	var b []byte
+	b = AppendVarint(b, 1, 150)
+	b = AppendInt64(b, 2, -1)
+	b = AppendBool(b, 3, true)
+	b = AppendFixed64(b, 4, 0x0102030405060708)
+	b = AppendFixed32(b, 5, 0x01020304)
+	b = AppendDouble(b, 6, 1.5)
+	b = AppendString(b, 7, "hi")
+	b = AppendBytes(b, 8, []byte{0xff})
+	b, pos := BeginMessage(b, 9)
+	b = AppendString(b, 1, long)
+	b = EndMessage(b, pos)
+	b, pos = BeginMessage(b, 10)
+	b = EndMessage(b, pos)
+	inner := AppendString(nil, 1, long)
+	b = AppendMessageHeader(b, 11, len(inner))
+	b = append(b, inner...)
+
+	var got []Field
+	for len(b) > 0 {
+		var f Field
+		var err error
+		f, b, err = ConsumeField(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, f)
+	}
+
+	difftest.AssertSame(t, "field count", 11, len(got))
+	difftest.AssertSame(t, "varint", uint64(150), got[0].Varint)
+	difftest.AssertSame(t, "int64", int64(-1), int64(got[1].Varint)) //nolint:gosec // two's complement
+	difftest.AssertSame(t, "bool", uint64(1), got[2].Varint)
+	difftest.AssertSame(t, "fixed64", uint64(0x0102030405060708), got[3].Varint)
+	difftest.AssertSame(t, "fixed32", uint64(0x01020304), got[4].Varint)
+	difftest.AssertSame(t, "double", 1.5, math.Float64frombits(got[5].Varint))
+	difftest.AssertSame(t, "string", "hi", string(got[6].Bytes))
+	difftest.AssertSame(t, "bytes", "\xff", string(got[7].Bytes))
+	difftest.AssertSame(t, "empty message", 0, len(got[9].Bytes))
+
+	for _, i := range []int{8, 10} {
+		f, rest, err := ConsumeField(got[i].Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		difftest.AssertSame(t, "message rest", 0, len(rest))
+		difftest.AssertSame(t, "message field", 1, f.Num)
+		difftest.AssertSame(t, "long string", long, string(f.Bytes))
+	}
+}
+
+func TestConsumeField_Truncated(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x08},       // varint without value
+		{0x09, 0x01}, // short fixed64
+		{0x0d, 0x01}, // short fixed32
+		{0x0a, 0x05, 'a'},
+		{0x0b}, // unsupported group type
+	} {
+		if _, _, err := ConsumeField(b); err == nil {
+			t.Errorf("ConsumeField(%x): want error", b)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build race
+
+// Package race reports whether the race detector is enabled, for tests that
+// depend on allocations, which the race detector adds.
+package race
+
+// Enabled is true if the race detector is enabled.
+const Enabled = true
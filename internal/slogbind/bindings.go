@@ -0,0 +1,47 @@
+// Package slogbind tracks the groups and attrs bound to a slog.Handler by
+// WithGroup and WithAttrs, for handlers that pass records on with the bound
+// attrs added to the record attrs.
+package slogbind
+
+import (
+	"log/slog"
+	"slices"
+)
+
+// Bindings are the groups and attrs bound by WithGroup and WithAttrs,
+// outermost first. The zero value has no bindings.
+type Bindings []groupOrAttrs
+
+// groupOrAttrs is a group name or attrs bound by WithGroup or WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// WithAttrs returns the bindings with the attrs bound innermost. Doesn't
+// modify b, so handlers derived from the same handler don't share attrs.
+func (b Bindings) WithAttrs(attrs []slog.Attr) Bindings {
+	return append(slices.Clip(b), groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup returns the bindings with the group bound innermost. Doesn't
+// modify b.
+func (b Bindings) WithGroup(name string) Bindings {
+	return append(slices.Clip(b), groupOrAttrs{group: name})
+}
+
+// Nest returns the record attrs nested in the bound groups, after the attrs
+// bound in each group. Omits groups without attrs, like the slog handlers.
+// May reuse attrs.
+func (b Bindings) Nest(attrs []slog.Attr) []slog.Attr {
+	for i := len(b) - 1; i >= 0; i-- {
+		goa := b[i]
+		switch {
+		case goa.group == "":
+			attrs = slices.Concat(goa.attrs, attrs)
+		case len(attrs) > 0:
+			attrs = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,43 @@
+package slogbind
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestBindings_Nest(t *testing.T) {
+	attrs := []slog.Attr{slog.Int("c", 3)}
+	tests := []struct {
+		name  string
+		binds Bindings
+		attrs []slog.Attr
+		want  string
+	}{
+		{name: "none", attrs: attrs, want: "[c=3]"},
+		{name: "attrs", binds: Bindings{}.WithAttrs([]slog.Attr{slog.Int("a", 1)}), attrs: attrs, want: "[a=1 c=3]"},
+		{name: "group", binds: Bindings{}.WithGroup("g"), attrs: attrs, want: "[g=[c=3]]"},
+		{
+			name:  "attrs in group",
+			binds: Bindings{}.WithAttrs([]slog.Attr{slog.Int("a", 1)}).WithGroup("g").WithAttrs([]slog.Attr{slog.Int("b", 2)}),
+			attrs: attrs,
+			want:  "[a=1 g=[b=2 c=3]]",
+		},
+		{name: "empty group", binds: Bindings{}.WithAttrs([]slog.Attr{slog.Int("a", 1)}).WithGroup("g"), want: "[a=1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slog.GroupValue(tt.binds.Nest(tt.attrs)...).String()
+			difftest.AssertSame(t, "nested attrs", tt.want, got)
+		})
+	}
+}
+
+func TestBindings_WithAttrs_NoAlias(t *testing.T) {
+	base := Bindings{}.WithGroup("g").WithGroup("h")[:1] // spare capacity
+	a := base.WithAttrs([]slog.Attr{slog.Int("a", 1)})
+	b := base.WithAttrs([]slog.Attr{slog.Int("b", 2)})
+	difftest.AssertSame(t, "a", "[g=[a=1]]", slog.GroupValue(a.Nest(nil)...).String())
+	difftest.AssertSame(t, "b", "[g=[b=2]]", slog.GroupValue(b.Nest(nil)...).String())
+}
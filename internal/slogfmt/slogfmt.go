@@ -0,0 +1,31 @@
+// Package slogfmt formats values shared by the slog handlers, like record
+// times and sources.
+package slogfmt
+
+import (
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// AppendRFC3339Millis appends the time in RFC3339 format with millisecond
+// precision.
+func AppendRFC3339Millis(b []byte, t time.Time) []byte {
+	// Format according to time.RFC3339Nano since it is highly optimized,
+	// but truncate it to use millisecond resolution.
+	// Unfortunately, that format trims trailing 0s, so add 1/10 millisecond
+	// to guarantee that there are exactly 4 digits after the period.
+	const prefixLen = len("2006-01-02T15:04:05.000")
+	n := len(b)
+	t = t.Truncate(time.Millisecond).Add(time.Millisecond / 10)
+	b = t.AppendFormat(b, time.RFC3339Nano)
+	b = append(b[:n+prefixLen], b[n+prefixLen+1:]...) // drop the 4th digit
+	return b
+}
+
+// RecordSource returns the source location of the record's PC.
+func RecordSource(r slog.Record) *slog.Source {
+	frames := runtime.CallersFrames([]uintptr{r.PC})
+	f, _ := frames.Next()
+	return &slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+}
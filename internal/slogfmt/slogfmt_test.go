@@ -0,0 +1,23 @@
+package slogfmt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestAppendRFC3339Millis(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC), "2024-01-01T12:00:00.000Z"},
+		{time.Date(2024, time.January, 1, 12, 0, 0, 120_999_999, time.UTC), "2024-01-01T12:00:00.120Z"},
+		{time.Date(2024, time.January, 1, 12, 0, 0, 0, time.FixedZone("", -5*60*60)), "2024-01-01T12:00:00.000-05:00"},
+	}
+	for _, tt := range tests {
+		got := string(AppendRFC3339Millis([]byte("t="), tt.t))
+		difftest.AssertSame(t, "time mismatch", "t="+tt.want, got)
+	}
+}
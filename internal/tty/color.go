@@ -1,12 +1,9 @@
 package tty
 
 import (
-	"os"
+	"strconv"
 )
 
-//nolint:gochecknoglobals
-var noColor = os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb"
-
 // Foreground colors.
 //
 //goland:noinspection GoUnusedConst
@@ -34,23 +31,232 @@ var codes = [8]string{
 	"\x1b[37m",
 }
 
-// Color represents a text color.
+const resetCode = "\x1b[0m"
+
+// Color represents a basic text color.
 type Color uint8
 
+// Code returns the escape sequence for the color, regardless of the terminal
+// mode. Prefer Mode.AppendCode, which respects the mode.
 func (c Color) Code() string {
-	if noColor {
-		return ""
-	}
 	if c < 30 || c > 37 {
-		return "\x1b[0m"
+		return resetCode
 	}
 	return codes[c-30]
 }
 
-// Add adds the coloring to the given string.
+// Add adds the coloring to the given string, regardless of the terminal mode.
 func (c Color) Add(s string) string {
-	if noColor || c == 0 {
+	if c == 0 {
 		return s
 	}
 	return c.Code() + s + Reset.Code()
 }
+
+// Attr is a set of text attributes.
+type Attr uint8
+
+const (
+	Bold Attr = 1 << iota
+	Dim
+)
+
+type colorKind uint8
+
+const (
+	kindDefault colorKind = iota
+	kindBasic
+	kind256
+	kindRGB
+)
+
+// Style is a foreground color with text attributes. The zero Style is the
+// terminal default.
+type Style struct {
+	kind  colorKind
+	color uint32 // the Color, 256-color index, or 0xRRGGBB, depending on kind
+	attrs Attr
+}
+
+// Fg returns a style with a basic foreground color.
+func Fg(c Color) Style {
+	if c < 30 || c > 37 {
+		return Style{}
+	}
+	return Style{kind: kindBasic, color: uint32(c)}
+}
+
+// Fg256 returns a style with a foreground color from the 256-color palette.
+func Fg256(n uint8) Style {
+	return Style{kind: kind256, color: uint32(n)}
+}
+
+// FgRGB returns a style with a 24-bit foreground color.
+func FgRGB(r, g, b uint8) Style {
+	return Style{kind: kindRGB, color: uint32(r)<<16 | uint32(g)<<8 | uint32(b)}
+}
+
+// With returns the style with the text attributes added.
+func (s Style) With(a Attr) Style {
+	s.attrs |= a
+	return s
+}
+
+// Mode is the color capability of a terminal.
+type Mode uint8
+
+const (
+	ModeNone      Mode = iota // no escape sequences
+	ModeBasic                 // the 16 standard colors
+	Mode256                   // the 256-color palette
+	ModeTrueColor             // 24-bit colors
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeNone:
+		return "none"
+	case ModeBasic:
+		return "basic"
+	case Mode256:
+		return "256"
+	case ModeTrueColor:
+		return "truecolor"
+	default:
+		return "Mode(" + strconv.Itoa(int(m)) + ")"
+	}
+}
+
+// AppendCode appends the escape sequence that sets the style. Downgrades
+// colors the mode doesn't support to the closest supported color. Appends
+// nothing for ModeNone or the zero Style.
+func (m Mode) AppendCode(b []byte, s Style) []byte {
+	if m == ModeNone || s == (Style{}) {
+		return b
+	}
+	b = append(b, "\x1b["...)
+	n := len(b)
+	if s.attrs&Bold != 0 {
+		b = append(b, "1;"...)
+	}
+	if s.attrs&Dim != 0 {
+		b = append(b, "2;"...)
+	}
+	kind, color := s.kind, s.color
+	if kind == kindRGB && m < ModeTrueColor {
+		kind, color = kind256, uint32(rgbTo256(uint8(color>>16), uint8(color>>8), uint8(color)))
+	}
+	if kind == kind256 && m < Mode256 {
+		kind, color = kindBasic, uint32(ansi256ToBasic(uint8(color)))
+	}
+	switch kind {
+	case kindDefault:
+	case kindBasic:
+		b = strconv.AppendUint(b, uint64(color), 10)
+		b = append(b, ';')
+	case kind256:
+		b = append(b, "38;5;"...)
+		b = strconv.AppendUint(b, uint64(color), 10)
+		b = append(b, ';')
+	case kindRGB:
+		b = append(b, "38;2;"...)
+		b = strconv.AppendUint(b, uint64(uint8(color>>16)), 10)
+		b = append(b, ';')
+		b = strconv.AppendUint(b, uint64(uint8(color>>8)), 10)
+		b = append(b, ';')
+		b = strconv.AppendUint(b, uint64(uint8(color)), 10)
+		b = append(b, ';')
+	}
+	if len(b) == n {
+		return b[:n-len("\x1b[")]
+	}
+	b[len(b)-1] = 'm' // replace the trailing semicolon
+	return b
+}
+
+// AppendReset appends the escape sequence that resets the style, or nothing
+// for ModeNone.
+func (m Mode) AppendReset(b []byte) []byte {
+	if m == ModeNone {
+		return b
+	}
+	return append(b, resetCode...)
+}
+
+// Wrap returns s styled with the style, followed by a reset. Returns s
+// unchanged for ModeNone.
+func (m Mode) Wrap(style Style, s string) string {
+	if m == ModeNone || style == (Style{}) {
+		return s
+	}
+	b := make([]byte, 0, len(s)+24)
+	b = m.AppendCode(b, style)
+	b = append(b, s...)
+	b = m.AppendReset(b)
+	return string(b)
+}
+
+// rgbTo256 returns the closest color in the 256-color palette, using the
+// grayscale ramp for grays and the 6x6x6 color cube otherwise.
+func rgbTo256(r, g, b uint8) uint8 {
+	if r == g && g == b {
+		switch {
+		case r < 8:
+			return 16
+		case r > 248:
+			return 231
+		default:
+			return 232 + uint8((int(r)-8)*24/247)
+		}
+	}
+	return 16 + 36*cubeLevel(r) + 6*cubeLevel(g) + cubeLevel(b)
+}
+
+// cubeLevel returns the closest of the 6 levels of a 256-color cube channel:
+// 0, 95, 135, 175, 215, 255.
+func cubeLevel(v uint8) uint8 {
+	switch {
+	case v < 48:
+		return 0
+	case v < 115:
+		return 1
+	default:
+		return (v - 35) / 40
+	}
+}
+
+// ansi256ToBasic returns the closest of the 16 basic colors as an SGR
+// parameter: 30-37 for normal colors and 90-97 for bright colors.
+func ansi256ToBasic(n uint8) uint8 {
+	switch {
+	case n < 8:
+		return 30 + n
+	case n < 16:
+		return 90 + n - 8
+	case n < 232:
+		// Map each cube channel to on or off and combine as ANSI color bits:
+		// red is 1, green is 2, and blue is 4.
+		n -= 16
+		r, g, b := n/36, n/6%6, n%6
+		c := uint8(0)
+		if r >= 3 {
+			c |= 1
+		}
+		if g >= 3 {
+			c |= 2
+		}
+		if b >= 3 {
+			c |= 4
+		}
+		return 30 + c
+	default:
+		switch gray := n - 232; {
+		case gray < 8:
+			return 30 // black
+		case gray < 16:
+			return 90 // bright black, or dark gray
+		default:
+			return 37 // white, or light gray
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package tty
+
+import (
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestMode_AppendCode(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  Mode
+		style Style
+		want  string
+	}{
+		{"none", ModeNone, Fg(Red), ""},
+		{"zero style", ModeTrueColor, Style{}, ""},
+		{"basic", ModeBasic, Fg(Red), "\x1b[31m"},
+		{"bold", ModeBasic, Fg(Red).With(Bold), "\x1b[1;31m"},
+		{"bold dim", ModeBasic, Fg(Cyan).With(Bold | Dim), "\x1b[1;2;36m"},
+		{"attr only", ModeBasic, Style{}.With(Dim), "\x1b[2m"},
+		{"reset color", ModeBasic, Fg(Reset), ""},
+		{"256", Mode256, Fg256(208), "\x1b[38;5;208m"},
+		{"256 in truecolor", ModeTrueColor, Fg256(208), "\x1b[38;5;208m"},
+		{"256 to basic", ModeBasic, Fg256(196), "\x1b[31m"},
+		{"256 bright to basic", ModeBasic, Fg256(12), "\x1b[94m"},
+		{"256 gray to basic", ModeBasic, Fg256(240), "\x1b[90m"},
+		{"rgb", ModeTrueColor, FgRGB(255, 128, 0).With(Bold), "\x1b[1;38;2;255;128;0m"},
+		{"rgb to 256", Mode256, FgRGB(255, 135, 0), "\x1b[38;5;208m"},
+		{"rgb gray to 256", Mode256, FgRGB(128, 128, 128), "\x1b[38;5;243m"},
+		{"rgb to basic", ModeBasic, FgRGB(0, 255, 0), "\x1b[32m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(tt.mode.AppendCode([]byte("x"), tt.style))
+			difftest.AssertSame(t, "AppendCode mismatch", "x"+tt.want, got)
+		})
+	}
+}
+
+func TestMode_Wrap(t *testing.T) {
+	difftest.AssertSame(t, "basic", Blue.Add("info"), ModeBasic.Wrap(Fg(Blue), "info"))
+	difftest.AssertSame(t, "none", "info", ModeNone.Wrap(Fg(Blue), "info"))
+}
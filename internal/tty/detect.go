@@ -0,0 +1,67 @@
+package tty
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// IsTerminal reports whether w writes to a terminal. Only recognizes writers
+// with a file descriptor, like *os.File.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Fd() uintptr })
+	if !ok {
+		return false
+	}
+	return isTerminal(f.Fd())
+}
+
+// DetectMode returns the color mode for output to w based on whether w is a
+// terminal and on the environment:
+//
+//   - NO_COLOR, if non-empty, or FORCE_COLOR=0 disables color.
+//   - FORCE_COLOR or CLICOLOR_FORCE, if non-empty and not "0", enables color
+//     even if w isn't a terminal. FORCE_COLOR of 2 or 3 selects 256 colors or
+//     truecolor.
+//   - TERM=dumb disables color unless forced.
+//   - COLORTERM of truecolor or 24bit selects truecolor. A TERM containing
+//     256color selects 256 colors.
+func DetectMode(w io.Writer) Mode {
+	return detectMode(IsTerminal(w), os.Getenv)
+}
+
+func detectMode(isTerm bool, getenv func(string) string) Mode {
+	if getenv("NO_COLOR") != "" {
+		return ModeNone
+	}
+
+	forced := ModeNone
+	switch force := getenv("FORCE_COLOR"); force {
+	case "":
+		if c := getenv("CLICOLOR_FORCE"); c != "" && c != "0" {
+			forced = ModeBasic
+		}
+	case "0", "false":
+		return ModeNone
+	case "2":
+		forced = Mode256
+	case "3":
+		forced = ModeTrueColor
+	default:
+		forced = ModeBasic
+	}
+
+	term := getenv("TERM")
+	if forced == ModeNone && (!isTerm || term == "dumb") {
+		return ModeNone
+	}
+
+	mode := ModeBasic
+	switch {
+	case getenv("COLORTERM") == "truecolor" || getenv("COLORTERM") == "24bit":
+		mode = ModeTrueColor
+	case strings.Contains(term, "256color"):
+		mode = Mode256
+	}
+	return max(mode, forced)
+}
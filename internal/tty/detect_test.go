@@ -0,0 +1,55 @@
+package tty
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestDetectMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		isTerm bool
+		env    map[string]string
+		want   Mode
+	}{
+		{"not terminal", false, nil, ModeNone},
+		{"terminal", true, nil, ModeBasic},
+		{"terminal 256", true, map[string]string{"TERM": "xterm-256color"}, Mode256},
+		{"terminal truecolor", true, map[string]string{"TERM": "xterm-256color", "COLORTERM": "truecolor"}, ModeTrueColor},
+		{"terminal 24bit", true, map[string]string{"COLORTERM": "24bit"}, ModeTrueColor},
+		{"dumb terminal", true, map[string]string{"TERM": "dumb"}, ModeNone},
+		{"no color", true, map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, ModeNone},
+		{"empty no color", true, map[string]string{"NO_COLOR": ""}, ModeBasic},
+		{"force color", false, map[string]string{"FORCE_COLOR": "1"}, ModeBasic},
+		{"force color true", false, map[string]string{"FORCE_COLOR": "true"}, ModeBasic},
+		{"force color 256", false, map[string]string{"FORCE_COLOR": "2"}, Mode256},
+		{"force color truecolor", false, map[string]string{"FORCE_COLOR": "3"}, ModeTrueColor},
+		{"force color with colorterm", false, map[string]string{"FORCE_COLOR": "1", "COLORTERM": "truecolor"}, ModeTrueColor},
+		{"force color dumb", false, map[string]string{"FORCE_COLOR": "1", "TERM": "dumb"}, ModeBasic},
+		{"force color off", true, map[string]string{"FORCE_COLOR": "0"}, ModeNone},
+		{"clicolor force", false, map[string]string{"CLICOLOR_FORCE": "1"}, ModeBasic},
+		{"clicolor force off", false, map[string]string{"CLICOLOR_FORCE": "0"}, ModeNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectMode(tt.isTerm, func(key string) string { return tt.env[key] })
+			difftest.AssertSame(t, "detectMode mismatch", tt.want.String(), got.String())
+		})
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "tty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	difftest.AssertSame(t, "file", false, IsTerminal(f))
+	difftest.AssertSame(t, "non-file", false, IsTerminal(&nopWriter{}))
+}
+
+type nopWriter struct{}
+
+func (*nopWriter) Write(p []byte) (int, error) { return len(p), nil }
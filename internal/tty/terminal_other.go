@@ -0,0 +1,9 @@
+//go:build !linux
+
+package tty
+
+// isTerminal reports false since terminal detection is only implemented on
+// Linux. Use FORCE_COLOR to enable color elsewhere.
+func isTerminal(uintptr) bool {
+	return false
+}
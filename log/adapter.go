@@ -0,0 +1,159 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	stdlog "log"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/jschaf/observe/internal/slogbind"
+)
+
+// rootLogger logs like the package-level functions, without a name.
+//
+//nolint:gochecknoglobals
+var rootLogger = &Logger{}
+
+// Slog returns a slog.Logger for libraries that take one. Like the logger's
+// own methods, passes records to the handler of slog.Default at the time of
+// logging, enabled by the level for the logger name in DefaultLevels, and
+// adds the logger attrs and the trace correlation attrs from the context.
+// Don't install the returned logger as slog.Default, which would loop.
+func (l *Logger) Slog() *slog.Logger {
+	return slog.New(&slogHandler{l: l})
+}
+
+// StdLogger returns a standard library logger for libraries that take one,
+// like http.Server.ErrorLog, that logs each line at the level like the
+// logger's own methods.
+func (l *Logger) StdLogger(level slog.Level) *stdlog.Logger {
+	return stdlog.New(&stdWriter{l: l, level: level}, "", 0)
+}
+
+// RedirectStdLog redirects the output of the standard library log package,
+// like from log.Printf, to log each line at the level like Log. Clears the
+// log package flags, since records have their own time and source. Call after
+// slog.SetDefault, which also redirects the log package. While slog.Default
+// uses the initial slog handler, which writes to the log package, writes
+// lines to the previous output instead. Returns a function that restores the
+// previous output and flags.
+func RedirectStdLog(level slog.Level) (restore func()) {
+	w, flags := stdlog.Writer(), stdlog.Flags()
+	stdlog.SetOutput(&stdWriter{l: rootLogger, level: level, fallback: w})
+	stdlog.SetFlags(0)
+	return func() {
+		stdlog.SetOutput(w)
+		stdlog.SetFlags(flags)
+	}
+}
+
+// stdWriter is the output of a standard library logger that logs each line.
+type stdWriter struct {
+	l     *Logger
+	level slog.Level
+	// fallback is the output to write to while slog.Default uses the initial
+	// slog handler, if not nil.
+	fallback io.Writer
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	ctx := context.Background()
+	h := slog.Default().Handler()
+	if w.fallback != nil && isSlogDefaultHandler(h) {
+		return w.fallback.Write(p) //nolint:wrapcheck // transparent wrapper
+	}
+	if !w.l.enabled(ctx, h, w.level) {
+		return len(p), nil
+	}
+	var pcs [maxCallerDepth]uintptr
+	n := runtime.Callers(2, pcs[:]) // skip [Callers, Write]
+	pc := firstPC(pcs[:n], isStdLogOrHelper)
+	msg := string(bytes.TrimSuffix(p, []byte("\n")))
+	_ = h.Handle(ctx, newRecord(ctx, w.level, msg, pc, w.l.attrs, nil))
+	return len(p), nil
+}
+
+// isSlogDefaultHandler reports whether h is the initial handler of
+// slog.Default, which writes to the log package. Checks the type instead of
+// comparing to the handler at init, which another package may have replaced
+// with slog.SetDefault before this package's init.
+func isSlogDefaultHandler(h slog.Handler) bool {
+	t := reflect.TypeOf(h)
+	return t != nil && t.Kind() == reflect.Pointer &&
+		t.Elem().PkgPath() == "log/slog" && t.Elem().Name() == "defaultHandler"
+}
+
+// isStdLogOrHelper reports whether the pc is in the standard library log
+// package or a helper. Compares the whole package path, so packages named
+// log elsewhere, like this one, aren't skipped.
+func isStdLogOrHelper(pc uintptr) bool {
+	return funcPkgPath(funcName(pc)) == "log" || helpers.contains(pc)
+}
+
+// funcPkgPath returns the package path of a function name from
+// runtime.Frame, like "net/http" for "net/http.(*Server).Serve".
+func funcPkgPath(name string) string {
+	slash := strings.LastIndexByte(name, '/') + 1
+	dot := strings.IndexByte(name[slash:], '.')
+	if dot < 0 {
+		return ""
+	}
+	return name[:slash+dot]
+}
+
+// slogHandler is the handler of loggers from Logger.Slog.
+type slogHandler struct {
+	l     *Logger
+	binds slogbind.Bindings
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.l.Enabled(ctx, level)
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	attrs = h.binds.Nest(attrs)
+	rec := newRecord(ctx, r.Level, r.Message, slogCallerPC(r.PC), h.l.attrs, attrs)
+	rec.Time = r.Time
+	return slog.Default().Handler().Handle(ctx, rec) //nolint:wrapcheck // transparent wrapper
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return &slogHandler{l: h.l, binds: h.binds.WithAttrs(attrs)}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{l: h.l, binds: h.binds.WithGroup(name)}
+}
+
+// slogCallerPC returns the pc recorded by a slog.Logger or, if the pc is in a
+// helper, the pc of the first caller above it that is not a helper. Must be
+// called from Handle, below the caller on the stack.
+func slogCallerPC(pc uintptr) uintptr {
+	if pc == 0 || !hasHelpers.Load() || !helpers.contains(pc) {
+		return pc
+	}
+	var pcs [maxCallerDepth]uintptr
+	n := runtime.Callers(2, pcs[:]) // skip [Callers, slogCallerPC]
+	i := slices.Index(pcs[:n], pc)
+	if i < 0 {
+		return pc
+	}
+	return firstPC(pcs[i:n], helpers.contains)
+}
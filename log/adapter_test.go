@@ -0,0 +1,125 @@
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+	"log/slog"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+//nolint:gochecknoglobals
+var initialSlogDefault = slog.Default()
+
+func TestLogger_Slog(t *testing.T) {
+	rec := &recordHandler{}
+	slog.SetDefault(slog.New(rec))
+	t.Cleanup(func() { _ = defaultLevels.SetSpec("") })
+
+	l := Named("db").With(slog.String("shard", "a")).Slog()
+	l.Info("msg", "a", 1)
+	l.With("b", 2).WithGroup("g").With("c", 3).Warn("grouped", "d", 4)
+	l.WithGroup("empty").Info("empty group")
+	slogHelper(l, "helper")
+	defaultLevels.SetLevel("db", slog.LevelWarn)
+	l.Info("disabled")
+
+	difftest.AssertSame(t, "records", []string{
+		"INFO msg logger=db shard=a a=1",
+		"WARN grouped logger=db shard=a b=2 g.c=3 g.d=4",
+		"INFO empty group logger=db shard=a",
+		"INFO helper logger=db shard=a",
+	}, rec.strings())
+	const test = "github.com/jschaf/observe/log.TestLogger_Slog"
+	difftest.AssertSame(t, "sources", []string{test, test, test, test}, sourceFuncs(rec))
+}
+
+func slogHelper(l *slog.Logger, msg string) {
+	Helper()
+	l.Info(msg)
+}
+
+func TestLogger_StdLogger(t *testing.T) {
+	rec := &recordHandler{}
+	slog.SetDefault(slog.New(rec))
+
+	l := Named("http").StdLogger(slog.LevelWarn)
+	l.Printf("bad request %d", 1)
+	l.Print("multi\nline")
+
+	difftest.AssertSame(t, "records", []string{
+		"WARN bad request 1 logger=http",
+		"WARN multi\nline logger=http",
+	}, rec.strings())
+	const test = "github.com/jschaf/observe/log.TestLogger_StdLogger"
+	difftest.AssertSame(t, "sources", []string{test, test}, sourceFuncs(rec))
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	w, flags := stdlog.Writer(), stdlog.Flags()
+	t.Cleanup(func() {
+		stdlog.SetOutput(w)
+		stdlog.SetFlags(flags)
+	})
+	rec := &recordHandler{}
+	slog.SetDefault(slog.New(rec))
+	prev := stdlog.Writer()
+
+	restore := RedirectStdLog(slog.LevelInfo)
+	stdlog.Print("print")
+	stdlog.Printf("printf %d", 1)
+	stdLogHelper("helper")
+	_ = stdlog.Output(1, "output")
+	restore()
+
+	difftest.AssertSame(t, "records", []string{
+		"INFO print",
+		"INFO printf 1",
+		"INFO helper",
+		"INFO output",
+	}, rec.strings())
+	const test = "github.com/jschaf/observe/log.TestRedirectStdLog"
+	difftest.AssertSame(t, "sources", []string{test, test, test, test}, sourceFuncs(rec))
+	if stdlog.Writer() != prev {
+		t.Error("log output not restored")
+	}
+}
+
+func stdLogHelper(msg string) {
+	Helper()
+	stdlog.Print(msg)
+}
+
+func TestRedirectStdLog_InitialSlogDefault(t *testing.T) {
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	slog.SetDefault(initialSlogDefault)
+
+	// The initial slog handler writes to the log package, so write to the
+	// previous output instead of looping.
+	buf := &bytes.Buffer{}
+	w := &stdWriter{l: rootLogger, level: slog.LevelInfo, fallback: buf}
+	stdlog.New(w, "", 0).Print("msg")
+	difftest.AssertSame(t, "output", "msg\n", buf.String())
+}
+
+func TestFuncPkgPath(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "log.(*Logger).output", want: "log"},
+		{name: "log.Printf", want: "log"},
+		{name: "github.com/jschaf/observe/log.(*stdWriter).Write", want: "github.com/jschaf/observe/log"},
+		{name: "example.com/app/log.Printf", want: "example.com/app/log"},
+		{name: "log/slog.(*Logger).Info", want: "log/slog"},
+		{name: "main.main.func1", want: "main"},
+		{name: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			difftest.AssertSame(t, "package path", tt.want, funcPkgPath(tt.name))
+		})
+	}
+}
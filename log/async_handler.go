@@ -0,0 +1,242 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy is what an AsyncHandler does with a record when its queue
+// is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for room in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop drops the record.
+	OverflowDrop
+	// OverflowDropAndCount drops the record and later emits a record,
+	// with the message OverflowMessage, counting the dropped records.
+	OverflowDropAndCount
+)
+
+// OverflowMessage is the message of the record emitted by an AsyncHandler
+// with OverflowDropAndCount after dropping records.
+const OverflowMessage = "log records dropped by full async queue"
+
+// AsyncOptions configures an AsyncHandler.
+type AsyncOptions struct {
+	// QueueSize is the maximum number of queued records. Defaults to 1024.
+	QueueSize int
+	// Overflow is what to do with records when the queue is full. Records at
+	// slog.LevelError or above always wait for room, regardless of policy.
+	Overflow OverflowPolicy
+}
+
+var errAsyncClosed = errors.New("async handler closed")
+
+// AsyncHandler is a slog.Handler that queues records and passes them to the
+// next handler from a separate goroutine so that logging doesn't wait on
+// slow writers. Call Close to stop the goroutine and handle the queued
+// records.
+//
+// The goroutine ignores errors from the next handler since the caller
+// already returned.
+type AsyncHandler struct {
+	next  slog.Handler
+	queue *asyncQueue
+}
+
+// asyncQueue is the queue and goroutine behind an AsyncHandler. Each queued
+// record carries its own next handler, so the loggers from WithAttrs and
+// WithGroup feed one queue and keep their relative order.
+type asyncQueue struct {
+	overflow OverflowPolicy
+	// root is the next handler given to NewAsyncHandler. The goroutine logs
+	// OverflowMessage records to it between queued records, where no
+	// logger's handler is at hand.
+	root    slog.Handler
+	records chan asyncRecord
+	done    chan struct{} // closed when the goroutine exits
+
+	dropped    atomic.Uint64 // total dropped records
+	unreported atomic.Uint64 // dropped records not yet in an overflow record
+
+	// closing is closed by Close before closing records, so that senders
+	// waiting for room in records stop waiting.
+	closing   chan struct{}
+	closeOnce sync.Once
+	// mu guards closing the records channel. Senders hold a read lock, which
+	// they release promptly once closing is closed.
+	mu sync.RWMutex
+}
+
+// asyncRecord is a queued record with the handler to pass it to, or a flush
+// marker if flushed is non-nil.
+type asyncRecord struct {
+	ctx     context.Context //nolint:containedctx // carried to the goroutine
+	next    slog.Handler
+	r       slog.Record
+	flushed chan struct{}
+}
+
+// NewAsyncHandler returns a handler that passes records to next from a new
+// goroutine.
+func NewAsyncHandler(next slog.Handler, opts *AsyncOptions) *AsyncHandler {
+	if opts == nil {
+		opts = &AsyncOptions{}
+	}
+	size := opts.QueueSize
+	if size <= 0 {
+		size = 1024
+	}
+	q := &asyncQueue{
+		overflow: opts.Overflow,
+		root:     next,
+		records:  make(chan asyncRecord, size),
+		done:     make(chan struct{}),
+		closing:  make(chan struct{}),
+	}
+	go q.run()
+	return &AsyncHandler{next: next, queue: q}
+}
+
+func (h *AsyncHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+// Handle queues a copy of the record. Returns an error if the handler is
+// closed.
+func (h *AsyncHandler) Handle(ctx context.Context, r slog.Record) error {
+	q := h.queue
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.isClosing() {
+		return errAsyncClosed
+	}
+	// Clone since the caller may reuse the record's attr backing array.
+	rec := asyncRecord{ctx: context.WithoutCancel(ctx), next: h.next, r: r.Clone()}
+	if q.overflow == OverflowBlock || r.Level >= slog.LevelError {
+		select {
+		case q.records <- rec:
+			return nil
+		case <-q.closing:
+			return errAsyncClosed
+		}
+	}
+	select {
+	case q.records <- rec:
+	default:
+		q.dropped.Add(1)
+		if q.overflow == OverflowDropAndCount {
+			q.unreported.Add(1)
+		}
+	}
+	return nil
+}
+
+func (h *AsyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &AsyncHandler{next: h.next.WithAttrs(attrs), queue: h.queue}
+}
+
+func (h *AsyncHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &AsyncHandler{next: h.next.WithGroup(name), queue: h.queue}
+}
+
+// Dropped returns the number of records dropped because the queue was full.
+func (h *AsyncHandler) Dropped() uint64 {
+	return h.queue.dropped.Load()
+}
+
+// Flush waits until the next handler handled the records queued before the
+// call, or until ctx is done.
+func (h *AsyncHandler) Flush(ctx context.Context) error {
+	q := h.queue
+	flushed := make(chan struct{})
+	q.mu.RLock()
+	if q.isClosing() {
+		q.mu.RUnlock()
+		return errAsyncClosed
+	}
+	select {
+	case q.records <- asyncRecord{flushed: flushed}:
+		q.mu.RUnlock()
+	case <-q.closing:
+		q.mu.RUnlock()
+		return errAsyncClosed
+	case <-ctx.Done():
+		q.mu.RUnlock()
+		return fmt.Errorf("flush async handler: %w", ctx.Err())
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("flush async handler: %w", ctx.Err())
+	}
+}
+
+// Close stops accepting records and waits until the next handler handled
+// the queued records, or until ctx is done. Calls to Handle waiting for room
+// in the queue return an error. If ctx is done first, the goroutine
+// continues to handle the queued records in the background.
+func (h *AsyncHandler) Close(ctx context.Context) error {
+	q := h.queue
+	q.closeOnce.Do(func() {
+		close(q.closing)
+		// Senders no longer wait for room, so the lock is quick to acquire.
+		q.mu.Lock()
+		close(q.records)
+		q.mu.Unlock()
+	})
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("close async handler: %w", ctx.Err())
+	}
+}
+
+// isClosing reports whether Close was called.
+func (q *asyncQueue) isClosing() bool {
+	select {
+	case <-q.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *asyncQueue) run() {
+	defer close(q.done)
+	for rec := range q.records {
+		if n := q.unreported.Swap(0); n > 0 {
+			q.reportDropped(n)
+		}
+		if rec.flushed != nil {
+			close(rec.flushed)
+			continue
+		}
+		_ = rec.next.Handle(rec.ctx, rec.r)
+	}
+	if n := q.unreported.Swap(0); n > 0 {
+		q.reportDropped(n)
+	}
+}
+
+func (q *asyncQueue) reportDropped(n uint64) {
+	ctx := context.Background()
+	if !q.root.Enabled(ctx, slog.LevelWarn) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), slog.LevelWarn, OverflowMessage, 0)
+	r.AddAttrs(slog.Uint64("dropped", n))
+	_ = q.root.Handle(ctx, r)
+}
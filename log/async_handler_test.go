@@ -0,0 +1,188 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestAsyncHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h := NewAsyncHandler(newTestTextHandler(buf), nil)
+	l := slog.New(h)
+
+	l.Info("i1", "a", 1)
+	l.WithGroup("g").With("b", 2).Warn("w1", "c", 3)
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "i2", 0)
+	r.AddAttrs(slog.Int("d", 4))
+	_ = h.Handle(t.Context(), r)
+	r.AddAttrs(slog.Int("after", 5)) // must not affect the queued record
+
+	if err := h.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	difftest.AssertSame(t, "records", []string{
+		"level=INFO msg=i1 a=1",
+		"level=WARN msg=w1 g.b=2 g.c=3",
+		"level=INFO msg=i2 d=4",
+	}, logLines(buf))
+
+	if err := h.Close(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Handle(t.Context(), r); !errors.Is(err, errAsyncClosed) {
+		t.Errorf("got error %v after close, want %v", err, errAsyncClosed)
+	}
+	if err := h.Close(t.Context()); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+}
+
+func TestAsyncHandler_Overflow(t *testing.T) {
+	tests := []struct {
+		name        string
+		overflow    OverflowPolicy
+		wantDropped uint64
+		want        []string
+	}{
+		{
+			name:        "drop",
+			overflow:    OverflowDrop,
+			wantDropped: 2,
+			want:        []string{"level=INFO msg=i0", "level=INFO msg=i1", "level=ERROR msg=e1"},
+		},
+		{
+			name:        "drop and count",
+			overflow:    OverflowDropAndCount,
+			wantDropped: 2,
+			want: []string{
+				"level=INFO msg=i0",
+				`level=WARN msg="` + OverflowMessage + `" dropped=2`,
+				"level=INFO msg=i1",
+				"level=ERROR msg=e1",
+			},
+		},
+		{
+			name:     "block",
+			overflow: OverflowBlock,
+			want: []string{
+				"level=INFO msg=i0",
+				"level=INFO msg=i1",
+				"level=INFO msg=i2",
+				"level=INFO msg=i3",
+				"level=ERROR msg=e1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			gate := newGateHandler(newTestTextHandler(buf))
+			h := NewAsyncHandler(gate, &AsyncOptions{QueueSize: 1, Overflow: tt.overflow})
+			l := slog.New(h)
+
+			l.Info("i0")
+			<-gate.started // the goroutine holds i0, leaving the queue empty
+			l.Info("i1")   // fills the queue
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				l.Info("i2")  // overflows
+				l.Info("i3")  // overflows
+				l.Error("e1") // waits for room
+			}()
+			if tt.overflow != OverflowBlock {
+				waitFor(t, func() bool { return h.Dropped() == tt.wantDropped })
+			}
+			close(gate.release)
+			wg.Wait()
+
+			if err := h.Close(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+			difftest.AssertSame(t, "dropped", int(tt.wantDropped), int(h.Dropped()))
+			difftest.AssertSame(t, "records", tt.want, logLines(buf))
+		})
+	}
+}
+
+func TestAsyncHandler_Deadline(t *testing.T) {
+	gate := newGateHandler(slog.DiscardHandler)
+	h := NewAsyncHandler(gate, nil)
+	defer close(gate.release)
+	_ = h.Handle(t.Context(), slog.NewRecord(time.Now(), slog.LevelInfo, "i1", 0))
+	<-gate.started
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got flush error %v, want deadline exceeded", err)
+	}
+	if err := h.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got close error %v, want deadline exceeded", err)
+	}
+}
+
+func TestAsyncHandler_CloseBlockedSender(t *testing.T) {
+	gate := newGateHandler(slog.DiscardHandler)
+	h := NewAsyncHandler(gate, &AsyncOptions{QueueSize: 1})
+	defer close(gate.release)
+	_ = h.Handle(t.Context(), slog.NewRecord(time.Now(), slog.LevelInfo, "i1", 0))
+	<-gate.started // the goroutine holds i1, leaving the queue empty
+	_ = h.Handle(t.Context(), slog.NewRecord(time.Now(), slog.LevelInfo, "i2", 0))
+
+	blocked := make(chan error)
+	go func() {
+		blocked <- h.Handle(t.Context(), slog.NewRecord(time.Now(), slog.LevelInfo, "i3", 0))
+	}()
+	time.Sleep(10 * time.Millisecond) // let the sender wait for room in the full queue
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got close error %v, want deadline exceeded", err)
+	}
+	if err := <-blocked; !errors.Is(err, errAsyncClosed) {
+		t.Errorf("got blocked handle error %v, want %v", err, errAsyncClosed)
+	}
+}
+
+// gateHandler is a slog.Handler that signals started when handling the first
+// record, then blocks until release is closed.
+type gateHandler struct {
+	slog.Handler
+	once    *sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func newGateHandler(next slog.Handler) gateHandler {
+	return gateHandler{Handler: next, once: &sync.Once{}, started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (h gateHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.once.Do(func() { close(h.started) })
+	<-h.release
+	return h.Handler.Handle(ctx, r) //nolint:wrapcheck // test wrapper
+}
+
+func BenchmarkAsyncHandler_Handle(b *testing.B) {
+	ctx := b.Context()
+	h := NewAsyncHandler(slog.DiscardHandler, &AsyncOptions{Overflow: OverflowDrop})
+	b.Cleanup(func() { _ = h.Close(context.Background()) })
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0)
+	r.AddAttrs(slog.String("a", "b"), slog.Int("c", 1))
+	b.ReportAllocs()
+
+	for b.Loop() {
+		_ = h.Handle(ctx, r)
+	}
+}
@@ -0,0 +1,96 @@
+package log
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// maxCallerDepth is the maximum number of frames to search for a caller that
+// is not a helper.
+const maxCallerDepth = 32
+
+//nolint:gochecknoglobals
+var (
+	helpers    = &helperSet{names: make(map[string]bool), pcs: make(map[uintptr]bool)}
+	hasHelpers atomic.Bool // whether any function called Helper
+)
+
+// Helper marks the calling function as a logging helper, like
+// testing.T.Helper. The source of records logged in a helper is the first
+// caller that is not a helper. Applies to the logging functions of this
+// package, Logger, and the adapters from Logger.Slog, Logger.StdLogger, and
+// RedirectStdLog.
+func Helper() {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:]) // skip [Callers, Helper]
+	if !helpers.contains(pcs[0]) {
+		helpers.add(pcs[0])
+	}
+}
+
+// helperSet is the set of functions marked by Helper.
+type helperSet struct {
+	mu    sync.RWMutex
+	names map[string]bool  // names of helper functions
+	pcs   map[uintptr]bool // cache of whether a pc is in a helper
+}
+
+func (s *helperSet) add(pc uintptr) {
+	name := funcName(pc)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.names[name] = true
+	// Other pcs in the function may be cached as not in a helper.
+	clear(s.pcs)
+	s.pcs[pc] = true
+	hasHelpers.Store(true)
+}
+
+// contains reports whether the pc is in a helper function.
+func (s *helperSet) contains(pc uintptr) bool {
+	s.mu.RLock()
+	isHelper, ok := s.pcs[pc]
+	s.mu.RUnlock()
+	if ok {
+		return isHelper
+	}
+	name := funcName(pc)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	isHelper = s.names[name]
+	s.pcs[pc] = isHelper
+	return isHelper
+}
+
+// callerPC returns the pc of the caller skip frames up the stack, where 0
+// identifies the caller of callerPC, like runtime.Caller. If the caller is a
+// helper, returns the pc of the first caller above it that is not a helper.
+func callerPC(skip int) uintptr {
+	if !hasHelpers.Load() {
+		var pcs [1]uintptr
+		runtime.Callers(skip+2, pcs[:]) // skip [Callers, callerPC]
+		return pcs[0]
+	}
+	var pcs [maxCallerDepth]uintptr
+	n := runtime.Callers(skip+2, pcs[:]) // skip [Callers, callerPC]
+	return firstPC(pcs[:n], helpers.contains)
+}
+
+// firstPC returns the first pc for which skip returns false, or the last pc
+// if skip returns true for all.
+func firstPC(pcs []uintptr, skip func(pc uintptr) bool) uintptr {
+	for i, pc := range pcs {
+		if i == len(pcs)-1 || !skip(pc) {
+			return pc
+		}
+	}
+	return 0
+}
+
+// funcName returns the name of the function containing the pc, as reported
+// in the record source. For inlined calls, returns the inlined function.
+func funcName(pc uintptr) string {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return frame.Function
+}
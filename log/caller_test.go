@@ -0,0 +1,76 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+	"github.com/jschaf/observe/internal/race"
+)
+
+func TestHelper(t *testing.T) {
+	rec := &recordHandler{}
+	slog.SetDefault(slog.New(rec))
+	ctx := t.Context()
+
+	Info(ctx, "direct")
+	infoHelper(ctx, "helper")
+	nestedInfoHelper(ctx, "nested")
+	loggerHelper(ctx, Named("db"), "logger")
+	infoWrapper(ctx, "wrapper")
+
+	const test = "github.com/jschaf/observe/log.TestHelper"
+	difftest.AssertSame(t, "sources", []string{
+		test,
+		test,
+		test,
+		test,
+		"github.com/jschaf/observe/log.infoWrapper",
+	}, sourceFuncs(rec))
+}
+
+func infoHelper(ctx context.Context, msg string) {
+	Helper()
+	Info(ctx, msg)
+}
+
+func nestedInfoHelper(ctx context.Context, msg string) {
+	Helper()
+	infoHelper(ctx, msg)
+}
+
+func loggerHelper(ctx context.Context, l *Logger, msg string) {
+	Helper()
+	l.Info(ctx, msg)
+}
+
+// infoWrapper is not a helper, so it's the source.
+func infoWrapper(ctx context.Context, msg string) {
+	Info(ctx, msg)
+}
+
+func TestHelper_Allocs(t *testing.T) {
+	if race.Enabled {
+		t.Skip("race detector adds allocations")
+	}
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	ctx := t.Context()
+	allocs := testing.AllocsPerRun(100, func() {
+		infoHelper(ctx, "msg")
+	})
+	if allocs != 0 {
+		t.Errorf("got %.0f allocs logging from a helper, want 0", allocs)
+	}
+}
+
+// sourceFuncs returns the functions of the record sources.
+func sourceFuncs(h *recordHandler) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	funcs := make([]string, 0, len(h.records))
+	for _, r := range h.records {
+		funcs = append(funcs, funcName(r.PC))
+	}
+	return funcs
+}
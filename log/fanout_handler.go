@@ -0,0 +1,118 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Route is a child handler of a FanoutHandler with the records and attrs to
+// pass to it.
+type Route struct {
+	// Handler is the child handler.
+	Handler slog.Handler
+	// Level is the minimum level of records to pass to the handler, in
+	// addition to the handler's own Enabled check. If nil, passes records at
+	// all levels the handler enables.
+	Level slog.Leveler
+	// Filter reports whether to pass an attr to the handler, for attrs from
+	// records and from WithAttrs. Applies to top-level attrs, so filtering a
+	// group filters all attrs in the group. If nil, passes all attrs.
+	Filter func(slog.Attr) bool
+}
+
+// FanoutHandler is a slog.Handler that passes each record to multiple child
+// handlers, like a DevHandler on stderr, a JSON handler for a file, and an
+// alerting handler for errors. Passes each child its own copy of the record.
+type FanoutHandler struct {
+	routes []Route
+}
+
+// NewFanoutHandler returns a handler that passes records to the handlers of
+// the routes.
+func NewFanoutHandler(routes ...Route) *FanoutHandler {
+	return &FanoutHandler{routes: routes}
+}
+
+// Enabled reports whether any child handler handles records at the level.
+func (h *FanoutHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	for i := range h.routes {
+		if h.routes[i].enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes the record to each child handler enabled for its level.
+// Returns the errors from all children joined.
+func (h *FanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for i := range h.routes {
+		route := &h.routes[i]
+		if !route.enabled(ctx, r.Level) {
+			continue
+		}
+		if err := route.Handler.Handle(ctx, route.record(r)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *FanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	routes := make([]Route, len(h.routes))
+	for i, route := range h.routes {
+		route.Handler = route.Handler.WithAttrs(route.filter(attrs))
+		routes[i] = route
+	}
+	return &FanoutHandler{routes: routes}
+}
+
+func (h *FanoutHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	routes := make([]Route, len(h.routes))
+	for i, route := range h.routes {
+		route.Handler = route.Handler.WithGroup(name)
+		routes[i] = route
+	}
+	return &FanoutHandler{routes: routes}
+}
+
+func (r *Route) enabled(ctx context.Context, l slog.Level) bool {
+	if r.Level != nil && l < r.Level.Level() {
+		return false
+	}
+	return r.Handler.Enabled(ctx, l)
+}
+
+// record returns a copy of the record with the attrs passing the filter.
+func (r *Route) record(rec slog.Record) slog.Record {
+	if r.Filter == nil {
+		return rec.Clone()
+	}
+	out := slog.NewRecord(rec.Time, rec.Level, rec.Message, rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		if r.Filter(a) {
+			out.AddAttrs(a)
+		}
+		return true
+	})
+	return out
+}
+
+// filter returns the attrs passing the filter.
+func (r *Route) filter(attrs []slog.Attr) []slog.Attr {
+	if r.Filter == nil {
+		return attrs
+	}
+	out := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if r.Filter(a) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
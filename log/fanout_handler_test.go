@@ -0,0 +1,109 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestFanoutHandler(t *testing.T) {
+	dev, file, alerts := &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}
+	h := NewFanoutHandler(
+		Route{Handler: newTestTextHandler(dev)},
+		Route{Handler: slog.NewJSONHandler(file, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) == 0 && a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		})},
+		Route{
+			Handler: newTestTextHandler(alerts),
+			Level:   slog.LevelError,
+			Filter:  func(a slog.Attr) bool { return a.Key != "debug" },
+		},
+	)
+	l := slog.New(h).With("svc", "api", "debug", "x").WithGroup("req")
+
+	l.Debug("d1", "a", 1)
+	l.Info("i1", "b", 2)
+	l.Error("e1", "c", 3, "debug", "y")
+
+	// The handlers' own levels apply, so none handle the debug record.
+	difftest.AssertSame(t, "dev", []string{
+		"level=INFO msg=i1 svc=api debug=x req.b=2",
+		"level=ERROR msg=e1 svc=api debug=x req.c=3 req.debug=y",
+	}, logLines(dev))
+	difftest.AssertSame(t, "file", []string{
+		`{"level":"INFO","msg":"i1","svc":"api","debug":"x","req":{"b":2}}`,
+		`{"level":"ERROR","msg":"e1","svc":"api","debug":"x","req":{"c":3,"debug":"y"}}`,
+	}, logLines(file))
+	difftest.AssertSame(t, "alerts", []string{
+		"level=ERROR msg=e1 svc=api req.c=3",
+	}, logLines(alerts))
+}
+
+func TestFanoutHandler_Enabled(t *testing.T) {
+	h := NewFanoutHandler(
+		Route{Handler: slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelWarn})},
+		Route{Handler: &recordHandler{}, Level: slog.LevelInfo},
+	)
+	tests := []struct {
+		level slog.Level
+		want  bool
+	}{
+		{slog.LevelDebug, false},
+		{slog.LevelInfo, true},
+		{slog.LevelError, true},
+	}
+	for _, tt := range tests {
+		difftest.AssertSame(t, tt.level.String(), tt.want, h.Enabled(t.Context(), tt.level))
+	}
+	difftest.AssertSame(t, "no routes", false, NewFanoutHandler().Enabled(t.Context(), slog.LevelError))
+}
+
+func TestFanoutHandler_Errors(t *testing.T) {
+	errA, errB := errors.New("a failed"), errors.New("b failed")
+	rec := &recordHandler{}
+	h := NewFanoutHandler(
+		Route{Handler: errHandler{errA}},
+		Route{Handler: rec},
+		Route{Handler: errHandler{errB}},
+	)
+	err := h.Handle(t.Context(), slog.NewRecord(time.Time{}, slog.LevelInfo, "m", 0))
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("got error %v, want both child errors", err)
+	}
+	difftest.AssertSame(t, "records after error", []string{"INFO m"}, rec.strings())
+}
+
+func BenchmarkFanoutHandler_Handle(b *testing.B) {
+	h := NewFanoutHandler(
+		Route{Handler: slog.DiscardHandler},
+		Route{Handler: &recordHandler{}, Level: slog.LevelError},
+	)
+	ctx := b.Context()
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+	r.AddAttrs(slog.String("a", "b"), slog.Int("c", 1))
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = h.Handle(ctx, r)
+	}
+}
+
+// errHandler is a slog.Handler that fails to handle records.
+type errHandler struct{ err error }
+
+func (h errHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h errHandler) Handle(context.Context, slog.Record) error { return h.err }
+
+func (h errHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h errHandler) WithGroup(string) slog.Handler { return h }
@@ -0,0 +1,312 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Levels holds the minimum levels for logging, globally and per logger name,
+// adjustable at runtime, like with loghttp.NewLevelHandler.
+//
+// Levels are set for a logger name, like "db", a pattern matching a name
+// and its descendants, like "http.*" for "http" and "http.client", or
+// globally with "*" or the empty string. The most specific level wins.
+//
+// A set level replaces the Enabled check of the handler, so a handler
+// configured at info level still receives debug records if the level for
+// the logger is debug. Without a set level, the handler decides.
+type Levels struct {
+	global    slog.LevelVar
+	hasGlobal atomic.Bool
+	// names is a copy-on-write map of logger names to levels so that reads
+	// don't need a lock.
+	names atomic.Pointer[map[string]*slog.LevelVar]
+	// gen increments after each change so loggers can cache their level.
+	gen atomic.Uint64
+
+	mu sync.Mutex // guards writes and the fields below
+	// reverts are the pending reverts of temporary levels, keyed by logger
+	// name or the empty string for the global level.
+	reverts map[string]*levelRevert
+}
+
+type levelRevert struct {
+	timer   *time.Timer
+	expires time.Time
+}
+
+// LevelSetting is a level set for a logger name, or globally if Name is the
+// empty string.
+type LevelSetting struct {
+	Name  string
+	Level slog.Level
+	// Expires is when a temporary level reverts, or zero if permanent.
+	Expires time.Time
+}
+
+// LevelsEnv is the environment variable with the level spec for
+// DefaultLevels, like "db=debug,http.*=warn,*=info". See Levels.SetSpec.
+const LevelsEnv = "LOG_LEVELS"
+
+//nolint:gochecknoglobals
+var defaultLevels = newDefaultLevels()
+
+func newDefaultLevels() *Levels {
+	l := &Levels{}
+	spec := os.Getenv(LevelsEnv)
+	if spec == "" {
+		return l
+	}
+	if err := l.SetSpec(spec); err != nil {
+		// Too early to log with slog, which the program may not have set up.
+		_, _ = fmt.Fprintf(os.Stderr, "log: ignoring %s: %v\n", LevelsEnv, err)
+	}
+	return l
+}
+
+// DefaultLevels returns the levels used by the package-level log functions.
+func DefaultLevels() *Levels {
+	return defaultLevels
+}
+
+// Level returns the minimum level for the logger name, falling back to the
+// most specific matching pattern and then the global level. Returns false if
+// none are set.
+func (l *Levels) Level(name string) (slog.Level, bool) {
+	if names := l.names.Load(); names != nil && name != "" {
+		if v, ok := (*names)[name]; ok {
+			return v.Level(), true
+		}
+		// Try patterns from most to least specific, like "a.b.*" then "a.*".
+		for prefix := name; ; {
+			if v, ok := (*names)[prefix+".*"]; ok {
+				return v.Level(), true
+			}
+			i := strings.LastIndexByte(prefix, '.')
+			if i < 0 {
+				break
+			}
+			prefix = prefix[:i]
+		}
+	}
+	if l.hasGlobal.Load() {
+		return l.global.Level(), true
+	}
+	return 0, false
+}
+
+// SetLevel sets the minimum level for the logger name or pattern, or the
+// global level if name is empty or "*". Cancels any pending revert of a
+// temporary level.
+func (l *Levels) SetLevel(name string, level slog.Level) {
+	name = levelKey(name)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopRevertLocked(name)
+	l.setLocked(name, level)
+}
+
+// SetLevelFor sets the minimum level for the logger name or pattern, or the
+// global level if name is empty or "*", for duration d. Afterward, reverts to
+// the level, or lack of one, from before the call.
+func (l *Levels) SetLevelFor(name string, level slog.Level, d time.Duration) {
+	name = levelKey(name)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopRevertLocked(name)
+	prev, hasPrev := l.levelLocked(name)
+	l.setLocked(name, level)
+
+	rev := &levelRevert{expires: time.Now().Add(d)}
+	rev.timer = time.AfterFunc(d, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.reverts[name] != rev {
+			return // replaced by a later call
+		}
+		delete(l.reverts, name)
+		if hasPrev {
+			l.setLocked(name, prev)
+		} else {
+			l.resetLocked(name)
+		}
+	})
+	if l.reverts == nil {
+		l.reverts = make(map[string]*levelRevert)
+	}
+	l.reverts[name] = rev
+}
+
+// ResetLevel removes the level for the logger name or pattern, or the global
+// level if name is empty or "*", so that loggers fall back to less specific
+// levels or the handler. Cancels any pending revert of a temporary level.
+func (l *Levels) ResetLevel(name string) {
+	name = levelKey(name)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopRevertLocked(name)
+	l.resetLocked(name)
+}
+
+// SetSpec replaces all levels with those in spec, a comma-separated list of
+// name=level pairs, like "db=debug,http.*=warn,*=info". A level without a
+// name sets the global level. Levels are parsed with slog.Level.UnmarshalText.
+// Leaves the levels unchanged if spec is invalid.
+func (l *Levels) SetSpec(spec string) error {
+	specs, err := parseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]*slog.LevelVar, len(specs))
+	var global *slog.Level
+	for _, s := range specs {
+		if s.Name == "" {
+			global = &s.Level
+			continue
+		}
+		v := &slog.LevelVar{}
+		v.Set(s.Level)
+		names[s.Name] = v
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for name := range l.reverts {
+		l.stopRevertLocked(name)
+	}
+	l.names.Store(&names)
+	if global != nil {
+		l.global.Set(*global)
+	}
+	l.hasGlobal.Store(global != nil)
+	l.gen.Add(1)
+	return nil
+}
+
+func parseLevelSpec(spec string) ([]LevelSetting, error) {
+	var specs []LevelSetting
+	for item := range strings.SplitSeq(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, levelStr, ok := strings.Cut(item, "=")
+		if !ok {
+			name, levelStr = "", item
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(levelStr))); err != nil {
+			return nil, fmt.Errorf("parse level spec %q: %w", item, err)
+		}
+		specs = append(specs, LevelSetting{Name: levelKey(strings.TrimSpace(name)), Level: level})
+	}
+	return specs, nil
+}
+
+// levelKey returns the key for a logger name or pattern, using the empty
+// string for the global level.
+func levelKey(name string) string {
+	if name == "*" {
+		return ""
+	}
+	return name
+}
+
+// Settings returns the set levels, sorted by name, starting with the global
+// level, if set.
+func (l *Levels) Settings() []LevelSetting {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var settings []LevelSetting
+	if l.hasGlobal.Load() {
+		settings = append(settings, LevelSetting{Level: l.global.Level()})
+	}
+	if names := l.names.Load(); names != nil {
+		for _, name := range slices.Sorted(maps.Keys(*names)) {
+			settings = append(settings, LevelSetting{Name: name, Level: (*names)[name].Level()})
+		}
+	}
+	for i, s := range settings {
+		if rev, ok := l.reverts[s.Name]; ok {
+			settings[i].Expires = rev.expires
+		}
+	}
+	return settings
+}
+
+// enabled reports whether to log a record at level for the logger name,
+// deferring to the handler if no level is set.
+func (l *Levels) enabled(ctx context.Context, h slog.Handler, name string, level slog.Level) bool {
+	if minLevel, ok := l.Level(name); ok {
+		return level >= minLevel
+	}
+	return h.Enabled(ctx, level)
+}
+
+func (l *Levels) levelLocked(name string) (slog.Level, bool) {
+	if name == "" {
+		return l.global.Level(), l.hasGlobal.Load()
+	}
+	if names := l.names.Load(); names != nil {
+		if v, ok := (*names)[name]; ok {
+			return v.Level(), true
+		}
+	}
+	return 0, false
+}
+
+func (l *Levels) setLocked(name string, level slog.Level) {
+	defer l.gen.Add(1)
+	if name == "" {
+		l.global.Set(level)
+		l.hasGlobal.Store(true)
+		return
+	}
+	var names map[string]*slog.LevelVar
+	if p := l.names.Load(); p != nil {
+		if v, ok := (*p)[name]; ok {
+			v.Set(level)
+			return
+		}
+		names = maps.Clone(*p)
+	} else {
+		names = make(map[string]*slog.LevelVar, 1)
+	}
+	v := &slog.LevelVar{}
+	v.Set(level)
+	names[name] = v
+	l.names.Store(&names)
+}
+
+func (l *Levels) resetLocked(name string) {
+	defer l.gen.Add(1)
+	if name == "" {
+		l.hasGlobal.Store(false)
+		return
+	}
+	p := l.names.Load()
+	if p == nil {
+		return
+	}
+	if _, ok := (*p)[name]; !ok {
+		return
+	}
+	names := maps.Clone(*p)
+	delete(names, name)
+	l.names.Store(&names)
+}
+
+func (l *Levels) stopRevertLocked(name string) {
+	if rev, ok := l.reverts[name]; ok {
+		rev.timer.Stop()
+		delete(l.reverts, name)
+	}
+}
@@ -0,0 +1,173 @@
+package log
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestLevels(t *testing.T) {
+	l := &Levels{}
+	checkLevel := func(name string, want slog.Level, wantOK bool) {
+		t.Helper()
+		got, ok := l.Level(name)
+		difftest.AssertSame(t, "level set for "+name, wantOK, ok)
+		difftest.AssertSame(t, "level for "+name, want.String(), got.String())
+	}
+
+	checkLevel("", 0, false)
+	checkLevel("db", 0, false)
+
+	l.SetLevel("", slog.LevelWarn)
+	checkLevel("", slog.LevelWarn, true)
+	checkLevel("db", slog.LevelWarn, true)
+
+	l.SetLevel("db", slog.LevelDebug)
+	checkLevel("db", slog.LevelDebug, true)
+	checkLevel("http", slog.LevelWarn, true)
+
+	difftest.AssertSame(t, "settings", []string{"=WARN", "db=DEBUG"}, settingStrings(l.Settings()))
+
+	l.ResetLevel("db")
+	checkLevel("db", slog.LevelWarn, true)
+	l.ResetLevel("")
+	checkLevel("db", 0, false)
+	difftest.AssertSame(t, "settings", 0, len(l.Settings()))
+}
+
+func TestLevels_SetLevelFor(t *testing.T) {
+	l := &Levels{}
+	l.SetLevel("db", slog.LevelWarn)
+	l.SetLevelFor("db", slog.LevelDebug, 20*time.Millisecond)
+	l.SetLevelFor("", slog.LevelError, 20*time.Millisecond)
+
+	if got, _ := l.Level("db"); got != slog.LevelDebug {
+		t.Errorf("got level %s, want DEBUG", got)
+	}
+	settings := l.Settings()
+	if len(settings) != 2 || settings[1].Expires.IsZero() {
+		t.Errorf("want expiring db level, got %v", settings)
+	}
+
+	waitFor(t, func() bool {
+		got, _ := l.Level("db")
+		_, hasGlobal := l.Level("")
+		return got == slog.LevelWarn && !hasGlobal
+	})
+	difftest.AssertSame(t, "settings", []string{"db=WARN"}, settingStrings(l.Settings()))
+}
+
+func TestLevels_SetLevelFor_Canceled(t *testing.T) {
+	l := &Levels{}
+	l.SetLevelFor("db", slog.LevelDebug, 10*time.Millisecond)
+	l.SetLevel("db", slog.LevelError)
+	time.Sleep(30 * time.Millisecond)
+
+	if got, ok := l.Level("db"); !ok || got != slog.LevelError {
+		t.Errorf("got level %s, want ERROR", got)
+	}
+}
+
+func TestLog_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { defaultLevels.ResetLevel("") })
+
+	defaultLevels.SetLevel("", slog.LevelDebug)
+	Debug(t.Context(), "dbg")
+	checkLogOutput(t, buf.String(), `time=`+textTimeRE+` level=DEBUG msg=dbg`)
+	buf.Reset()
+
+	defaultLevels.SetLevel("", slog.LevelError)
+	Warn(t.Context(), "warn")
+	checkLogOutput(t, buf.String(), "")
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func settingStrings(settings []LevelSetting) []string {
+	strs := make([]string, 0, len(settings))
+	for _, s := range settings {
+		strs = append(strs, s.Name+"="+s.Level.String())
+	}
+	return strs
+}
+
+func BenchmarkLog_Disabled(b *testing.B) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+	b.Cleanup(func() { defaultLevels.ResetLevel("") })
+	ctx := b.Context()
+	b.Run("handler level", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			Debug(ctx, "msg", slog.Int("a", 1))
+		}
+	})
+	b.Run("global level", func(b *testing.B) {
+		defaultLevels.SetLevel("", slog.LevelInfo)
+		b.ReportAllocs()
+		for b.Loop() {
+			Debug(ctx, "msg", slog.Int("a", 1))
+		}
+	})
+}
+
+func TestLevels_Patterns(t *testing.T) {
+	l := &Levels{}
+	l.SetLevel("*", slog.LevelInfo)
+	l.SetLevel("http.*", slog.LevelWarn)
+	l.SetLevel("http.client.*", slog.LevelError)
+	l.SetLevel("http.client.pool", slog.LevelDebug)
+
+	tests := []struct {
+		name string
+		want slog.Level
+	}{
+		{"", slog.LevelInfo},
+		{"db", slog.LevelInfo},
+		{"httpd", slog.LevelInfo},
+		{"http", slog.LevelWarn},
+		{"http.server", slog.LevelWarn},
+		{"http.client", slog.LevelError},
+		{"http.client.conn", slog.LevelError},
+		{"http.client.pool", slog.LevelDebug},
+		{"http.client.pool.idle", slog.LevelError},
+	}
+	for _, tt := range tests {
+		got, ok := l.Level(tt.name)
+		if !ok || got != tt.want {
+			t.Errorf("Level(%q) = %s, %t; want %s", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func TestLevels_SetSpec(t *testing.T) {
+	l := &Levels{}
+	l.SetLevel("old", slog.LevelError)
+	l.SetLevelFor("tmp", slog.LevelError, time.Hour)
+	if err := l.SetSpec(" db=debug, http.*=WARN,,*=info+2 "); err != nil {
+		t.Fatal(err)
+	}
+	difftest.AssertSame(t, "settings", []string{"=INFO+2", "db=DEBUG", "http.*=WARN"}, settingStrings(l.Settings()))
+
+	if err := l.SetSpec("warn"); err != nil {
+		t.Fatal(err)
+	}
+	difftest.AssertSame(t, "bare level settings", []string{"=WARN"}, settingStrings(l.Settings()))
+
+	err := l.SetSpec("db=debug,http=loud")
+	difftest.AssertSame(t, "error", `parse level spec "http=loud": slog: level string "loud": unknown name`, err.Error())
+	difftest.AssertSame(t, "unchanged settings", []string{"=WARN"}, settingStrings(l.Settings()))
+}
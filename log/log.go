@@ -3,43 +3,67 @@ package log
 import (
 	"context"
 	"log/slog"
-	"runtime"
 	"time"
+
+	"github.com/jschaf/observe/internal/logkey"
+	"github.com/jschaf/observe/trace"
+)
+
+// Keys for the trace correlation attributes added to records logged with a
+// context containing a span.
+const (
+	TraceIDKey      = logkey.TraceID
+	SpanIDKey       = logkey.SpanID
+	TraceSampledKey = logkey.TraceSampled
 )
 
 // Debug logs at [slog.LevelDebug].
 func Debug(ctx context.Context, msg string, attrs ...slog.Attr) {
-	log(ctx, slog.LevelDebug, msg, attrs)
+	log(ctx, slog.LevelDebug, msg, nil, attrs)
 }
 
 // Info logs at [slog.LevelInfo].
 func Info(ctx context.Context, msg string, attrs ...slog.Attr) {
-	log(ctx, slog.LevelInfo, msg, attrs)
+	log(ctx, slog.LevelInfo, msg, nil, attrs)
 }
 
 // Warn logs at [slog.LevelWarn].
 func Warn(ctx context.Context, msg string, attrs ...slog.Attr) {
-	log(ctx, slog.LevelWarn, msg, attrs)
+	log(ctx, slog.LevelWarn, msg, nil, attrs)
 }
 
 // Error logs at [slog.LevelError].
 func Error(ctx context.Context, msg string, attrs ...slog.Attr) {
-	log(ctx, slog.LevelError, msg, attrs)
+	log(ctx, slog.LevelError, msg, nil, attrs)
 }
 
 // Log emits a log record with the current time and the given level and message.
 func Log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
-	log(ctx, level, msg, attrs)
+	log(ctx, level, msg, nil, attrs)
 }
 
-func log(ctx context.Context, level slog.Level, msg string, attrs []slog.Attr) {
-	l := slog.Default()
-	if !l.Enabled(ctx, level) {
+func log(ctx context.Context, level slog.Level, msg string, bound, attrs []slog.Attr) {
+	h := slog.Default().Handler()
+	if !defaultLevels.enabled(ctx, h, "", level) {
 		return
 	}
-	var pcs [1]uintptr
-	runtime.Callers(3, pcs[:]) // skip [Callers, log, caller]
-	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	pc := callerPC(2) // skip [log, caller]
+	r := newRecord(ctx, level, msg, pc, bound, attrs)
+	_ = h.Handle(ctx, r)
+}
+
+// newRecord returns a record with the trace correlation attrs from ctx,
+// followed by the bound attrs and then attrs.
+func newRecord(ctx context.Context, level slog.Level, msg string, pc uintptr, bound, attrs []slog.Attr) slog.Record {
+	r := slog.NewRecord(time.Now(), level, msg, pc)
+	if sc := trace.SpanFromContext(ctx).Context(); sc.IsValid() {
+		r.AddAttrs(
+			slog.String(TraceIDKey, sc.TraceID.String()),
+			slog.String(SpanIDKey, sc.SpanID.String()),
+			slog.Bool(TraceSampledKey, sc.IsSampled()),
+		)
+	}
+	r.AddAttrs(bound...)
 	r.AddAttrs(attrs...)
-	_ = l.Handler().Handle(ctx, r)
+	return r
 }
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/jschaf/observe/trace"
 )
 
 // textTimeRE is a regexp to match log timestamps for Text handler.
@@ -45,6 +47,26 @@ func TestLogTextHandler(t *testing.T) {
 	check(`level=WARN\+1 msg=w a=1 b=two`)
 }
 
+func TestLogTraceCorrelation(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	tr := &trace.Tracer{}
+	ctx, span := tr.Start(t.Context(), "test-span")
+	defer span.End()
+	sc := span.Context()
+
+	Info(ctx, "msg", slog.Int("a", 1))
+	want := `time=` + textTimeRE + ` level=INFO msg=msg trace_id=` + sc.TraceID.String() +
+		` span_id=` + sc.SpanID.String() + ` trace_sampled=true a=1`
+	checkLogOutput(t, buf.String(), want)
+	buf.Reset()
+
+	// No span in the context.
+	Info(t.Context(), "msg", slog.Int("a", 1))
+	checkLogOutput(t, buf.String(), `time=`+textTimeRE+` level=INFO msg=msg a=1`)
+}
+
 func checkLogOutput(t *testing.T, got, wantRegexp string) {
 	t.Helper()
 	got = clean(got)
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/jschaf/observe/log/logerr"
+)
+
+// pairAppender appends a single flattened attr, with the key qualified by
+// prefix, in a handler-specific format.
+type pairAppender interface {
+	appendPair(buf *Buffer, prefix string, attr slog.Attr)
+}
+
+// appendFlatAttr appends the attr with keys qualified by prefix using p.
+// Resolves LogValuers, except errors, flattens groups into dotted keys,
+// applies replaceAttr, if non-nil, to non-group attrs with resolved values,
+// and omits empty attrs and empty groups.
+//
+// Shared by DevHandler and LogfmtHandler so that both treat attrs the same.
+func appendFlatAttr(buf *Buffer, p pairAppender, replaceAttr func([]string, slog.Attr) slog.Attr, groups []string, prefix string, attr slog.Attr) {
+	attr.Value = logerr.Resolve(attr.Value)
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "." // an empty key inlines the group
+			groups = append(slices.Clip(groups), attr.Key)
+		}
+		for _, ga := range attr.Value.Group() {
+			appendFlatAttr(buf, p, replaceAttr, groups, prefix, ga)
+		}
+		return
+	}
+	if replaceAttr != nil {
+		// If replaceAttr returns a group, the pairAppender renders it inline.
+		attr = logerr.ReplaceAttr(replaceAttr, groups, attr)
+	}
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	p.appendPair(buf, prefix, attr)
+}
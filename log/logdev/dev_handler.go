@@ -5,24 +5,92 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jschaf/observe/internal/humanize"
+	"github.com/jschaf/observe/internal/logkey"
+	"github.com/jschaf/observe/internal/slogfmt"
 	"github.com/jschaf/observe/internal/tty"
+	"github.com/jschaf/observe/log/logerr"
 )
 
+// DevHandler is a slog.Handler that writes human-readable, colored records
+// for local development.
 type DevHandler struct {
 	w    io.Writer
 	opts slog.HandlerOptions
+	// preAttrs are the attrs bound with WithAttrs, rendered once so that
+	// each record doesn't re-format them. Starts with a space if not empty.
+	preAttrs []byte
+	// preErrors are the rendered details of errors bound with WithAttrs,
+	// written on the lines after each record.
+	preErrors []byte
+	// groups are the group names from WithGroup, passed to ReplaceAttr.
+	groups []string
+	// groupPrefix is the dotted key prefix from WithGroup, like "a.b.".
+	groupPrefix string
+	// color is the color capability of w.
+	color tty.Mode
 }
 
-func NewDevHandler(w io.Writer, opts *slog.HandlerOptions) *DevHandler {
+// ColorMode controls whether and how a DevHandler colors its output.
+type ColorMode int
+
+const (
+	// ColorAuto colors output if the writer is a terminal, respecting the
+	// NO_COLOR, FORCE_COLOR, CLICOLOR_FORCE, TERM, and COLORTERM environment
+	// variables.
+	ColorAuto ColorMode = iota
+	ColorNever
+	ColorBasic // the 16 standard colors
+	Color256   // the 256-color palette
+	ColorTrueColor
+)
+
+type devConfig struct {
+	color ColorMode
+}
+
+// DevOption configures a DevHandler.
+type DevOption func(devConfig) devConfig
+
+// WithColorMode sets the color mode. Defaults to ColorAuto.
+func WithColorMode(m ColorMode) DevOption {
+	return func(cfg devConfig) devConfig {
+		cfg.color = m
+		return cfg
+	}
+}
+
+func NewDevHandler(w io.Writer, opts *slog.HandlerOptions, devOpts ...DevOption) *DevHandler {
 	if opts == nil {
 		opts = &slog.HandlerOptions{}
 	}
-	return &DevHandler{w: w, opts: *opts}
+	cfg := devConfig{}
+	for _, opt := range devOpts {
+		cfg = opt(cfg)
+	}
+	return &DevHandler{w: w, opts: *opts, color: ttyMode(w, cfg.color)}
+}
+
+func ttyMode(w io.Writer, m ColorMode) tty.Mode {
+	switch m {
+	case ColorAuto:
+		return tty.DetectMode(w)
+	case ColorNever:
+		return tty.ModeNone
+	case ColorBasic:
+		return tty.ModeBasic
+	case Color256:
+		return tty.Mode256
+	case ColorTrueColor:
+		return tty.ModeTrueColor
+	default:
+		return tty.ModeNone
+	}
 }
 
 func (h *DevHandler) Enabled(_ context.Context, l slog.Level) bool {
@@ -34,9 +102,9 @@ func (h *DevHandler) Enabled(_ context.Context, l slog.Level) bool {
 }
 
 const (
-	align       = 40 // make logs easier to scan by aligning the first attr
-	alignStr    = "                                        "
-	readyPrefix = "ready: "
+	align           = 40 // make logs easier to scan by aligning the first attr
+	alignStr        = "                                        "
+	shortTraceIDLen = 8 // enough to distinguish concurrent traces
 )
 
 func (h *DevHandler) Handle(_ context.Context, r slog.Record) error {
@@ -44,24 +112,72 @@ func (h *DevHandler) Handle(_ context.Context, r slog.Record) error {
 	defer buf.Free()
 
 	// Time
-	appendTime(buf, r.Time)
+	if !r.Time.IsZero() {
+		h.appendBuiltin(buf, slog.Time(slog.TimeKey, r.Time), func(v slog.Value) bool {
+			if v.Kind() != slog.KindTime {
+				return false
+			}
+			appendTime(buf, v.Time())
+			return true
+		})
+	}
 
 	// Level
 	_ = buf.WriteByte('\t')
-	appendLevel(buf, r)
+	traceID, ready, attrCount := scanAttrs(r)
+	h.appendBuiltin(buf, slog.Any(slog.LevelKey, r.Level), func(v slog.Value) bool {
+		level, ok := v.Any().(slog.Level)
+		if !ok {
+			return false
+		}
+		h.appendLevel(buf, level, ready)
+		return true
+	})
+
+	// Trace ID
+	_ = buf.WriteByte('\t')
+	prefixLen := 0
+	if traceID != "" {
+		short := traceID[:min(len(traceID), shortTraceIDLen)]
+		h.appendStyled(buf, tty.Fg(tty.Cyan), short)
+		_ = buf.WriteByte(' ')
+		prefixLen = len(short) + 1
+	}
 
 	// Message
-	r.Message = strings.TrimPrefix(r.Message, readyPrefix)
-	_ = buf.WriteByte('\t')
-	_, _ = buf.WriteString(r.Message)
+	msgStart := buf.Len()
+	h.appendBuiltin(buf, slog.String(slog.MessageKey, r.Message), nil)
+	msgLen := buf.Len() - msgStart
 
 	// Attrs
-	if r.NumAttrs() > 0 {
-		padCount := max(align-len(r.Message), 2)
+	var details *Buffer // error details, if the record has errors
+	if attrCount > 0 || len(h.preAttrs) > 0 {
+		padCount := max(align-prefixLen-msgLen, 2)
 		pad := alignStr[:padCount]
 		_, _ = buf.WriteString(pad)
+		_, _ = buf.Write(h.preAttrs)
+		if hasError(r) {
+			details = NewBuffer()
+			defer details.Free()
+		}
 		r.Attrs(func(attr slog.Attr) bool {
-			appendAttr(buf, attr)
+			if logkey.IsTrace(attr.Key) || logkey.IsReady(attr) {
+				return true // rendered as the trace ID prefix or ready level
+			}
+			h.appendAttr(buf, details, h.groups, h.groupPrefix, attr)
+			return true
+		})
+	}
+
+	// Source
+	if h.opts.AddSource && r.PC != 0 {
+		_ = buf.WriteByte(' ')
+		h.appendBuiltin(buf, slog.Any(slog.SourceKey, slogfmt.RecordSource(r)), func(v slog.Value) bool {
+			src, ok := v.Any().(*slog.Source)
+			if !ok {
+				return false
+			}
+			appendSource(buf, src)
 			return true
 		})
 	}
@@ -69,6 +185,12 @@ func (h *DevHandler) Handle(_ context.Context, r slog.Record) error {
 	// Newline
 	_ = buf.WriteByte('\n')
 
+	// Error details
+	_, _ = buf.Write(h.preErrors)
+	if details != nil {
+		_, _ = buf.Write(*details)
+	}
+
 	_, err := h.w.Write(*buf)
 	if err != nil {
 		return fmt.Errorf("write record: %w", err)
@@ -76,12 +198,69 @@ func (h *DevHandler) Handle(_ context.Context, r slog.Record) error {
 	return nil
 }
 
-func (h *DevHandler) WithAttrs(_ []slog.Attr) slog.Handler {
-	panic("not implemented")
+// WithAttrs returns a handler that includes attrs in every record. Renders
+// attrs and error details once, when called, instead of for each record.
+func (h *DevHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	buf, details := NewBuffer(), NewBuffer()
+	defer buf.Free()
+	defer details.Free()
+	for _, attr := range attrs {
+		h.appendAttr(buf, details, h.groups, h.groupPrefix, attr)
+	}
+	h2 := *h
+	h2.preAttrs = slices.Concat(h.preAttrs, *buf)
+	h2.preErrors = slices.Concat(h.preErrors, *details)
+	return &h2
 }
 
-func (h *DevHandler) WithGroup(_ string) slog.Handler {
-	panic("not implemented")
+// WithGroup returns a handler that qualifies the keys of subsequent attrs
+// with the group name, like "group.key".
+func (h *DevHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(slices.Clip(h.groups), name)
+	h2.groupPrefix = h.groupPrefix + name + "."
+	return &h2
+}
+
+// scanAttrs returns the trace ID attribute value of the record, whether the
+// record has the ready attr, and the count of the other attributes.
+func scanAttrs(r slog.Record) (traceID string, ready bool, count int) {
+	r.Attrs(func(attr slog.Attr) bool {
+		switch {
+		case logkey.IsReady(attr):
+			ready = true
+		case !logkey.IsTrace(attr.Key):
+			count++
+		case attr.Key == logkey.TraceID:
+			traceID = attr.Value.String()
+		}
+		return true
+	})
+	return traceID, ready, count
+}
+
+// hasError reports whether the record has an error value, including in
+// groups.
+func hasError(r slog.Record) bool {
+	found := false
+	r.Attrs(func(attr slog.Attr) bool {
+		found = isErrorValue(attr.Value)
+		return !found
+	})
+	return found
+}
+
+func isErrorValue(v slog.Value) bool {
+	if v.Kind() == slog.KindGroup {
+		return slices.ContainsFunc(v.Group(), func(a slog.Attr) bool { return isErrorValue(a.Value) })
+	}
+	return logerr.ErrorOf(v) != nil
 }
 
 func appendTime(buf *Buffer, t time.Time) {
@@ -114,44 +293,140 @@ func appendTime(buf *Buffer, t time.Time) {
 	_ = buf.WriteByte('0' + byte(lo))
 }
 
-func appendLevel(buf *Buffer, r slog.Record) {
+func (h *DevHandler) appendLevel(buf *Buffer, level slog.Level, ready bool) {
 	switch {
-	case r.Level < slog.LevelInfo:
-		_, _ = buf.WriteString(tty.Magenta.Code())
-		_, _ = buf.WriteString("debug")
-		_, _ = buf.WriteString(tty.Reset.Code())
-	case r.Level < slog.LevelWarn:
-		if strings.HasPrefix(r.Message, readyPrefix) {
-			_, _ = buf.WriteString(tty.Green.Code())
-			_, _ = buf.WriteString("ready")
+	case level < slog.LevelInfo:
+		h.appendStyled(buf, tty.Fg(tty.Magenta), "debug")
+	case level < slog.LevelWarn:
+		if ready {
+			h.appendStyled(buf, tty.Fg(tty.Green), "ready")
 		} else {
-			_, _ = buf.WriteString(tty.Blue.Code())
-			_, _ = buf.WriteString("info")
+			h.appendStyled(buf, tty.Fg(tty.Blue), "info")
 		}
-		_, _ = buf.WriteString(tty.Reset.Code())
-	case r.Level < slog.LevelError:
-		_, _ = buf.WriteString(tty.Yellow.Code())
-		_, _ = buf.WriteString("warn")
-		_, _ = buf.WriteString(tty.Reset.Code())
+	case level < slog.LevelError:
+		h.appendStyled(buf, tty.Fg(tty.Yellow), "warn")
 	default:
-		_, _ = buf.WriteString(tty.Red.Code())
-		_, _ = buf.WriteString("error")
-		_, _ = buf.WriteString(tty.Reset.Code())
+		h.appendStyled(buf, tty.Fg(tty.Red), "error")
 	}
 }
 
-func appendAttr(buf *Buffer, attr slog.Attr) {
+// appendStyled appends s with the style if the handler uses color.
+func (h *DevHandler) appendStyled(buf *Buffer, style tty.Style, s string) {
+	*buf = h.color.AppendCode(*buf, style)
+	_, _ = buf.WriteString(s)
+	*buf = h.color.AppendReset(*buf)
+}
+
+// appendBuiltin appends the value of a built-in attr, like the time or level,
+// after applying ReplaceAttr. Uses appendSpecial to format the value, if
+// non-nil, falling back to appendValue if appendSpecial returns false, like
+// when ReplaceAttr changes the value type. Omits the attr if ReplaceAttr
+// returns an empty attr.
+func (h *DevHandler) appendBuiltin(buf *Buffer, attr slog.Attr, appendSpecial func(slog.Value) bool) {
+	if h.opts.ReplaceAttr != nil {
+		attr = h.opts.ReplaceAttr(nil, attr)
+		attr.Value = attr.Value.Resolve()
+		if attr.Equal(slog.Attr{}) {
+			return
+		}
+	}
+	if appendSpecial != nil && appendSpecial(attr.Value) {
+		return
+	}
+	appendValue(buf, attr.Value)
+}
+
+// appendAttr appends the attr with keys qualified by prefix. See
+// appendFlatAttr. Appends the details of errors to details, if not nil.
+func (h *DevHandler) appendAttr(buf, details *Buffer, groups []string, prefix string, attr slog.Attr) {
+	if details == nil {
+		appendFlatAttr(buf, h, h.opts.ReplaceAttr, groups, prefix, attr)
+		return
+	}
+	appendFlatAttr(buf, &devErrorPairs{h: h, details: details}, h.opts.ReplaceAttr, groups, prefix, attr)
+}
+
+// devErrorPairs appends pairs like DevHandler and the details of errors that
+// aren't plain, like wrapped errors or errors with a stack, to details.
+type devErrorPairs struct {
+	h       *DevHandler
+	details *Buffer
+}
+
+func (p *devErrorPairs) appendPair(buf *Buffer, prefix string, attr slog.Attr) {
+	p.h.appendPair(buf, prefix, attr)
+	if err := logerr.ErrorOf(attr.Value); err != nil {
+		if d := logerr.Describe(err); !d.IsPlain() {
+			p.h.appendErrorDetail(p.details, "    ", prefix+attr.Key+": ", d)
+		}
+	}
+}
+
+// appendErrorDetail appends the error detail on lines after the record, with
+// the message and type, the LogValue, the stack, and then the causes,
+// indented further:
+//
+//	err: load: open db: refused (*fmt.wrapError)
+//	  cause: open db: refused (*errors.errorString)
+//	      at main.openDB /app/db.go:42
+func (h *DevHandler) appendErrorDetail(buf *Buffer, indent, label string, d logerr.Detail) {
+	_, _ = buf.WriteString(indent)
+	_, _ = buf.WriteString(label)
+	_, _ = buf.WriteString(oneLine(d.Message))
+	_ = buf.WriteByte(' ')
+	h.appendStyled(buf, tty.Style{}.With(tty.Dim), "("+d.Type+")")
+	_ = buf.WriteByte('\n')
+	if !d.Value.Equal(slog.Value{}) {
+		_, _ = buf.WriteString(indent)
+		_, _ = buf.WriteString("   ") // appendPair adds a space
+		if d.Value.Kind() == slog.KindGroup {
+			for _, a := range d.Value.Group() {
+				appendFlatAttr(buf, h, nil, nil, "", a)
+			}
+		} else {
+			_ = buf.WriteByte(' ')
+			appendValue(buf, d.Value)
+		}
+		_ = buf.WriteByte('\n')
+	}
+	for _, f := range d.Stack {
+		_, _ = buf.WriteString(indent)
+		_, _ = buf.WriteString("    at ")
+		_, _ = buf.WriteString(f.Function)
+		_ = buf.WriteByte(' ')
+		*buf = h.color.AppendCode(*buf, tty.Style{}.With(tty.Dim))
+		appendSource(buf, &slog.Source{File: f.File, Line: f.Line})
+		*buf = h.color.AppendReset(*buf)
+		_ = buf.WriteByte('\n')
+	}
+	for _, c := range d.Causes {
+		h.appendErrorDetail(buf, indent+"  ", "cause: ", c)
+	}
+}
+
+// appendPair appends a flattened attr as " key=value", except for a
+// top-level url, which is shown bare so terminals recognize it as a link.
+func (h *DevHandler) appendPair(buf *Buffer, prefix string, attr slog.Attr) {
 	_ = buf.WriteByte(' ')
-	switch attr.Key {
-	case "url":
+	switch {
+	case attr.Key == "url" && prefix == "":
 		appendValue(buf, attr.Value)
 	default:
+		_, _ = buf.WriteString(prefix)
 		_, _ = buf.WriteString(attr.Key)
 		_ = buf.WriteByte('=')
 		appendValue(buf, attr.Value)
 	}
 }
 
+// appendSource appends the source as file:line, which most terminals and
+// editors recognize as a link.
+func appendSource(buf *Buffer, src *slog.Source) {
+	_, _ = buf.WriteString(src.File)
+	_ = buf.WriteByte(':')
+	*buf = strconv.AppendInt(*buf, int64(src.Line), 10)
+}
+
 func appendValue(buf *Buffer, v slog.Value) {
 	switch v.Kind() {
 	case slog.KindString:
@@ -167,31 +442,45 @@ func appendValue(buf *Buffer, v slog.Value) {
 	case slog.KindDuration:
 		_, _ = buf.WriteString(humanize.Duration(v.Duration()))
 	case slog.KindTime:
-		*buf = appendRFC3339Millis(*buf, v.Time())
+		*buf = slogfmt.AppendRFC3339Millis(*buf, v.Time())
 	case slog.KindAny:
 		a := v.Any()
 		switch a := a.(type) {
 		case error:
-			_, _ = buf.WriteString(a.Error())
+			appendErrorMessage(buf, a)
 		default:
 			_, _ = fmt.Fprint(buf, a)
 		}
-	case slog.KindGroup, slog.KindLogValuer:
-		panic(fmt.Sprintf("unsupported kind: %s", v.Kind()))
+	case slog.KindGroup:
+		// Groups are flattened by appendAttr, but may appear as values elsewhere.
+		_ = buf.WriteByte('{')
+		for i, ga := range v.Group() {
+			if i > 0 {
+				_ = buf.WriteByte(' ')
+			}
+			_, _ = buf.WriteString(ga.Key)
+			_ = buf.WriteByte('=')
+			appendValue(buf, ga.Value)
+		}
+		_ = buf.WriteByte('}')
+	case slog.KindLogValuer:
+		if err := logerr.ErrorOf(v); err != nil {
+			appendErrorMessage(buf, err)
+			return
+		}
+		appendValue(buf, v.Resolve())
 	default:
 		panic(fmt.Sprintf("bad kind: %s", v.Kind()))
 	}
 }
 
-func appendRFC3339Millis(b []byte, t time.Time) []byte {
-	// Format according to time.RFC3339Nano since it is highly optimized,
-	// but truncate it to use millisecond resolution.
-	// Unfortunately, that format trims trailing 0s, so add 1/10 millisecond
-	// to guarantee that there are exactly 4 digits after the period.
-	const prefixLen = len("2006-01-02T15:04:05.000")
-	n := len(b)
-	t = t.Truncate(time.Millisecond).Add(time.Millisecond / 10)
-	b = t.AppendFormat(b, time.RFC3339Nano)
-	b = append(b[:n+prefixLen], b[n+prefixLen+1:]...) // drop the 4th digit
-	return b
+// appendErrorMessage appends the error message on one line.
+func appendErrorMessage(buf *Buffer, err error) {
+	_, _ = buf.WriteString(oneLine(err.Error()))
+}
+
+// oneLine joins the lines of an error message, like from errors.Join, with
+// semicolons.
+func oneLine(msg string) string {
+	return strings.ReplaceAll(msg, "\n", "; ")
 }
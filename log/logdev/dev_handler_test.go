@@ -2,14 +2,20 @@ package log
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jschaf/observe/internal/difftest"
 	"github.com/jschaf/observe/internal/tty"
+	"github.com/jschaf/observe/log/logerr"
 )
 
 func TestDevHandler_Sample(t *testing.T) {
@@ -27,7 +33,7 @@ func TestDevHandler_Sample(t *testing.T) {
 func TestDevHandler_Handle(t *testing.T) {
 	ctx := t.Context()
 	buf := &bytes.Buffer{}
-	h := &DevHandler{w: buf}
+	h := &DevHandler{w: buf, color: tty.ModeBasic}
 
 	err := h.Handle(ctx, slog.Record{
 		Time:    time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC),
@@ -42,10 +48,392 @@ func TestDevHandler_Handle(t *testing.T) {
 	difftest.AssertSame(t, "DevHandler mismatch", want, got)
 }
 
+func TestDevHandler_Handle_TraceID(t *testing.T) {
+	ctx := t.Context()
+	buf := &bytes.Buffer{}
+	h := &DevHandler{w: buf, color: tty.ModeBasic}
+
+	r := slog.Record{
+		Time:    time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC),
+		Message: "msg",
+	}
+	r.AddAttrs(
+		slog.String("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736"),
+		slog.String("span_id", "00f067aa0ba902b7"),
+		slog.Bool("trace_sampled", true),
+		slog.Int("a", 1),
+	)
+	err := h.Handle(ctx, r)
+	if err != nil {
+		t.Fatalf("handle record: %v", err)
+	}
+	got := buf.String()
+
+	want := fmt.Sprintf("12:00:00.000\t%s\t%s msg%s a=1\n",
+		tty.Blue.Add("info"), tty.Cyan.Add("4bf92f35"), alignStr[:align-len("4bf92f35 msg")])
+	difftest.AssertSame(t, "DevHandler mismatch", want, got)
+}
+
+func TestDevHandler_Handle_Ready(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs []slog.Attr
+		want  string
+	}{
+		{
+			name:  "ready",
+			attrs: []slog.Attr{slog.Bool("ready", true), slog.Int("port", 8080)},
+			want:  fmt.Sprintf("\t%s\tlistening%s port=8080\n", tty.Green.Add("ready"), alignStr[:align-len("listening")]),
+		},
+		{
+			name:  "ready only",
+			attrs: []slog.Attr{slog.Bool("ready", true)},
+			want:  fmt.Sprintf("\t%s\tlistening\n", tty.Green.Add("ready")),
+		},
+		{
+			name:  "not ready",
+			attrs: []slog.Attr{slog.Bool("ready", false)},
+			want:  fmt.Sprintf("\t%s\tlistening%s ready=false\n", tty.Blue.Add("info"), alignStr[:align-len("listening")]),
+		},
+		{
+			name:  "ready string",
+			attrs: []slog.Attr{slog.String("ready", "yes")},
+			want:  fmt.Sprintf("\t%s\tlistening%s ready=yes\n", tty.Blue.Add("info"), alignStr[:align-len("listening")]),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			h := &DevHandler{w: buf, color: tty.ModeBasic}
+			r := slog.Record{Message: "listening"}
+			r.AddAttrs(tt.attrs...)
+			if err := h.Handle(t.Context(), r); err != nil {
+				t.Fatalf("handle record: %v", err)
+			}
+			difftest.AssertSame(t, "DevHandler mismatch", tt.want, buf.String())
+		})
+	}
+}
+
+type codeError struct{ code int }
+
+func (e *codeError) Error() string { return "code " + strconv.Itoa(e.code) }
+
+func (e *codeError) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("code", e.code))
+}
+
+func TestDevHandler_Handle_Error(t *testing.T) {
+	pad := alignStr[:align-len("msg")]
+	tests := []struct {
+		name  string
+		attrs []slog.Attr
+		want  []string
+	}{
+		{
+			name:  "plain",
+			attrs: []slog.Attr{slog.Any("err", errors.New("boom"))},
+			want:  []string{"\tinfo\tmsg" + pad + " err=boom"},
+		},
+		{
+			name:  "wrapped",
+			attrs: []slog.Attr{slog.Any("err", fmt.Errorf("call: %w", &codeError{code: 7}))},
+			want: []string{
+				"\tinfo\tmsg" + pad + " err=call: code 7",
+				"    err: call: code 7 (*fmt.wrapError)",
+				"      cause: code 7 (*log.codeError)",
+				"          code=7",
+			},
+		},
+		{
+			name:  "log valuer",
+			attrs: []slog.Attr{slog.Any("err", &codeError{code: 7})},
+			want: []string{
+				"\tinfo\tmsg" + pad + " err=code 7",
+				"    err: code 7 (*log.codeError)",
+				"        code=7",
+			},
+		},
+		{
+			name:  "joined in group",
+			attrs: []slog.Attr{slog.Group("req", slog.Any("err", errors.Join(io.EOF, io.ErrUnexpectedEOF)))},
+			want: []string{
+				"\tinfo\tmsg" + pad + " req.err=EOF; unexpected EOF",
+				"    req.err: EOF; unexpected EOF (*errors.joinError)",
+				"      cause: EOF (*errors.errorString)",
+				"      cause: unexpected EOF (*errors.errorString)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			h := &DevHandler{w: buf}
+			r := slog.Record{Message: "msg"}
+			r.AddAttrs(tt.attrs...)
+			if err := h.Handle(t.Context(), r); err != nil {
+				t.Fatalf("handle record: %v", err)
+			}
+			difftest.AssertSame(t, "DevHandler mismatch", tt.want, strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"))
+		})
+	}
+}
+
+func TestDevHandler_Handle_ErrorReplaceAttr(t *testing.T) {
+	opts := &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			switch {
+			case a.Key == slog.TimeKey:
+				return slog.Attr{}
+			case a.Value.Kind() == slog.KindLogValuer:
+				return slog.String(a.Key, "unresolved")
+			case a.Key == "code":
+				return slog.Attr{Key: a.Key, Value: a.Value.Group()[0].Value}
+			}
+			return a
+		},
+	}
+	buf := &bytes.Buffer{}
+	l := slog.New(NewDevHandler(buf, opts, WithColorMode(ColorNever)))
+	l.Info("msg", "err", &codeError{code: 7}, "code", &codeError{code: 8})
+
+	want := []string{
+		"\tinfo\tmsg" + alignStr[:align-len("msg")] + " err=code 7 code=8",
+		"    err: code 7 (*log.codeError)",
+		"        code=7",
+	}
+	difftest.AssertSame(t, "DevHandler mismatch", want, strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"))
+}
+
+func TestDevHandler_Handle_ErrorStack(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := slog.New(NewDevHandler(buf, nil, WithColorMode(ColorNever))).With("err", logerr.New("boom"))
+	l.Info("msg", "n", 1)
+
+	lines := strings.Split(buf.String(), "\n")
+	difftest.AssertSame(t, "record", " err=boom n=1", lines[0][strings.Index(lines[0], " err="):])
+	difftest.AssertSame(t, "error", "    err: boom (*errors.errorString)", lines[1])
+	wantFrame := "        at github.com/jschaf/observe/log/logdev.TestDevHandler_Handle_ErrorStack "
+	if !strings.HasPrefix(lines[2], wantFrame) || !strings.Contains(lines[2], "dev_handler_test.go:") {
+		t.Errorf("got first frame %q, want prefix %q", lines[2], wantFrame)
+	}
+}
+
+func TestDevHandler_WithAttrs(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(h slog.Handler) slog.Handler
+		attrs []slog.Attr
+		want  string
+	}{
+		{
+			name:  "with attrs",
+			build: func(h slog.Handler) slog.Handler { return h.WithAttrs([]slog.Attr{slog.String("svc", "api")}) },
+			attrs: []slog.Attr{slog.Int("a", 1)},
+			want:  " svc=api a=1",
+		},
+		{
+			name:  "with attrs only",
+			build: func(h slog.Handler) slog.Handler { return h.WithAttrs([]slog.Attr{slog.String("svc", "api")}) },
+			want:  " svc=api",
+		},
+		{
+			name:  "with group",
+			build: func(h slog.Handler) slog.Handler { return h.WithGroup("req") },
+			attrs: []slog.Attr{slog.Int("a", 1), slog.Int("b", 2)},
+			want:  " req.a=1 req.b=2",
+		},
+		{
+			name: "with attrs and nested groups",
+			build: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.Int("top", 0)}).
+					WithGroup("g1").WithAttrs([]slog.Attr{slog.Int("x", 1)}).
+					WithGroup("g2")
+			},
+			attrs: []slog.Attr{slog.Int("y", 2)},
+			want:  " top=0 g1.x=1 g1.g2.y=2",
+		},
+		{
+			name:  "empty group name",
+			build: func(h slog.Handler) slog.Handler { return h.WithGroup("") },
+			attrs: []slog.Attr{slog.Int("a", 1)},
+			want:  " a=1",
+		},
+		{
+			name:  "group without attrs",
+			build: func(h slog.Handler) slog.Handler { return h.WithGroup("empty") },
+			want:  "",
+		},
+		{
+			name:  "group attr",
+			build: func(h slog.Handler) slog.Handler { return h },
+			attrs: []slog.Attr{slog.Group("req", slog.String("method", "GET"), slog.Group("url", slog.String("path", "/")))},
+			want:  " req.method=GET req.url.path=/",
+		},
+		{
+			name:  "inline group attr",
+			build: func(h slog.Handler) slog.Handler { return h.WithGroup("g") },
+			attrs: []slog.Attr{slog.Group("", slog.Int("a", 1)), slog.Int("b", 2)},
+			want:  " g.a=1 g.b=2",
+		},
+		{
+			name:  "empty group attr",
+			build: func(h slog.Handler) slog.Handler { return h },
+			attrs: []slog.Attr{slog.Group("empty"), slog.Int("a", 1)},
+			want:  " a=1",
+		},
+		{
+			name:  "empty attr",
+			build: func(h slog.Handler) slog.Handler { return h },
+			attrs: []slog.Attr{{}, slog.Int("a", 1)},
+			want:  " a=1",
+		},
+		{
+			name:  "group value",
+			build: func(h slog.Handler) slog.Handler { return h },
+			attrs: []slog.Attr{slog.Any("m", slog.GroupValue(slog.Int("a", 1), slog.Int("b", 2)))},
+			want:  " m.a=1 m.b=2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			h := tt.build(NewDevHandler(buf, nil, WithColorMode(ColorBasic)))
+			r := slog.Record{
+				Time:    time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC),
+				Message: "msg",
+			}
+			r.AddAttrs(tt.attrs...)
+			if err := h.Handle(t.Context(), r); err != nil {
+				t.Fatalf("handle record: %v", err)
+			}
+
+			pad := ""
+			if tt.want != "" {
+				pad = alignStr[:align-len("msg")]
+			}
+			want := fmt.Sprintf("12:00:00.000\t%s\tmsg%s%s\n", tty.Blue.Add("info"), pad, tt.want)
+			difftest.AssertSame(t, "DevHandler mismatch", want, buf.String())
+		})
+	}
+}
+
+func TestDevHandler_LogValuer(t *testing.T) {
+	tests := []struct {
+		name string
+		attr slog.Attr
+		want string
+	}{
+		{
+			name: "string",
+			attr: slog.Any("user", userValuer{name: "alice"}),
+			want: " user=alice",
+		},
+		{
+			name: "group",
+			attr: slog.Any("req", groupValuer{}),
+			want: " req.method=GET req.user=bob",
+		},
+		{
+			name: "cycle",
+			attr: slog.Any("loop", cycleValuer{}),
+			want: " loop=LogValue called too many times on Value of type log.cycleValuer",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			r := slog.Record{Message: "msg"}
+			r.AddAttrs(tt.attr)
+			if err := NewDevHandler(buf, nil, WithColorMode(ColorBasic)).Handle(t.Context(), r); err != nil {
+				t.Fatalf("handle record: %v", err)
+			}
+			want := fmt.Sprintf("\t%s\tmsg%s%s\n", tty.Blue.Add("info"), alignStr[:align-len("msg")], tt.want)
+			difftest.AssertSame(t, "DevHandler mismatch", want, buf.String())
+		})
+	}
+}
+
+type userValuer struct{ name string }
+
+func (u userValuer) LogValue() slog.Value { return slog.StringValue(u.name) }
+
+type groupValuer struct{}
+
+func (groupValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("method", "GET"), slog.Any("user", userValuer{name: "bob"}))
+}
+
+type cycleValuer struct{}
+
+func (c cycleValuer) LogValue() slog.Value { return slog.AnyValue(c) }
+
+func TestDevHandler_ReplaceAttr(t *testing.T) {
+	var gotGroups []string
+	opts := &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch a.Key {
+			case slog.TimeKey:
+				return slog.Attr{}
+			case slog.LevelKey:
+				return slog.String(slog.LevelKey, "NOTICE")
+			case slog.MessageKey:
+				return slog.String(slog.MessageKey, strings.ToUpper(a.Value.String()))
+			case "password":
+				gotGroups = append(gotGroups, strings.Join(groups, "."))
+				return slog.String(a.Key, "REDACTED")
+			case "drop":
+				return slog.Attr{}
+			}
+			return a
+		},
+	}
+	buf := &bytes.Buffer{}
+	h := NewDevHandler(buf, opts, WithColorMode(ColorBasic)).WithGroup("g").WithAttrs([]slog.Attr{slog.String("password", "bound")})
+	r := slog.Record{
+		Time:    time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC),
+		Message: "msg",
+	}
+	r.AddAttrs(
+		slog.Group("user", slog.String("password", "hunter2"), slog.String("name", "alice")),
+		slog.Int("drop", 1),
+	)
+	if err := h.Handle(t.Context(), r); err != nil {
+		t.Fatalf("handle record: %v", err)
+	}
+
+	want := fmt.Sprintf("\tNOTICE\tMSG%s g.password=REDACTED g.user.password=REDACTED g.user.name=alice\n", alignStr[:align-len("MSG")])
+	difftest.AssertSame(t, "DevHandler mismatch", want, buf.String())
+	difftest.AssertSame(t, "ReplaceAttr groups mismatch", []string{"g", "g.user"}, gotGroups)
+}
+
+func TestDevHandler_AddSource(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h := NewDevHandler(buf, &slog.HandlerOptions{AddSource: true}, WithColorMode(ColorBasic))
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	_, file, line, _ := runtime.Caller(0)
+	r := slog.NewRecord(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC), slog.LevelInfo, "msg", pcs[0])
+	r.AddAttrs(slog.Int("a", 1))
+	if err := h.Handle(t.Context(), r); err != nil {
+		t.Fatalf("handle record: %v", err)
+	}
+
+	want := fmt.Sprintf("12:00:00.000\t%s\tmsg%s a=1 %s:%d\n", tty.Blue.Add("info"), alignStr[:align-len("msg")], file, line-1)
+	difftest.AssertSame(t, "DevHandler mismatch", want, buf.String())
+}
+
+func TestAppendValue_Group(t *testing.T) {
+	buf := NewBuffer()
+	defer buf.Free()
+	appendValue(buf, slog.GroupValue(slog.Int("a", 1), slog.String("b", "two")))
+	difftest.AssertSame(t, "appendValue mismatch", "{a=1 b=two}", buf.String())
+}
+
 func BenchmarkDevHandler_Handle(b *testing.B) {
 	ctx := b.Context()
 	buf := &bytes.Buffer{}
-	h := &DevHandler{w: buf}
+	h := &DevHandler{w: buf, color: tty.ModeBasic}
 
 	r := slog.Record{
 		Time:    time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC),
@@ -64,3 +452,31 @@ func BenchmarkDevHandler_Handle(b *testing.B) {
 		}
 	}
 }
+
+func TestDevHandler_ColorMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		forceColor string
+		mode       ColorMode
+		want       string
+	}{
+		{"auto non-terminal", "", ColorAuto, "info"},
+		{"auto forced", "1", ColorAuto, tty.Blue.Add("info")},
+		{"never forced", "1", ColorNever, "info"},
+		{"basic", "", ColorBasic, tty.Blue.Add("info")},
+		{"256", "", Color256, tty.Blue.Add("info")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "")
+			t.Setenv("CLICOLOR_FORCE", "")
+			t.Setenv("FORCE_COLOR", tt.forceColor)
+			buf := &bytes.Buffer{}
+			r := slog.Record{Message: "msg"}
+			if err := NewDevHandler(buf, nil, WithColorMode(tt.mode)).Handle(t.Context(), r); err != nil {
+				t.Fatalf("handle record: %v", err)
+			}
+			difftest.AssertSame(t, "DevHandler mismatch", "\t"+tt.want+"\tmsg\n", buf.String())
+		})
+	}
+}
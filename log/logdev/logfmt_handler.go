@@ -0,0 +1,249 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jschaf/observe/internal/humanize"
+	"github.com/jschaf/observe/internal/logkey"
+	"github.com/jschaf/observe/internal/slogfmt"
+	"github.com/jschaf/observe/log/logerr"
+)
+
+// LogfmtOptions configures a LogfmtHandler.
+type LogfmtOptions struct {
+	slog.HandlerOptions
+
+	// HumanDurations renders durations with humanize.Duration, like
+	// "1.5 ms", instead of time.Duration.String, like 1.5ms.
+	HumanDurations bool
+}
+
+// LogfmtHandler is a slog.Handler that writes records as logfmt lines of
+// space-separated key=value pairs, like:
+//
+//	time=2024-01-01T12:00:00.000Z level=INFO msg="request done" req.path=/ dur=1.5ms
+//
+// Quotes values containing spaces, quotes, equal signs, or non-printable
+// characters. Flattens groups into dotted keys, like DevHandler.
+type LogfmtHandler struct {
+	w    io.Writer
+	opts LogfmtOptions
+	// preAttrs are the attrs bound with WithAttrs, rendered once so that
+	// each record doesn't re-format them. Starts with a space if not empty.
+	preAttrs []byte
+	// groups are the group names from WithGroup, passed to ReplaceAttr.
+	groups []string
+	// groupPrefix is the dotted key prefix from WithGroup, like "a.b.".
+	groupPrefix string
+}
+
+func NewLogfmtHandler(w io.Writer, opts *LogfmtOptions) *LogfmtHandler {
+	if opts == nil {
+		opts = &LogfmtOptions{}
+	}
+	return &LogfmtHandler{w: w, opts: *opts}
+}
+
+func (h *LogfmtHandler) Enabled(_ context.Context, l slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return l >= minLevel
+}
+
+func (h *LogfmtHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := NewBuffer()
+	defer buf.Free()
+
+	// Each pair starts with a space, trimmed from the first pair on write.
+	if !r.Time.IsZero() {
+		h.appendBuiltin(buf, slog.Time(slog.TimeKey, r.Time))
+	}
+	h.appendBuiltin(buf, slog.Any(slog.LevelKey, r.Level))
+	if h.opts.AddSource && r.PC != 0 {
+		h.appendBuiltin(buf, slog.Any(slog.SourceKey, slogfmt.RecordSource(r)))
+	}
+	h.appendBuiltin(buf, slog.String(slog.MessageKey, r.Message))
+
+	_, _ = buf.Write(h.preAttrs)
+	r.Attrs(func(attr slog.Attr) bool {
+		if logkey.IsTrace(attr.Key) {
+			h.appendPair(buf, "", attr) // keep trace attrs top-level for correlation
+			return true
+		}
+		appendFlatAttr(buf, h, h.opts.ReplaceAttr, h.groups, h.groupPrefix, attr)
+		return true
+	})
+	_ = buf.WriteByte('\n')
+
+	line := *buf
+	if line[0] == ' ' {
+		line = line[1:]
+	}
+	_, err := h.w.Write(line)
+	if err != nil {
+		return fmt.Errorf("write record: %w", err)
+	}
+	return nil
+}
+
+// WithAttrs returns a handler that includes attrs in every record. Renders
+// attrs once, when called, instead of for each record.
+func (h *LogfmtHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	buf := NewBuffer()
+	defer buf.Free()
+	for _, attr := range attrs {
+		appendFlatAttr(buf, h, h.opts.ReplaceAttr, h.groups, h.groupPrefix, attr)
+	}
+	h2 := *h
+	h2.preAttrs = slices.Concat(h.preAttrs, *buf)
+	return &h2
+}
+
+// WithGroup returns a handler that qualifies the keys of subsequent attrs
+// with the group name, like "group.key".
+func (h *LogfmtHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(slices.Clip(h.groups), name)
+	h2.groupPrefix = h.groupPrefix + name + "."
+	return &h2
+}
+
+// appendBuiltin appends a built-in attr, like the time or level, after
+// applying ReplaceAttr. Omits the attr if ReplaceAttr returns an empty attr.
+func (h *LogfmtHandler) appendBuiltin(buf *Buffer, attr slog.Attr) {
+	if h.opts.ReplaceAttr != nil {
+		attr = h.opts.ReplaceAttr(nil, attr)
+		attr.Value = attr.Value.Resolve()
+		if attr.Equal(slog.Attr{}) {
+			return
+		}
+	}
+	h.appendPair(buf, "", attr)
+}
+
+// appendPair appends a flattened attr as " key=value". Flattens group values,
+// which only occur if ReplaceAttr returns a group.
+func (h *LogfmtHandler) appendPair(buf *Buffer, prefix string, attr slog.Attr) {
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, ga := range attr.Value.Group() {
+			h.appendPair(buf, prefix, ga)
+		}
+		return
+	}
+	_ = buf.WriteByte(' ')
+	appendLogfmtKey(buf, prefix)
+	appendLogfmtKey(buf, attr.Key)
+	_ = buf.WriteByte('=')
+	h.appendValue(buf, attr.Value)
+}
+
+func (h *LogfmtHandler) appendValue(buf *Buffer, v slog.Value) {
+	switch v.Kind() {
+	case slog.KindString:
+		appendLogfmtString(buf, v.String())
+	case slog.KindInt64:
+		*buf = strconv.AppendInt(*buf, v.Int64(), 10)
+	case slog.KindUint64:
+		*buf = strconv.AppendUint(*buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		*buf = strconv.AppendFloat(*buf, v.Float64(), 'g', -1, 64)
+	case slog.KindBool:
+		*buf = strconv.AppendBool(*buf, v.Bool())
+	case slog.KindDuration:
+		if h.opts.HumanDurations {
+			appendLogfmtString(buf, humanize.Duration(v.Duration()))
+		} else {
+			_, _ = buf.WriteString(v.Duration().String())
+		}
+	case slog.KindTime:
+		*buf = slogfmt.AppendRFC3339Millis(*buf, v.Time())
+	case slog.KindAny:
+		switch a := v.Any().(type) {
+		case slog.Level:
+			_, _ = buf.WriteString(a.String())
+		case *slog.Source:
+			appendLogfmtString(buf, a.File+":"+strconv.Itoa(a.Line))
+		case error:
+			appendLogfmtString(buf, a.Error())
+		case time.Duration:
+			h.appendValue(buf, slog.DurationValue(a))
+		default:
+			appendLogfmtString(buf, fmt.Sprint(a))
+		}
+	case slog.KindGroup, slog.KindLogValuer:
+		if err := logerr.ErrorOf(v); err != nil {
+			appendLogfmtString(buf, err.Error())
+			return
+		}
+		// Groups are flattened by appendPair and LogValuers resolved by
+		// appendFlatAttr, but may appear here if nested in a group value.
+		h.appendValue(buf, v.Resolve())
+	default:
+		panic(fmt.Sprintf("bad kind: %s", v.Kind()))
+	}
+}
+
+// appendLogfmtKey appends the key, replacing characters that would need
+// quoting with underscores since logfmt keys can't be quoted.
+func appendLogfmtKey(buf *Buffer, key string) {
+	if !needsLogfmtQuote(key) {
+		_, _ = buf.WriteString(key)
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			r = '_'
+		}
+		*buf = utf8.AppendRune(*buf, r)
+	}
+}
+
+// appendLogfmtString appends s, quoted and escaped as a Go string if s is
+// empty or contains characters that would break logfmt parsing.
+func appendLogfmtString(buf *Buffer, s string) {
+	if s == "" || needsLogfmtQuote(s) {
+		*buf = strconv.AppendQuote(*buf, s)
+		return
+	}
+	_, _ = buf.WriteString(s)
+}
+
+// needsLogfmtQuote reports whether s contains a space, equal sign, quote,
+// or non-printable character, including invalid UTF-8.
+func needsLogfmtQuote(s string) bool {
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b <= ' ' || b == '=' || b == '"' || b == '\\' || b == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestLogfmtHandler_Handle(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  *LogfmtOptions
+		build func(h slog.Handler) slog.Handler
+		attrs []slog.Attr
+		want  string
+	}{
+		{
+			name: "no attrs",
+			want: "",
+		},
+		{
+			name: "common kinds",
+			attrs: []slog.Attr{
+				slog.String("s", "str"),
+				slog.Int("i", -1),
+				slog.Uint64("u", 2),
+				slog.Float64("f", 1.5),
+				slog.Bool("b", true),
+				slog.Duration("d", 1500*time.Microsecond),
+				slog.Time("t", time.Date(2024, time.January, 1, 12, 0, 0, 123e6, time.UTC)),
+				slog.Any("err", errors.New("no such file")),
+				slog.Any("m", map[string]int{"a": 1}),
+			},
+			want: ` s=str i=-1 u=2 f=1.5 b=true d=1.5ms t=2024-01-01T12:00:00.123Z err="no such file" m=map[a:1]`,
+		},
+		{
+			name:  "log valuer error",
+			attrs: []slog.Attr{slog.Any("err", &codeError{code: 7})},
+			want:  ` err="code 7"`,
+		},
+		{
+			name:  "human durations",
+			opts:  &LogfmtOptions{HumanDurations: true},
+			attrs: []slog.Attr{slog.Duration("d", 1500*time.Microsecond), slog.Duration("zero", 0)},
+			want:  ` d="1.5 ms" zero=0`,
+		},
+		{
+			name: "quotes values",
+			attrs: []slog.Attr{
+				slog.String("empty", ""),
+				slog.String("space", "a b"),
+				slog.String("eq", "a=b"),
+				slog.String("quote", `say "hi"`),
+				slog.String("backslash", `a\b`),
+				slog.String("newline", "a\nb"),
+				slog.String("nbsp", "a\u00a0b"),
+				slog.String("invalid", "a\xffb"),
+				slog.String("unicode", "héllo"),
+			},
+			want: ` empty="" space="a b" eq="a=b" quote="say \"hi\"" backslash="a\\b" newline="a\nb" nbsp="a\u00a0b" invalid="a\xffb" unicode=héllo`,
+		},
+		{
+			name:  "sanitizes keys",
+			attrs: []slog.Attr{slog.Int("a b", 1), slog.Int("a=b", 2), slog.Int(`a"b`, 3)},
+			want:  ` a_b=1 a_b=2 a_b=3`,
+		},
+		{
+			name:  "group attr",
+			attrs: []slog.Attr{slog.Group("req", slog.String("method", "GET"), slog.Group("url", slog.String("path", "/"))), slog.Group("empty")},
+			want:  ` req.method=GET req.url.path=/`,
+		},
+		{
+			name:  "log valuer",
+			attrs: []slog.Attr{slog.Any("user", userValuer{name: "alice"}), slog.Any("req", groupValuer{})},
+			want:  ` user=alice req.method=GET req.user=bob`,
+		},
+		{
+			name: "with attrs and nested groups",
+			build: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.Int("top", 0)}).
+					WithGroup("g1").WithAttrs([]slog.Attr{slog.Int("x", 1)}).
+					WithGroup("g2")
+			},
+			attrs: []slog.Attr{slog.Int("y", 2)},
+			want:  ` top=0 g1.x=1 g1.g2.y=2`,
+		},
+		{
+			name:  "trace attrs are top-level",
+			build: func(h slog.Handler) slog.Handler { return h.WithGroup("g") },
+			attrs: []slog.Attr{slog.String("trace_id", "abc"), slog.String("span_id", "def"), slog.Int("a", 1)},
+			want:  ` trace_id=abc span_id=def g.a=1`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			var h slog.Handler = NewLogfmtHandler(buf, tt.opts)
+			if tt.build != nil {
+				h = tt.build(h)
+			}
+			r := slog.NewRecord(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC), slog.LevelInfo, "msg", 0)
+			r.AddAttrs(tt.attrs...)
+			if err := h.Handle(t.Context(), r); err != nil {
+				t.Fatalf("handle record: %v", err)
+			}
+
+			want := "time=2024-01-01T12:00:00.000Z level=INFO msg=msg" + tt.want + "\n"
+			difftest.AssertSame(t, "LogfmtHandler mismatch", want, buf.String())
+		})
+	}
+}
+
+func TestLogfmtHandler_ReplaceAttr(t *testing.T) {
+	var gotGroups []string
+	opts := &LogfmtOptions{
+		HandlerOptions: slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				switch a.Key {
+				case slog.TimeKey:
+					return slog.Attr{}
+				case slog.LevelKey:
+					return slog.String("severity", "notice")
+				case "password":
+					gotGroups = append(gotGroups, strings.Join(groups, "."))
+					return slog.String(a.Key, "REDACTED")
+				case "expand":
+					return slog.Group("exp", slog.Int("a", 1), slog.Int("b", 2))
+				}
+				return a
+			},
+		},
+	}
+	buf := &bytes.Buffer{}
+	h := NewLogfmtHandler(buf, opts).WithGroup("g")
+	r := slog.NewRecord(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC), slog.LevelInfo, "hello world", 0)
+	r.AddAttrs(slog.Group("user", slog.String("password", "hunter2")), slog.Int("expand", 0))
+	if err := h.Handle(t.Context(), r); err != nil {
+		t.Fatalf("handle record: %v", err)
+	}
+
+	want := `severity=notice msg="hello world" g.user.password=REDACTED g.exp.a=1 g.exp.b=2` + "\n"
+	difftest.AssertSame(t, "LogfmtHandler mismatch", want, buf.String())
+	difftest.AssertSame(t, "ReplaceAttr groups mismatch", []string{"g.user"}, gotGroups)
+}
+
+func TestLogfmtHandler_AddSource(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h := NewLogfmtHandler(buf, &LogfmtOptions{HandlerOptions: slog.HandlerOptions{AddSource: true}})
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	_, file, line, _ := runtime.Caller(0)
+	r := slog.NewRecord(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC), slog.LevelWarn, "msg", pcs[0])
+	if err := h.Handle(t.Context(), r); err != nil {
+		t.Fatalf("handle record: %v", err)
+	}
+
+	want := "time=2024-01-01T12:00:00.000Z level=WARN source=" + file + ":" + strconv.Itoa(line-1) + " msg=msg\n"
+	difftest.AssertSame(t, "LogfmtHandler mismatch", want, buf.String())
+}
+
+func BenchmarkLogfmtHandler_Handle(b *testing.B) {
+	ctx := b.Context()
+	buf := &bytes.Buffer{}
+	h := NewLogfmtHandler(buf, nil).WithAttrs([]slog.Attr{slog.String("svc", "api")}).WithGroup("req")
+
+	r := slog.NewRecord(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC), slog.LevelInfo, "request handled", 0)
+	r.AddAttrs(
+		slog.String("method", "GET"),
+		slog.String("path", "/items/123"),
+		slog.Int("status", 200),
+		slog.Float64("ratio", 0.25),
+		slog.Bool("cached", true),
+	)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		buf.Reset() // reset the buffer to avoid accumulation of data
+		err := h.Handle(ctx, r)
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
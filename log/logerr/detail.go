@@ -0,0 +1,126 @@
+package logerr
+
+import (
+	"log/slog"
+	"reflect"
+)
+
+// maxDepth is the maximum depth of wrapped errors to describe, guarding
+// against cycles in Unwrap.
+const maxDepth = 32
+
+// Detail describes an error and the errors it wraps, for log handlers to
+// render errors as structured values.
+type Detail struct {
+	// Message is the error message.
+	Message string
+	// Type is the Go type of the error, like "*fs.PathError".
+	Type string
+	// Value is the resolved value of an error that implements
+	// slog.LogValuer, or the zero value otherwise.
+	Value slog.Value
+	// Stack is the stack of where the error was created by New, Errorf, or
+	// WithStack, innermost call first.
+	Stack []Frame
+	// Causes are the wrapped errors: one for errors wrapped with %w, or one
+	// for each joined error for errors.Join.
+	Causes []Detail
+}
+
+// Describe returns the detail of the error and the errors it wraps.
+func Describe(err error) Detail {
+	return describe(err, 0)
+}
+
+func describe(err error, depth int) Detail {
+	if se, ok := err.(*stackError); ok { //nolint:errorlint // only the outer error
+		d := describe(se.err, depth)
+		d.Stack = frames(se.stack)
+		return d
+	}
+	d := Detail{Message: err.Error(), Type: reflect.TypeOf(err).String()}
+	if lv, ok := err.(slog.LogValuer); ok {
+		d.Value = slog.AnyValue(lv).Resolve() // recovers from panics in LogValue
+	}
+	if depth >= maxDepth {
+		return d
+	}
+	switch x := err.(type) { //nolint:errorlint // only the outer error
+	case interface{ Unwrap() error }:
+		if cause := x.Unwrap(); cause != nil {
+			d.Causes = []Detail{describe(cause, depth+1)}
+		}
+	case interface{ Unwrap() []error }:
+		for _, cause := range x.Unwrap() {
+			if cause != nil {
+				d.Causes = append(d.Causes, describe(cause, depth+1))
+			}
+		}
+	}
+	return d
+}
+
+// IsPlain reports whether the detail has only a message and type, so
+// handlers may render the error as the message alone.
+func (d Detail) IsPlain() bool {
+	return d.Value.Equal(slog.Value{}) && len(d.Stack) == 0 && len(d.Causes) == 0
+}
+
+// ErrorOf returns the error in a log value, either an error value or an
+// error that implements slog.LogValuer, or nil if none. Handlers that resolve
+// values must check LogValuers before resolving, like with Resolve, to
+// render them as errors.
+func ErrorOf(v slog.Value) error {
+	switch v.Kind() {
+	case slog.KindAny:
+		err, _ := v.Any().(error)
+		return err
+	case slog.KindLogValuer:
+		err, _ := v.LogValuer().(error)
+		return err
+	default:
+		return nil
+	}
+}
+
+// Resolve is like slog.Value.Resolve but keeps errors that implement
+// slog.LogValuer unresolved, so that handlers render them as errors. Like
+// slog.Value.Resolve, guards against LogValue cycles by giving up after many
+// calls. Use ReplaceAttr to apply slog.HandlerOptions.ReplaceAttr, which
+// expects resolved values.
+func Resolve(v slog.Value) slog.Value {
+	if v.Kind() == slog.KindLogValuer && ErrorOf(v) != nil {
+		return v
+	}
+	return v.Resolve()
+}
+
+// ReplaceAttr applies replace, a slog.HandlerOptions.ReplaceAttr, to the attr
+// with its value resolved, and returns the replaced attr with its value
+// resolved. If the attr is an error that implements slog.LogValuer and
+// replace keeps the resolved value, returns the error unresolved, like
+// Resolve, so that handlers still render it as an error.
+func ReplaceAttr(replace func([]string, slog.Attr) slog.Attr, groups []string, a slog.Attr) slog.Attr {
+	orig := a.Value
+	resolved := orig.Resolve()
+	a.Value = resolved
+	a = replace(groups, a)
+	a.Value = a.Value.Resolve()
+	if ErrorOf(orig) != nil && orig.Kind() == slog.KindLogValuer &&
+		!a.Equal(slog.Attr{}) && sameValue(a.Value, resolved) {
+		a.Value = orig
+	}
+	return a
+}
+
+// sameValue is like slog.Value.Equal but doesn't panic on incomparable values
+// of KindAny, like slices.
+func sameValue(a, b slog.Value) bool {
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	if a.Kind() == slog.KindAny || a.Kind() == slog.KindGroup {
+		return reflect.DeepEqual(a.Any(), b.Any())
+	}
+	return a.Equal(b)
+}
@@ -0,0 +1,111 @@
+// Package logerr creates errors with stack traces and describes errors as
+// structured values for log handlers, like the chain of wrapped errors.
+package logerr
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxStackDepth is the maximum number of frames in a captured stack.
+const maxStackDepth = 32
+
+// New returns an error with the message and the stack of the caller.
+func New(msg string) error {
+	return &stackError{err: errors.New(msg), stack: callers()}
+}
+
+// Errorf returns an error formatted like fmt.Errorf, including wrapping
+// errors with %w. Captures the stack of the caller unless a wrapped error
+// already has a stack.
+func Errorf(format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	if hasStack(err) {
+		return err
+	}
+	return &stackError{err: err, stack: callers()}
+}
+
+// WithStack returns the error with the stack of the caller, or the error
+// unchanged if nil or if it already has a stack.
+func WithStack(err error) error {
+	if err == nil || hasStack(err) {
+		return err
+	}
+	return &stackError{err: err, stack: callers()}
+}
+
+// stackError is an error with the stack of where it was created. Transparent
+// in the error chain, so errors.Is and errors.As see the wrapped error.
+type stackError struct {
+	err   error
+	stack []uintptr
+}
+
+func (e *stackError) Error() string { return e.err.Error() }
+
+func (e *stackError) Unwrap() error { return e.err }
+
+// hasStack reports whether any error in the chain has a stack.
+func hasStack(err error) bool {
+	var se *stackError
+	return errors.As(err, &se)
+}
+
+// callers returns the stack of the caller of the function calling callers.
+func callers() []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs) // skip [Callers, callers, New]
+	return pcs[:n:n]
+}
+
+// Frame is a function call in a stack trace.
+type Frame struct {
+	Function string // package path-qualified function name
+	File     string
+	Line     int
+}
+
+// Stack returns the stack of the first error in the chain with a stack,
+// innermost call first. Returns nil if no error has a stack.
+func Stack(err error) []Frame {
+	var se *stackError
+	if !errors.As(err, &se) {
+		return nil
+	}
+	return frames(se.stack)
+}
+
+// frames returns the frames of the pcs, omitting runtime frames like
+// runtime.main.
+func frames(pcs []uintptr) []Frame {
+	out := make([]Frame, 0, len(pcs))
+	iter := runtime.CallersFrames(pcs)
+	for {
+		f, more := iter.Next()
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			out = append(out, Frame{Function: f.Function, File: f.File, Line: f.Line})
+		}
+		if !more {
+			return out
+		}
+	}
+}
+
+// FormatStack formats the frames like a goroutine stack trace, with the
+// function on one line and the indented file and line on the next.
+func FormatStack(frames []Frame) string {
+	sb := strings.Builder{}
+	for _, f := range frames {
+		sb.WriteString(f.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(f.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(f.Line))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,218 @@
+package logerr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+const pkg = "github.com/jschaf/observe/log/logerr."
+
+func TestNew(t *testing.T) {
+	err := New("boom")
+	difftest.AssertSame(t, "message", "boom", err.Error())
+	stack := Stack(err)
+	if len(stack) == 0 {
+		t.Fatal("got no stack")
+	}
+	difftest.AssertSame(t, "function", pkg+"TestNew", stack[0].Function)
+	difftest.AssertSame(t, "file", "errors_test.go", filepath.Base(stack[0].File))
+	difftest.AssertSame(t, "last function", "testing.tRunner", stack[len(stack)-1].Function)
+}
+
+func TestErrorf(t *testing.T) {
+	err := Errorf("read config: %w", io.EOF)
+	difftest.AssertSame(t, "message", "read config: EOF", err.Error())
+	difftest.AssertSame(t, "is", true, errors.Is(err, io.EOF))
+	difftest.AssertSame(t, "function", pkg+"TestErrorf", Stack(err)[0].Function)
+
+	// Keeps the stack of the wrapped error.
+	inner := newHelper("inner")
+	outer := Errorf("outer: %w", inner)
+	difftest.AssertSame(t, "wrapped function", pkg+"newHelper", Stack(outer)[0].Function)
+	difftest.AssertSame(t, "with stack", true, WithStack(outer) == outer)
+}
+
+func newHelper(msg string) error {
+	return New(msg)
+}
+
+func TestWithStack(t *testing.T) {
+	difftest.AssertSame(t, "nil", true, WithStack(nil) == nil)
+	err := WithStack(io.EOF)
+	difftest.AssertSame(t, "is", true, errors.Is(err, io.EOF))
+	difftest.AssertSame(t, "function", pkg+"TestWithStack", Stack(err)[0].Function)
+	difftest.AssertSame(t, "no stack", 0, len(Stack(io.EOF)))
+}
+
+type codeError struct{ code int }
+
+func (e *codeError) Error() string { return fmt.Sprintf("code %d", e.code) }
+
+func (e *codeError) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("code", e.code))
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{
+			name: "plain",
+			err:  io.EOF,
+			want: []string{"EOF (*errors.errorString)"},
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("read: %w", io.EOF),
+			want: []string{
+				"read: EOF (*fmt.wrapError)",
+				"  EOF (*errors.errorString)",
+			},
+		},
+		{
+			name: "joined",
+			err:  errors.Join(io.EOF, fmt.Errorf("b: %w", io.ErrUnexpectedEOF)),
+			want: []string{
+				"EOF\nb: unexpected EOF (*errors.joinError)",
+				"  EOF (*errors.errorString)",
+				"  b: unexpected EOF (*fmt.wrapError)",
+				"    unexpected EOF (*errors.errorString)",
+			},
+		},
+		{
+			name: "log valuer",
+			err:  fmt.Errorf("call: %w", &codeError{code: 7}),
+			want: []string{
+				"call: code 7 (*fmt.wrapError)",
+				"  code 7 (*logerr.codeError) [code=7]",
+			},
+		},
+		{
+			name: "stack",
+			err:  New("boom"),
+			want: []string{"boom (*errors.errorString) at " + pkg + "TestDescribe"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			difftest.AssertSame(t, "detail", tt.want, detailLines(Describe(tt.err), ""))
+		})
+	}
+}
+
+// detailLines formats the detail with one line per error, indenting causes.
+func detailLines(d Detail, indent string) []string {
+	line := indent + d.Message + " (" + d.Type + ")"
+	if !d.Value.Equal(slog.Value{}) {
+		line += " " + d.Value.String()
+	}
+	if len(d.Stack) > 0 {
+		line += " at " + d.Stack[0].Function
+	}
+	lines := []string{line}
+	for _, c := range d.Causes {
+		lines = append(lines, detailLines(c, indent+"  ")...)
+	}
+	return lines
+}
+
+func TestDetail_IsPlain(t *testing.T) {
+	difftest.AssertSame(t, "plain", true, Describe(io.EOF).IsPlain())
+	difftest.AssertSame(t, "wrapped", false, Describe(fmt.Errorf("a: %w", io.EOF)).IsPlain())
+	difftest.AssertSame(t, "stack", false, Describe(New("a")).IsPlain())
+	difftest.AssertSame(t, "log valuer", false, Describe(&codeError{}).IsPlain())
+}
+
+func TestErrorOf(t *testing.T) {
+	lv := &codeError{code: 1}
+	tests := []struct {
+		name string
+		v    slog.Value
+		want error
+	}{
+		{"error", slog.AnyValue(io.EOF), io.EOF},
+		{"log valuer error", slog.AnyValue(lv), lv},
+		{"string", slog.StringValue("EOF"), nil},
+		{"any", slog.AnyValue([]int{1}), nil},
+	}
+	for _, tt := range tests {
+		difftest.AssertSame(t, tt.name, true, ErrorOf(tt.v) == tt.want)
+	}
+}
+
+func TestReplaceAttr(t *testing.T) {
+	lv := &codeError{code: 7}
+	tests := []struct {
+		name     string
+		attr     slog.Attr
+		replace  func([]string, slog.Attr) slog.Attr
+		wantKind slog.Kind // of the value passed to replace
+		want     string
+	}{
+		{
+			name:     "log valuer error kept",
+			attr:     slog.Any("err", lv),
+			replace:  func(_ []string, a slog.Attr) slog.Attr { return a },
+			wantKind: slog.KindGroup,
+			want:     "err=code 7",
+		},
+		{
+			name:     "log valuer error renamed",
+			attr:     slog.Any("err", lv),
+			replace:  func(_ []string, a slog.Attr) slog.Attr { return slog.Attr{Key: "error", Value: a.Value} },
+			wantKind: slog.KindGroup,
+			want:     "error=code 7",
+		},
+		{
+			name:     "log valuer error replaced",
+			attr:     slog.Any("err", lv),
+			replace:  func(_ []string, a slog.Attr) slog.Attr { return slog.String(a.Key, "REDACTED") },
+			wantKind: slog.KindGroup,
+			want:     "err=REDACTED",
+		},
+		{
+			name:     "log valuer error dropped",
+			attr:     slog.Any("err", lv),
+			replace:  func([]string, slog.Attr) slog.Attr { return slog.Attr{} },
+			wantKind: slog.KindGroup,
+			want:     "=<nil>",
+		},
+		{
+			name:     "error",
+			attr:     slog.Any("err", io.EOF),
+			replace:  func(_ []string, a slog.Attr) slog.Attr { return a },
+			wantKind: slog.KindAny,
+			want:     "err=EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotKind slog.Kind
+			got := ReplaceAttr(func(groups []string, a slog.Attr) slog.Attr {
+				gotKind = a.Value.Kind()
+				return tt.replace(groups, a)
+			}, nil, tt.attr)
+			difftest.AssertSame(t, "replace kind", tt.wantKind.String(), gotKind.String())
+			difftest.AssertSame(t, "attr", tt.want, got.String())
+		})
+	}
+}
+
+func TestFormatStack(t *testing.T) {
+	got := FormatStack([]Frame{
+		{Function: "main.open", File: "/app/db.go", Line: 42},
+		{Function: "main.main", File: "/app/main.go", Line: 7},
+	})
+	want := "main.open\n\t/app/db.go:42\nmain.main\n\t/app/main.go:7\n"
+	difftest.AssertSame(t, "stack", want, got)
+	difftest.AssertSame(t, "func prefix", true, strings.HasPrefix(FormatStack(Stack(New("x"))), pkg+"TestFormatStack\n"))
+}
@@ -0,0 +1,411 @@
+// Package logfile provides an io.Writer for log handlers that writes to a
+// file and rotates it.
+package logfile
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// backupTimeFormat is the time format in backup file names. Sorts
+// lexicographically in time order.
+const backupTimeFormat = "20060102T150405.000"
+
+type config struct {
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	compress   bool
+	reopenHUP  bool
+	now        func() time.Time
+}
+
+// Option configures a Writer.
+type Option func(config) config
+
+// WithMaxSize rotates the file before a write would grow it beyond n bytes.
+// A single write larger than n goes to a new file.
+func WithMaxSize(n int64) Option {
+	return func(cfg config) config {
+		cfg.maxSize = n
+		return cfg
+	}
+}
+
+// WithInterval rotates the file when the time crosses a multiple of d since
+// the zero time, like every hour on the hour for time.Hour, or every day at
+// midnight UTC for 24 * time.Hour.
+func WithInterval(d time.Duration) Option {
+	return func(cfg config) config {
+		cfg.interval = d
+		return cfg
+	}
+}
+
+// WithMaxBackups keeps at most n rotated files, removing the oldest. Keeps all
+// rotated files if n is zero, the default.
+func WithMaxBackups(n int) Option {
+	return func(cfg config) config {
+		cfg.maxBackups = n
+		return cfg
+	}
+}
+
+// WithCompress gzip-compresses rotated files in the background.
+func WithCompress() Option {
+	return func(cfg config) config {
+		cfg.compress = true
+		return cfg
+	}
+}
+
+// WithReopenOnSIGHUP reopens the file when the process receives SIGHUP, for
+// external rotation like logrotate, which moves the file then signals the
+// process.
+func WithReopenOnSIGHUP() Option {
+	return func(cfg config) config {
+		cfg.reopenHUP = true
+		return cfg
+	}
+}
+
+// Writer is an io.Writer that appends to a file and rotates it by size or
+// time. Rotating renames the file with the rotation time, like
+// "app-20240101T120000.000.log" for "app.log", and opens a new file.
+//
+// Writer is safe for concurrent use, so multiple handlers can share it.
+type Writer struct {
+	path string
+	cfg  config
+
+	mu       sync.Mutex // guards the fields below
+	f        *os.File   // nil after a failed rotation or reopen
+	size     int64
+	openedAt time.Time
+	closed   bool
+	// compressQueue are the rotated files to compress, oldest first.
+	compressQueue []string
+	compressing   bool // if a goroutine is draining compressQueue
+
+	signals chan os.Signal
+	bg      sync.WaitGroup // background compression and signal handling
+	errMu   sync.Mutex
+	bgErrs  []error // errors from background compression
+}
+
+// New opens or creates the file at path for appending and returns a Writer
+// for it.
+func New(path string, opts ...Option) (*Writer, error) {
+	cfg := config{now: time.Now}
+	for _, opt := range opts {
+		cfg = opt(cfg)
+	}
+	w := &Writer{path: path, cfg: cfg}
+	if err := w.openLocked(); err != nil {
+		return nil, err
+	}
+	if cfg.reopenHUP {
+		w.signals = make(chan os.Signal, 1)
+		signal.Notify(w.signals, syscall.SIGHUP)
+		w.bg.Add(1)
+		go w.handleSignals()
+	}
+	return w, nil
+}
+
+// Write appends p to the file, rotating the file first if needed. If a
+// previous rotation or reopen failed to open the file, retries opening it.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.f == nil {
+		if err := w.openLocked(); err != nil {
+			return 0, err
+		}
+	}
+	if w.shouldRotateLocked(len(p)) {
+		if err := w.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	if err != nil {
+		return n, fmt.Errorf("write log file: %w", err)
+	}
+	return n, nil
+}
+
+// Rotate rotates the file now.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotateLocked()
+}
+
+// Reopen closes and reopens the file at the path, like after an external
+// tool moved the file. If opening fails, the next write retries.
+func (w *Writer) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if err := w.closeFileLocked(); err != nil {
+		return err
+	}
+	return w.openLocked()
+}
+
+// Close closes the file and waits for background compression to finish.
+// Returns errors from background compression.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := w.closeFileLocked()
+	if w.signals != nil {
+		signal.Stop(w.signals)
+		close(w.signals)
+	}
+	w.mu.Unlock()
+
+	w.bg.Wait()
+	w.errMu.Lock()
+	defer w.errMu.Unlock()
+	return errors.Join(append([]error{err}, w.bgErrs...)...)
+}
+
+func (w *Writer) handleSignals() {
+	defer w.bg.Done()
+	for range w.signals {
+		_ = w.Reopen() // the next write reports persistent errors
+	}
+}
+
+func (w *Writer) shouldRotateLocked(n int) bool {
+	if w.size == 0 {
+		return false // rotating an empty file doesn't help
+	}
+	if w.cfg.maxSize > 0 && w.size+int64(n) > w.cfg.maxSize {
+		return true
+	}
+	if w.cfg.interval > 0 {
+		now := w.cfg.now()
+		return !now.Truncate(w.cfg.interval).Equal(w.openedAt.Truncate(w.cfg.interval))
+	}
+	return false
+}
+
+// closeFileLocked closes the file, if open, and unsets it even if closing
+// fails, since the file can't be used either way.
+func (w *Writer) closeFileLocked() error {
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	if err != nil {
+		return fmt.Errorf("close log file: %w", err)
+	}
+	return nil
+}
+
+func (w *Writer) openLocked() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return fmt.Errorf("create log dir: %w", err)
+	}
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644) //nolint:gosec // log path from caller
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	w.f = f
+	w.size = info.Size()
+	w.openedAt = w.cfg.now()
+	return nil
+}
+
+func (w *Writer) rotateLocked() error {
+	if err := w.closeFileLocked(); err != nil {
+		return err
+	}
+	backup := w.backupName(w.cfg.now())
+	if err := os.Rename(w.path, backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("rename log file: %w", err)
+	}
+	if err := w.openLocked(); err != nil {
+		return err
+	}
+	if w.cfg.compress {
+		w.compressQueue = append(w.compressQueue, backup)
+		if !w.compressing {
+			w.compressing = true
+			w.bg.Add(1)
+			go w.compressLoop()
+		}
+		return nil
+	}
+	w.pruneLocked()
+	return nil
+}
+
+// compressLoop compresses the queued files in order, pruning after each, so
+// that pruning sees compressed files in rotation order.
+func (w *Writer) compressLoop() {
+	defer w.bg.Done()
+	for {
+		w.mu.Lock()
+		if len(w.compressQueue) == 0 {
+			w.compressing = false
+			w.mu.Unlock()
+			return
+		}
+		path := w.compressQueue[0]
+		w.compressQueue = w.compressQueue[1:]
+		w.mu.Unlock()
+
+		// A file may not exist if pruned while queued.
+		if err := compress(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			w.errMu.Lock()
+			w.bgErrs = append(w.bgErrs, err)
+			w.errMu.Unlock()
+		}
+		w.prune()
+	}
+}
+
+// backupName returns an unused path for a backup rotated at t.
+func (w *Writer) backupName(t time.Time) string {
+	dir, prefix, ext := w.nameParts()
+	stamp := t.UTC().Format(backupTimeFormat)
+	for i := 0; ; i++ {
+		name := prefix + stamp + ext
+		if i > 0 {
+			name = fmt.Sprintf("%s%s_%d%s", prefix, stamp, i, ext) // sorts after the first
+		}
+		name = filepath.Join(dir, name)
+		_, err1 := os.Lstat(name)
+		_, err2 := os.Lstat(name + ".gz")
+		if errors.Is(err1, os.ErrNotExist) && errors.Is(err2, os.ErrNotExist) {
+			return name
+		}
+	}
+}
+
+// nameParts splits the path into the dir, the backup prefix, like "app-",
+// and the extension, like ".log".
+func (w *Writer) nameParts() (dir, prefix, ext string) {
+	dir, base := filepath.Split(w.path)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// backups returns the paths of the rotated files, oldest first.
+func (w *Writer) backups() ([]string, error) {
+	dir, prefix, ext := w.nameParts()
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read log dir: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)[len(prefix):]
+		if len(stamp) < len(backupTimeFormat) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, stamp[:len(backupTimeFormat)]); err != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for i, name := range names {
+		names[i] = filepath.Join(dir, name)
+	}
+	return names, nil
+}
+
+// prune removes the oldest backups beyond the maximum.
+func (w *Writer) prune() {
+	// Serialize with rotation to avoid racing with concurrent prunes.
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pruneLocked()
+}
+
+func (w *Writer) pruneLocked() {
+	if w.cfg.maxBackups <= 0 {
+		return
+	}
+	names, err := w.backups()
+	if err != nil || len(names) <= w.cfg.maxBackups {
+		return
+	}
+	for _, name := range names[:len(names)-w.cfg.maxBackups] {
+		_ = os.Remove(name)
+	}
+}
+
+// compress gzips the file at path to path.gz and removes the original.
+func compress(path string) (err error) {
+	src, err := os.Open(path) //nolint:gosec // rotated log path
+	if err != nil {
+		return fmt.Errorf("open rotated log file: %w", err)
+	}
+	defer func() { _ = src.Close() }()
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644) //nolint:gosec // rotated log path
+	if err != nil {
+		return fmt.Errorf("create compressed log file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = dst.Close()
+			_ = os.Remove(path + ".gz")
+		}
+	}()
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		return fmt.Errorf("compress log file: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compress log file: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("close compressed log file: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove rotated log file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package logfile
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestWriter_MaxSize(t *testing.T) {
+	dir := t.TempDir()
+	clock := newFakeClock()
+	w := newTestWriter(t, filepath.Join(dir, "app.log"), clock, WithMaxSize(10), WithMaxBackups(2))
+
+	for _, s := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "this is too long\n", "eeee\n"} {
+		clock.advance(time.Second)
+		mustWrite(t, w, s)
+	}
+	mustClose(t, w)
+
+	difftest.AssertSame(t, "files", []string{
+		"app-20240101T120005.000.log",
+		"app-20240101T120006.000.log",
+		"app.log",
+	}, dirNames(t, dir))
+	difftest.AssertSame(t, "backup 1", "cccc\ndddd\n", readFile(t, filepath.Join(dir, "app-20240101T120005.000.log")))
+	difftest.AssertSame(t, "backup 2", "this is too long\n", readFile(t, filepath.Join(dir, "app-20240101T120006.000.log")))
+	difftest.AssertSame(t, "current", "eeee\n", readFile(t, filepath.Join(dir, "app.log")))
+}
+
+func TestWriter_Interval(t *testing.T) {
+	dir := t.TempDir()
+	clock := newFakeClock()
+	w := newTestWriter(t, filepath.Join(dir, "app.log"), clock, WithInterval(time.Hour))
+
+	mustWrite(t, w, "a\n")
+	clock.advance(30 * time.Minute)
+	mustWrite(t, w, "b\n")
+	clock.advance(30 * time.Minute) // crosses 13:00
+	mustWrite(t, w, "c\n")
+	clock.advance(3 * time.Hour) // no writes in between
+	mustWrite(t, w, "d\n")
+	mustClose(t, w)
+
+	difftest.AssertSame(t, "files", []string{
+		"app-20240101T130000.000.log",
+		"app-20240101T160000.000.log",
+		"app.log",
+	}, dirNames(t, dir))
+	difftest.AssertSame(t, "backup 1", "a\nb\n", readFile(t, filepath.Join(dir, "app-20240101T130000.000.log")))
+	difftest.AssertSame(t, "backup 2", "c\n", readFile(t, filepath.Join(dir, "app-20240101T160000.000.log")))
+	difftest.AssertSame(t, "current", "d\n", readFile(t, filepath.Join(dir, "app.log")))
+}
+
+func TestWriter_Compress(t *testing.T) {
+	dir := t.TempDir()
+	clock := newFakeClock()
+	w := newTestWriter(t, filepath.Join(dir, "app.log"), clock, WithCompress(), WithMaxBackups(1))
+
+	mustWrite(t, w, "a\n")
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, w, "b\n")
+	if err := w.Rotate(); err != nil { // same time, so needs a suffix
+		t.Fatal(err)
+	}
+	mustWrite(t, w, "c\n")
+	mustClose(t, w)
+
+	names := dirNames(t, dir)
+	difftest.AssertSame(t, "files", []string{"app-20240101T120000.000_1.log.gz", "app.log"}, names)
+	f, err := os.Open(filepath.Join(dir, names[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	difftest.AssertSame(t, "compressed backup", "b\n", string(got))
+}
+
+func TestWriter_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(filepath.Join(dir, "app.log"), WithMaxSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const goroutines, perGoroutine = 8, 100
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perGoroutine {
+				mustWrite(t, w, "0123456789\n")
+			}
+		}()
+	}
+	wg.Wait()
+	mustClose(t, w)
+
+	lines := 0
+	for _, name := range dirNames(t, dir) {
+		content := readFile(t, filepath.Join(dir, name))
+		if len(content) > 100 {
+			t.Errorf("file %s has %d bytes, want at most 100", name, len(content))
+		}
+		for line := range strings.Lines(content) {
+			if line != "0123456789\n" {
+				t.Errorf("file %s has interleaved line %q", name, line)
+			}
+			lines++
+		}
+	}
+	difftest.AssertSame(t, "total lines", goroutines*perGoroutine, lines)
+}
+
+func TestWriter_Closed(t *testing.T) {
+	w := newTestWriter(t, filepath.Join(t.TempDir(), "app.log"), newFakeClock())
+	mustClose(t, w)
+	if _, err := w.Write([]byte("a")); err == nil {
+		t.Error("want error writing to closed writer")
+	}
+	mustClose(t, w)
+}
+
+func TestWriter_ReopenFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w := newTestWriter(t, path, newFakeClock())
+	mustWrite(t, w, "a\n")
+
+	// A directory in place of the file makes opening fail.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reopen(); err == nil {
+		t.Fatal("want error reopening a directory")
+	}
+	if _, err := w.Write([]byte("b\n")); err == nil {
+		t.Fatal("want error writing while the file can't be opened")
+	}
+
+	// The next write retries opening the file.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, w, "c\n")
+	mustClose(t, w)
+	difftest.AssertSame(t, "moved", "a\n", readFile(t, path+".1"))
+	difftest.AssertSame(t, "reopened", "c\n", readFile(t, path))
+}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestWriter(t *testing.T, path string, clock *fakeClock, opts ...Option) *Writer {
+	t.Helper()
+	withClock := func(cfg config) config {
+		cfg.now = clock.Now
+		return cfg
+	}
+	w, err := New(path, append(opts, withClock)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func mustWrite(t *testing.T, w *Writer, s string) {
+	t.Helper()
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Error(err)
+	}
+}
+
+func mustClose(t *testing.T, w *Writer) {
+	t.Helper()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	slices.Sort(names)
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
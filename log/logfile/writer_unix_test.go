@@ -0,0 +1,43 @@
+//go:build unix
+
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestWriter_ReopenOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w := newTestWriter(t, path, newFakeClock(), WithReopenOnSIGHUP())
+	mustWrite(t, w, "a\n")
+
+	// Simulate logrotate, which moves the file and then signals the process.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for reopen")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mustWrite(t, w, "b\n")
+	mustClose(t, w)
+
+	difftest.AssertSame(t, "moved", "a\n", readFile(t, path+".1"))
+	difftest.AssertSame(t, "reopened", "b\n", readFile(t, path))
+}
@@ -0,0 +1,115 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+)
+
+// LoggerKey is the key for the logger name attribute added to records logged
+// by a named Logger.
+const LoggerKey = "logger"
+
+// Logger is a named component logger, like for a database or HTTP client.
+// Logs to the handler of slog.Default, enabled by the level for the name in
+// DefaultLevels.
+type Logger struct {
+	name  string
+	attrs []slog.Attr // includes the name attr
+	// level caches the level for the name, valid while the generation
+	// matches the generation of DefaultLevels.
+	level atomic.Pointer[cachedLevel]
+}
+
+type cachedLevel struct {
+	gen   uint64
+	level slog.Level
+	ok    bool
+}
+
+// Named returns a logger with the name, like "db". Use dots to separate
+// parts of the name, like "http.client", to control levels with patterns,
+// like "http.*".
+func Named(name string) *Logger {
+	return &Logger{name: name, attrs: []slog.Attr{slog.String(LoggerKey, name)}}
+}
+
+// Named returns a child logger named by appending name to the logger name,
+// separated by a dot. The child inherits the bound attrs.
+func (l *Logger) Named(name string) *Logger {
+	full := l.name + "." + name
+	attrs := slices.Clone(l.attrs)
+	attrs[0] = slog.String(LoggerKey, full)
+	return &Logger{name: full, attrs: attrs}
+}
+
+// With returns a logger with the same name that includes attrs in every
+// record.
+func (l *Logger) With(attrs ...slog.Attr) *Logger {
+	if len(attrs) == 0 {
+		return l
+	}
+	return &Logger{name: l.name, attrs: slices.Concat(l.attrs, attrs)}
+}
+
+// Name returns the logger name.
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// Enabled reports whether the logger logs records at the level.
+func (l *Logger) Enabled(ctx context.Context, level slog.Level) bool {
+	return l.enabled(ctx, slog.Default().Handler(), level)
+}
+
+// Debug logs at [slog.LevelDebug].
+func (l *Logger) Debug(ctx context.Context, msg string, attrs ...slog.Attr) {
+	l.log(ctx, slog.LevelDebug, msg, attrs)
+}
+
+// Info logs at [slog.LevelInfo].
+func (l *Logger) Info(ctx context.Context, msg string, attrs ...slog.Attr) {
+	l.log(ctx, slog.LevelInfo, msg, attrs)
+}
+
+// Warn logs at [slog.LevelWarn].
+func (l *Logger) Warn(ctx context.Context, msg string, attrs ...slog.Attr) {
+	l.log(ctx, slog.LevelWarn, msg, attrs)
+}
+
+// Error logs at [slog.LevelError].
+func (l *Logger) Error(ctx context.Context, msg string, attrs ...slog.Attr) {
+	l.log(ctx, slog.LevelError, msg, attrs)
+}
+
+// Log emits a log record with the current time and the given level and message.
+func (l *Logger) Log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	l.log(ctx, level, msg, attrs)
+}
+
+func (l *Logger) log(ctx context.Context, level slog.Level, msg string, attrs []slog.Attr) {
+	h := slog.Default().Handler()
+	if !l.enabled(ctx, h, level) {
+		return
+	}
+	pc := callerPC(2) // skip [log, caller]
+	r := newRecord(ctx, level, msg, pc, l.attrs, attrs)
+	_ = h.Handle(ctx, r)
+}
+
+// enabled is like Levels.enabled but caches the level for the logger name to
+// avoid matching patterns on each call.
+func (l *Logger) enabled(ctx context.Context, h slog.Handler, level slog.Level) bool {
+	gen := defaultLevels.gen.Load()
+	c := l.level.Load()
+	if c == nil || c.gen != gen {
+		c = &cachedLevel{gen: gen}
+		c.level, c.ok = defaultLevels.Level(l.name)
+		l.level.Store(c)
+	}
+	if c.ok {
+		return level >= c.level
+	}
+	return h.Enabled(ctx, level)
+}
@@ -0,0 +1,88 @@
+package log
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	ctx := t.Context()
+
+	db := Named("db").With(slog.String("shard", "a"))
+	db.Info(ctx, "msg", slog.Int("a", 1))
+	checkLogOutput(t, buf.String(), `time=`+textTimeRE+` level=INFO msg=msg logger=db shard=a a=1`)
+	buf.Reset()
+
+	pool := db.Named("pool").With(slog.Int("size", 2))
+	pool.Warn(ctx, "full")
+	checkLogOutput(t, buf.String(), `time=`+textTimeRE+` level=WARN msg=full logger=db.pool shard=a size=2`)
+	buf.Reset()
+
+	// Without set levels, the handler decides.
+	db.Debug(ctx, "dbg")
+	checkLogOutput(t, buf.String(), "")
+	if got := pool.Name(); got != "db.pool" {
+		t.Errorf("got name %q, want db.pool", got)
+	}
+}
+
+func TestLogger_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { _ = defaultLevels.SetSpec("") })
+	ctx := t.Context()
+
+	db := Named("db")
+	pool := db.Named("pool")
+	httpClient := Named("http").Named("client")
+
+	if err := defaultLevels.SetSpec("db=debug,http.*=warn,*=error"); err != nil {
+		t.Fatal(err)
+	}
+	db.Debug(ctx, "db debug")
+	pool.Warn(ctx, "pool warn")
+	httpClient.Info(ctx, "http info")
+	httpClient.Warn(ctx, "http warn")
+	Warn(ctx, "root warn")
+	Error(ctx, "root error")
+	checkLogOutput(t, buf.String(), `time=`+textTimeRE+` level=DEBUG msg="db debug" logger=db~`+
+		`time=`+textTimeRE+` level=WARN msg="http warn" logger=http.client~`+
+		`time=`+textTimeRE+` level=ERROR msg="root error"`)
+	buf.Reset()
+
+	// Changes apply to loggers with cached levels.
+	defaultLevels.SetLevel("db.pool", slog.LevelInfo)
+	defaultLevels.SetLevel("db", slog.LevelError)
+	pool.Info(ctx, "pool info")
+	db.Warn(ctx, "db warn")
+	checkLogOutput(t, buf.String(), `time=`+textTimeRE+` level=INFO msg="pool info" logger=db.pool`)
+	if !db.Enabled(ctx, slog.LevelError) || db.Enabled(ctx, slog.LevelWarn) {
+		t.Error("want db enabled only at error level")
+	}
+}
+
+func BenchmarkLogger_Disabled(b *testing.B) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+	b.Cleanup(func() { _ = defaultLevels.SetSpec("") })
+	ctx := b.Context()
+	l := Named("http").Named("client").With(slog.String("svc", "api"))
+
+	b.Run("handler level", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			l.Debug(ctx, "msg", slog.Int("a", 1))
+		}
+	})
+	b.Run("pattern level", func(b *testing.B) {
+		if err := defaultLevels.SetSpec("db=debug,http.*=info"); err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		for b.Loop() {
+			l.Debug(ctx, "msg", slog.Int("a", 1))
+		}
+	})
+}
@@ -0,0 +1,106 @@
+// Package loghttp provides HTTP handlers to control logging at runtime and to
+// serve readiness probes.
+package loghttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jschaf/observe/log"
+)
+
+const maxBodySize = 64 << 10
+
+// levelJSON is the JSON form of a log.LevelSetting.
+type levelJSON struct {
+	// Logger is the logger name, or empty for the global level.
+	Logger  string     `json:"logger,omitempty"`
+	Level   slog.Level `json:"level"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+type levelsJSON struct {
+	Levels []levelJSON `json:"levels"`
+}
+
+type putLevelJSON struct {
+	Logger string      `json:"logger"`
+	Level  *slog.Level `json:"level"`
+	// Duration, if set, is how long the level lasts before reverting, like
+	// "15m".
+	Duration string `json:"duration"`
+}
+
+// NewLevelHandler returns a handler to read and change the log levels:
+//
+//   - GET responds with the set levels, like:
+//     {"levels":[{"level":"INFO"},{"logger":"db","level":"DEBUG"}]}
+//   - PUT sets a level from a JSON body, like:
+//     {"logger":"db","level":"debug","duration":"15m"}
+//     The logger is a name or a pattern, like "http.*". An empty logger sets
+//     the global level. The optional duration reverts the level afterward.
+//   - DELETE removes the level for the logger in the "logger" query
+//     parameter, or the global level if absent.
+//
+// Responds to PUT and DELETE with the levels after the change, like GET.
+func NewLevelHandler(levels *log.Levels) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut:
+			if err := putLevel(levels, w, r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case http.MethodDelete:
+			levels.ResetLevel(r.URL.Query().Get("logger"))
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeLevels(levels, w)
+	})
+}
+
+func putLevel(levels *log.Levels, w http.ResponseWriter, r *http.Request) error {
+	var req putLevelJSON
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return fmt.Errorf("decode level json: %w", err)
+	}
+	if req.Level == nil {
+		return errors.New("missing level")
+	}
+	if req.Duration == "" {
+		levels.SetLevel(req.Logger, *req.Level)
+		return nil
+	}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		return fmt.Errorf("parse duration: %w", err)
+	}
+	if d <= 0 {
+		return fmt.Errorf("duration must be positive, got %s", d)
+	}
+	levels.SetLevelFor(req.Logger, *req.Level, d)
+	return nil
+}
+
+func writeLevels(levels *log.Levels, w http.ResponseWriter) {
+	resp := levelsJSON{Levels: []levelJSON{}}
+	for _, s := range levels.Settings() {
+		lj := levelJSON{Logger: s.Name, Level: s.Level}
+		if !s.Expires.IsZero() {
+			lj.Expires = &s.Expires
+		}
+		resp.Levels = append(resp.Levels, lj)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
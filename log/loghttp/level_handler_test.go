@@ -0,0 +1,86 @@
+package loghttp_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+	"github.com/jschaf/observe/log"
+	"github.com/jschaf/observe/log/loghttp"
+)
+
+func TestLevelHandler(t *testing.T) {
+	levels := &log.Levels{}
+	h := loghttp.NewLevelHandler(levels)
+	serve := func(method, target, body string) (int, string) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code, strings.TrimSpace(rec.Body.String())
+	}
+
+	code, body := serve(http.MethodGet, "/", "")
+	difftest.AssertSame(t, "get empty status", http.StatusOK, code)
+	difftest.AssertSame(t, "get empty body", `{"levels":[]}`, body)
+
+	code, body = serve(http.MethodPut, "/", `{"level":"warn"}`)
+	difftest.AssertSame(t, "put global status", http.StatusOK, code)
+	difftest.AssertSame(t, "put global body", `{"levels":[{"level":"WARN"}]}`, body)
+
+	code, body = serve(http.MethodPut, "/", `{"logger":"db","level":"DEBUG"}`)
+	difftest.AssertSame(t, "put db status", http.StatusOK, code)
+	difftest.AssertSame(t, "put db body", `{"levels":[{"level":"WARN"},{"logger":"db","level":"DEBUG"}]}`, body)
+	if got, _ := levels.Level("db"); got != slog.LevelDebug {
+		t.Errorf("got db level %s, want DEBUG", got)
+	}
+
+	code, body = serve(http.MethodPut, "/", `{"logger":"http","level":"error","duration":"1h"}`)
+	difftest.AssertSame(t, "put temporary status", http.StatusOK, code)
+	wantRE := `^\{"levels":\[\{"level":"WARN"\},\{"logger":"db","level":"DEBUG"\},\{"logger":"http","level":"ERROR","expires":"[^"]+"\}\]\}$`
+	if !regexp.MustCompile(wantRE).MatchString(body) {
+		t.Errorf("put temporary body mismatch\ngot  %s\nwant %s", body, wantRE)
+	}
+
+	code, body = serve(http.MethodDelete, "/?logger=http", "")
+	difftest.AssertSame(t, "delete http status", http.StatusOK, code)
+	difftest.AssertSame(t, "delete http body", `{"levels":[{"level":"WARN"},{"logger":"db","level":"DEBUG"}]}`, body)
+
+	code, body = serve(http.MethodDelete, "/", "")
+	difftest.AssertSame(t, "delete global status", http.StatusOK, code)
+	difftest.AssertSame(t, "delete global body", `{"levels":[{"logger":"db","level":"DEBUG"}]}`, body)
+}
+
+func TestLevelHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{"bad json", http.MethodPut, `{`, http.StatusBadRequest, "decode level json: unexpected EOF"},
+		{"missing level", http.MethodPut, `{"logger":"db"}`, http.StatusBadRequest, "missing level"},
+		{"bad level", http.MethodPut, `{"level":"loud"}`, http.StatusBadRequest, `decode level json: slog: level string "loud": unknown name`},
+		{"unknown field", http.MethodPut, `{"level":"info","lvl":"x"}`, http.StatusBadRequest, `decode level json: json: unknown field "lvl"`},
+		{"bad duration", http.MethodPut, `{"level":"info","duration":"soon"}`, http.StatusBadRequest, `parse duration: time: invalid duration "soon"`},
+		{"negative duration", http.MethodPut, `{"level":"info","duration":"-1m"}`, http.StatusBadRequest, "duration must be positive, got -1m0s"},
+		{"bad method", http.MethodPost, `{"level":"info"}`, http.StatusMethodNotAllowed, "method not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels := &log.Levels{}
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			loghttp.NewLevelHandler(levels).ServeHTTP(rec, req)
+
+			difftest.AssertSame(t, "status", tt.wantCode, rec.Code)
+			difftest.AssertSame(t, "body", tt.wantBody, strings.TrimSpace(rec.Body.String()))
+			difftest.AssertSame(t, "settings", 0, len(levels.Settings()))
+		})
+	}
+}
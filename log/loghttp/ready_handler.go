@@ -0,0 +1,44 @@
+package loghttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jschaf/observe/log"
+)
+
+type readyJSON struct {
+	Ready   bool     `json:"ready"`
+	Pending []string `json:"pending"`
+}
+
+// NewReadyHandler returns a handler for a readiness probe, like /readyz.
+// Responds to GET with 200 OK once all components registered with the
+// readiness are ready, like:
+//
+//	{"ready":true,"pending":[]}
+//
+// and with 503 Service Unavailable before, listing the pending components,
+// like:
+//
+//	{"ready":false,"pending":["db"]}
+func NewReadyHandler(r *log.Readiness) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		resp := readyJSON{Pending: r.Pending()}
+		if resp.Pending == nil {
+			resp.Pending = []string{}
+		}
+		resp.Ready = len(resp.Pending) == 0
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if !resp.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
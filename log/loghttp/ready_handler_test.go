@@ -0,0 +1,45 @@
+package loghttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+	"github.com/jschaf/observe/log"
+	"github.com/jschaf/observe/log/loghttp"
+)
+
+func TestReadyHandler(t *testing.T) {
+	readiness := &log.Readiness{}
+	readiness.Register("db", "http")
+	h := loghttp.NewReadyHandler(readiness)
+	serve := func(method string) (int, string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "/readyz", nil))
+		return rec.Code, strings.TrimSpace(rec.Body.String())
+	}
+
+	code, body := serve(http.MethodGet)
+	difftest.AssertSame(t, "pending status", http.StatusServiceUnavailable, code)
+	difftest.AssertSame(t, "pending body", `{"ready":false,"pending":["db","http"]}`, body)
+
+	readiness.MarkReady("db")
+	readiness.MarkReady("http")
+	code, body = serve(http.MethodGet)
+	difftest.AssertSame(t, "ready status", http.StatusOK, code)
+	difftest.AssertSame(t, "ready body", `{"ready":true,"pending":[]}`, body)
+
+	code, _ = serve(http.MethodPost)
+	difftest.AssertSame(t, "post status", http.StatusMethodNotAllowed, code)
+}
+
+func TestReadyHandler_DefaultReadiness(t *testing.T) {
+	h := loghttp.NewReadyHandler(log.DefaultReadiness())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	difftest.AssertSame(t, "status before Ready", http.StatusServiceUnavailable, rec.Code)
+	difftest.AssertSame(t, "body before Ready", `{"ready":false,"pending":[""]}`, strings.TrimSpace(rec.Body.String()))
+}
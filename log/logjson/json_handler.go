@@ -0,0 +1,466 @@
+// Package logjson provides a fast slog.Handler that writes records as JSON
+// objects, one per line.
+package logjson
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/jschaf/observe/internal/logkey"
+	"github.com/jschaf/observe/internal/slogfmt"
+	logdev "github.com/jschaf/observe/log/logdev"
+	"github.com/jschaf/observe/log/logerr"
+)
+
+// Options configures a JSONHandler.
+type Options struct {
+	slog.HandlerOptions
+
+	// Field names for the built-in attrs. Defaults to the slog keys, like
+	// slog.TimeKey. ReplaceAttr receives the built-in attrs with the slog keys
+	// and the output uses these names unless ReplaceAttr changes the key.
+	TimeKey    string
+	LevelKey   string
+	MessageKey string
+	SourceKey  string
+}
+
+// GCPOptions returns Options with field names recognized by Google Cloud
+// Logging, and with GCPSeverity as the ReplaceAttr function to write levels
+// as Cloud Logging severities. To use another ReplaceAttr, call GCPSeverity
+// from it to keep the severities.
+func GCPOptions() Options {
+	return Options{
+		HandlerOptions: slog.HandlerOptions{ReplaceAttr: GCPSeverity},
+		TimeKey:        "time",
+		LevelKey:       "severity",
+		MessageKey:     "message",
+		SourceKey:      "logging.googleapis.com/sourceLocation",
+	}
+}
+
+// GCPSeverity is a ReplaceAttr function that replaces the level with the
+// name of the Cloud Logging severity: DEBUG, INFO, WARNING, ERROR, or
+// CRITICAL. Levels between the slog levels map to the severity of the lower
+// slog level, and levels from slog.LevelError+4 map to CRITICAL.
+func GCPSeverity(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 || a.Key != slog.LevelKey {
+		return a
+	}
+	level, ok := a.Value.Any().(slog.Level)
+	if !ok {
+		return a
+	}
+	switch {
+	case level < slog.LevelInfo:
+		a.Value = slog.StringValue("DEBUG")
+	case level < slog.LevelWarn:
+		a.Value = slog.StringValue("INFO")
+	case level < slog.LevelError:
+		a.Value = slog.StringValue("WARNING")
+	case level < slog.LevelError+4:
+		a.Value = slog.StringValue("ERROR")
+	default:
+		a.Value = slog.StringValue("CRITICAL")
+	}
+	return a
+}
+
+// JSONHandler is a slog.Handler that writes records as JSON objects, one per
+// line. It avoids allocations for common attr kinds.
+type JSONHandler struct {
+	w    io.Writer
+	opts Options
+	// preAttrs are the attrs bound with WithAttrs, rendered once. Each attr
+	// ends with a comma. Includes the opening of the first nOpenGroups groups.
+	preAttrs    []byte
+	groups      []string // group names from WithGroup
+	nOpenGroups int      // number of groups opened in preAttrs
+}
+
+// NewJSONHandler returns a JSONHandler that writes to w. If opts is nil, uses
+// the default options.
+func NewJSONHandler(w io.Writer, opts *Options) *JSONHandler {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	o.TimeKey = cmpOr(o.TimeKey, slog.TimeKey)
+	o.LevelKey = cmpOr(o.LevelKey, slog.LevelKey)
+	o.MessageKey = cmpOr(o.MessageKey, slog.MessageKey)
+	o.SourceKey = cmpOr(o.SourceKey, slog.SourceKey)
+	return &JSONHandler{w: w, opts: o}
+}
+
+func cmpOr(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a
+}
+
+func (h *JSONHandler) Enabled(_ context.Context, l slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return l >= minLevel
+}
+
+func (h *JSONHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := logdev.NewBuffer()
+	defer buf.Free()
+
+	_ = buf.WriteByte('{')
+
+	// Built-in attrs
+	if !r.Time.IsZero() {
+		h.appendBuiltin(buf, slog.TimeKey, h.opts.TimeKey, slog.TimeValue(r.Time))
+	}
+	h.appendBuiltin(buf, slog.LevelKey, h.opts.LevelKey, slog.AnyValue(r.Level))
+	h.appendBuiltin(buf, slog.MessageKey, h.opts.MessageKey, slog.StringValue(r.Message))
+	if h.opts.AddSource && r.PC != 0 {
+		h.appendBuiltin(buf, slog.SourceKey, h.opts.SourceKey, slog.AnyValue(slogfmt.RecordSource(r)))
+	}
+
+	// Trace correlation attrs are top-level fields, even in a group.
+	r.Attrs(func(attr slog.Attr) bool {
+		if logkey.IsTrace(attr.Key) {
+			h.appendAttr(buf, nil, attr)
+		}
+		return true
+	})
+
+	// Attrs
+	_, _ = buf.Write(h.preAttrs)
+	if r.NumAttrs() > 0 {
+		start := buf.Len()
+		h.openGroups(buf, h.nOpenGroups)
+		groupsEnd := buf.Len()
+		r.Attrs(func(attr slog.Attr) bool {
+			if !logkey.IsTrace(attr.Key) {
+				h.appendAttr(buf, h.groups, attr)
+			}
+			return true
+		})
+		if buf.Len() == groupsEnd {
+			buf.SetLen(start) // omit groups without attrs
+		} else {
+			closeObjects(buf, len(h.groups)-h.nOpenGroups)
+		}
+	}
+	closeObjects(buf, h.nOpenGroups)
+
+	// Replace the trailing comma with the closing brace.
+	if (*buf)[buf.Len()-1] == ',' {
+		buf.SetLen(buf.Len() - 1)
+	}
+	_, _ = buf.WriteString("}\n")
+
+	_, err := h.w.Write(*buf)
+	if err != nil {
+		return fmt.Errorf("write record: %w", err)
+	}
+	return nil
+}
+
+// WithAttrs returns a handler that includes attrs in every record. Renders
+// attrs once, when called, instead of for each record.
+func (h *JSONHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	buf := logdev.NewBuffer()
+	defer buf.Free()
+	for _, attr := range attrs {
+		h.appendAttr(buf, h.groups, attr)
+	}
+	if buf.Len() == 0 {
+		return h // only empty attrs
+	}
+	h2 := *h
+	pre := slices.Clone(h.preAttrs)
+	pre = appendOpenGroups(pre, h.groups[h.nOpenGroups:])
+	h2.preAttrs = append(pre, *buf...)
+	h2.nOpenGroups = len(h.groups)
+	return &h2
+}
+
+// WithGroup returns a handler that nests subsequent attrs in a JSON object
+// named by the group.
+func (h *JSONHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(slices.Clip(h.groups), name)
+	return &h2
+}
+
+// openGroups appends the opening of the groups after the first n groups.
+func (h *JSONHandler) openGroups(buf *logdev.Buffer, n int) {
+	*buf = appendOpenGroups(*buf, h.groups[n:])
+}
+
+func appendOpenGroups(b []byte, groups []string) []byte {
+	for _, g := range groups {
+		b = appendString(b, g)
+		b = append(b, ':', '{')
+	}
+	return b
+}
+
+// closeObjects closes n JSON objects, replacing a trailing comma.
+func closeObjects(buf *logdev.Buffer, n int) {
+	for range n {
+		if (*buf)[buf.Len()-1] == ',' {
+			buf.SetLen(buf.Len() - 1)
+		}
+		_, _ = buf.WriteString("},")
+	}
+}
+
+// appendBuiltin appends a built-in attr after applying ReplaceAttr. Uses
+// outKey as the key unless ReplaceAttr changes the key.
+func (h *JSONHandler) appendBuiltin(buf *logdev.Buffer, key, outKey string, v slog.Value) {
+	attr := slog.Attr{Key: key, Value: v}
+	if h.opts.ReplaceAttr != nil {
+		attr = h.opts.ReplaceAttr(nil, attr)
+		attr.Value = attr.Value.Resolve()
+		if attr.Equal(slog.Attr{}) {
+			return
+		}
+	}
+	if attr.Key == key {
+		attr.Key = outKey
+	}
+	*buf = appendString(*buf, attr.Key)
+	_ = buf.WriteByte(':')
+	appendValue(buf, attr.Value)
+	_ = buf.WriteByte(',')
+}
+
+// appendAttr appends an attr followed by a comma. Resolves LogValuers, except
+// errors, nests groups, applies ReplaceAttr to non-group attrs with resolved
+// values, and omits empty attrs and empty groups.
+func (h *JSONHandler) appendAttr(buf *logdev.Buffer, groups []string, attr slog.Attr) {
+	attr.Value = logerr.Resolve(attr.Value)
+	if attr.Value.Kind() == slog.KindGroup {
+		ga := attr.Value.Group()
+		if len(ga) == 0 {
+			return
+		}
+		if attr.Key == "" {
+			for _, a := range ga {
+				h.appendAttr(buf, groups, a) // an empty key inlines the group
+			}
+			return
+		}
+		start := buf.Len()
+		*buf = appendString(*buf, attr.Key)
+		_, _ = buf.WriteString(":{")
+		groupStart := buf.Len()
+		if h.opts.ReplaceAttr != nil {
+			// Only ReplaceAttr observes groups so skip the allocation otherwise.
+			groups = append(slices.Clip(groups), attr.Key)
+		}
+		for _, a := range ga {
+			h.appendAttr(buf, groups, a)
+		}
+		if buf.Len() == groupStart {
+			buf.SetLen(start) // all group attrs were empty
+			return
+		}
+		closeObjects(buf, 1)
+		return
+	}
+	if h.opts.ReplaceAttr != nil {
+		attr = logerr.ReplaceAttr(h.opts.ReplaceAttr, groups, attr)
+	}
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	*buf = appendString(*buf, attr.Key)
+	_ = buf.WriteByte(':')
+	appendValue(buf, attr.Value)
+	_ = buf.WriteByte(',')
+}
+
+func appendValue(buf *logdev.Buffer, v slog.Value) {
+	switch v.Kind() {
+	case slog.KindString:
+		*buf = appendString(*buf, v.String())
+	case slog.KindInt64:
+		*buf = strconv.AppendInt(*buf, v.Int64(), 10)
+	case slog.KindUint64:
+		*buf = strconv.AppendUint(*buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		f := v.Float64()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			// JSON has no representation for NaN or infinity.
+			*buf = appendString(*buf, strconv.FormatFloat(f, 'g', -1, 64))
+			return
+		}
+		*buf = strconv.AppendFloat(*buf, f, 'g', -1, 64)
+	case slog.KindBool:
+		*buf = strconv.AppendBool(*buf, v.Bool())
+	case slog.KindDuration:
+		*buf = strconv.AppendInt(*buf, int64(v.Duration()), 10) // nanoseconds, like slog
+	case slog.KindTime:
+		_ = buf.WriteByte('"')
+		*buf = slogfmt.AppendRFC3339Millis(*buf, v.Time())
+		_ = buf.WriteByte('"')
+	case slog.KindAny:
+		appendAny(buf, v.Any())
+	case slog.KindGroup:
+		_ = buf.WriteByte('{')
+		for _, ga := range v.Group() {
+			*buf = appendString(*buf, ga.Key)
+			_ = buf.WriteByte(':')
+			appendValue(buf, ga.Value.Resolve())
+			_ = buf.WriteByte(',')
+		}
+		if (*buf)[buf.Len()-1] == ',' {
+			buf.SetLen(buf.Len() - 1)
+		}
+		_ = buf.WriteByte('}')
+	case slog.KindLogValuer:
+		if err := logerr.ErrorOf(v); err != nil {
+			appendError(buf, err)
+			return
+		}
+		appendValue(buf, v.Resolve())
+	default:
+		panic(fmt.Sprintf("bad kind: %s", v.Kind()))
+	}
+}
+
+func appendAny(buf *logdev.Buffer, a any) {
+	switch a := a.(type) {
+	case slog.Level:
+		*buf = appendString(*buf, a.String())
+	case error:
+		appendError(buf, a)
+	case *slog.Source:
+		_, _ = buf.WriteString(`{"function":`)
+		*buf = appendString(*buf, a.Function)
+		_, _ = buf.WriteString(`,"file":`)
+		*buf = appendString(*buf, a.File)
+		_, _ = buf.WriteString(`,"line":`)
+		*buf = strconv.AppendInt(*buf, int64(a.Line), 10)
+		_ = buf.WriteByte('}')
+	default:
+		b, err := json.Marshal(a)
+		if err != nil {
+			*buf = appendString(*buf, "!ERROR:"+err.Error())
+			return
+		}
+		_, _ = buf.Write(b)
+	}
+}
+
+// appendError appends an error as an object with the message and the Go type
+// of the error. Adds the LogValue of errors that implement slog.LogValuer,
+// the stack of errors created by logerr, and the wrapped errors as causes, if
+// any.
+func appendError(buf *logdev.Buffer, err error) {
+	appendErrorDetail(buf, logerr.Describe(err))
+}
+
+func appendErrorDetail(buf *logdev.Buffer, d logerr.Detail) {
+	_, _ = buf.WriteString(`{"message":`)
+	*buf = appendString(*buf, d.Message)
+	_, _ = buf.WriteString(`,"type":`)
+	*buf = appendString(*buf, d.Type)
+	if !d.Value.Equal(slog.Value{}) {
+		_, _ = buf.WriteString(`,"value":`)
+		appendValue(buf, d.Value)
+	}
+	if len(d.Stack) > 0 {
+		_, _ = buf.WriteString(`,"stack":[`)
+		for i, f := range d.Stack {
+			if i > 0 {
+				_ = buf.WriteByte(',')
+			}
+			_, _ = buf.WriteString(`{"function":`)
+			*buf = appendString(*buf, f.Function)
+			_, _ = buf.WriteString(`,"file":`)
+			*buf = appendString(*buf, f.File)
+			_, _ = buf.WriteString(`,"line":`)
+			*buf = strconv.AppendInt(*buf, int64(f.Line), 10)
+			_ = buf.WriteByte('}')
+		}
+		_ = buf.WriteByte(']')
+	}
+	if len(d.Causes) > 0 {
+		_, _ = buf.WriteString(`,"causes":[`)
+		for i, c := range d.Causes {
+			if i > 0 {
+				_ = buf.WriteByte(',')
+			}
+			appendErrorDetail(buf, c)
+		}
+		_ = buf.WriteByte(']')
+	}
+	_ = buf.WriteByte('}')
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendString appends s as a quoted JSON string. Escapes quotes,
+// backslashes, control characters, and the line separators U+2028 and
+// U+2029. Replaces invalid UTF-8 with U+FFFD.
+func appendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= ' ' && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
@@ -0,0 +1,318 @@
+package logjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+	"github.com/jschaf/observe/log/logerr"
+)
+
+//nolint:gochecknoglobals
+var testTime = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+func TestJSONHandler_Handle(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  *Options
+		build func(h slog.Handler) slog.Handler
+		attrs []slog.Attr
+		want  string
+	}{
+		{
+			name: "no attrs",
+			want: `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg"}`,
+		},
+		{
+			name: "common kinds",
+			attrs: []slog.Attr{
+				slog.String("s", "str"),
+				slog.Int("i", -1),
+				slog.Uint64("u", 2),
+				slog.Float64("f", 1.5),
+				slog.Bool("b", true),
+				slog.Duration("d", time.Second),
+				slog.Time("t", testTime.Add(123*time.Millisecond)),
+			},
+			want: `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","s":"str","i":-1,"u":2,"f":1.5,"b":true,"d":1000000000,"t":"2024-01-01T12:00:00.123Z"}`,
+		},
+		{
+			name:  "escapes strings",
+			attrs: []slog.Attr{slog.String("a\"b", "q\"b\\n\n\t\x01\u2028\xff")},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","a\"b":"q\"b\\n\n\t\u0001\u2028\ufffd"}`,
+		},
+		{
+			name:  "non-finite floats",
+			attrs: []slog.Attr{slog.Float64("nan", math.NaN()), slog.Float64("inf", math.Inf(1))},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","nan":"NaN","inf":"+Inf"}`,
+		},
+		{
+			name:  "error",
+			attrs: []slog.Attr{slog.Any("err", errors.New("boom"))},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","err":{"message":"boom","type":"*errors.errorString"}}`,
+		},
+		{
+			name:  "wrapped error",
+			attrs: []slog.Attr{slog.Any("err", fmt.Errorf("read: %w", io.EOF))},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","err":{"message":"read: EOF","type":"*fmt.wrapError","causes":[{"message":"EOF","type":"*errors.errorString"}]}}`,
+		},
+		{
+			name:  "joined error",
+			attrs: []slog.Attr{slog.Any("err", errors.Join(io.EOF, io.ErrUnexpectedEOF))},
+			want: `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","err":{"message":"EOF\nunexpected EOF","type":"*errors.joinError","causes":[` +
+				`{"message":"EOF","type":"*errors.errorString"},{"message":"unexpected EOF","type":"*errors.errorString"}]}}`,
+		},
+		{
+			name:  "log valuer error",
+			attrs: []slog.Attr{slog.Any("err", &codeError{code: 7})},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","err":{"message":"code 7","type":"*logjson.codeError","value":{"code":7}}}`,
+		},
+		{
+			name:  "any",
+			attrs: []slog.Attr{slog.Any("m", map[string]int{"a": 1}), slog.Any("sl", []string{"x"})},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","m":{"a":1},"sl":["x"]}`,
+		},
+		{
+			name:  "group attr",
+			attrs: []slog.Attr{slog.Group("req", slog.String("method", "GET"), slog.Group("url", slog.String("path", "/")))},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","req":{"method":"GET","url":{"path":"/"}}}`,
+		},
+		{
+			name:  "empty and inline groups",
+			attrs: []slog.Attr{slog.Group("empty"), slog.Group("", slog.Int("a", 1)), {}, slog.Group("g", slog.Attr{})},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","a":1}`,
+		},
+		{
+			name:  "log valuer",
+			attrs: []slog.Attr{slog.Any("user", userValuer{name: "alice"})},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","user":"alice"}`,
+		},
+		{
+			name:  "with attrs",
+			build: func(h slog.Handler) slog.Handler { return h.WithAttrs([]slog.Attr{slog.String("svc", "api")}) },
+			attrs: []slog.Attr{slog.Int("a", 1)},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","svc":"api","a":1}`,
+		},
+		{
+			name:  "with group",
+			build: func(h slog.Handler) slog.Handler { return h.WithGroup("g") },
+			attrs: []slog.Attr{slog.Int("a", 1)},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","g":{"a":1}}`,
+		},
+		{
+			name:  "with group without attrs",
+			build: func(h slog.Handler) slog.Handler { return h.WithGroup("g") },
+			attrs: []slog.Attr{slog.Group("empty")},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg"}`,
+		},
+		{
+			name: "with attrs and nested groups",
+			build: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.Int("top", 0)}).
+					WithGroup("g1").WithAttrs([]slog.Attr{slog.Int("x", 1)}).
+					WithGroup("g2").WithGroup("g3")
+			},
+			attrs: []slog.Attr{slog.Int("y", 2)},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","top":0,"g1":{"x":1,"g2":{"g3":{"y":2}}}}`,
+		},
+		{
+			name: "with bound group and no record attrs",
+			build: func(h slog.Handler) slog.Handler {
+				return h.WithGroup("g1").WithAttrs([]slog.Attr{slog.Int("x", 1)}).WithGroup("g2")
+			},
+			want: `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","g1":{"x":1}}`,
+		},
+		{
+			name:  "trace attrs are top-level",
+			build: func(h slog.Handler) slog.Handler { return h.WithGroup("g") },
+			attrs: []slog.Attr{slog.String("trace_id", "abc"), slog.String("span_id", "def"), slog.Int("a", 1)},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","trace_id":"abc","span_id":"def","g":{"a":1}}`,
+		},
+		{
+			name: "field names",
+			opts: &Options{LevelKey: "severity", MessageKey: "message", TimeKey: "ts"},
+			want: `{"ts":"2024-01-01T12:00:00.000Z","severity":"INFO","message":"msg"}`,
+		},
+		{
+			name: "replace attr",
+			opts: &Options{
+				HandlerOptions: slog.HandlerOptions{
+					ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+						switch {
+						case a.Key == slog.TimeKey:
+							return slog.Attr{}
+						case a.Key == slog.LevelKey:
+							return slog.String("lvl", "notice")
+						case a.Key == "password":
+							return slog.String(a.Key, strings.Join(groups, ".")+":REDACTED")
+						}
+						return a
+					},
+				},
+				MessageKey: "message",
+			},
+			build: func(h slog.Handler) slog.Handler { return h.WithGroup("g") },
+			attrs: []slog.Attr{slog.Group("user", slog.String("password", "hunter2"))},
+			want:  `{"lvl":"notice","message":"msg","g":{"user":{"password":"g.user:REDACTED"}}}`,
+		},
+		{
+			name: "replace attr log valuer error",
+			opts: &Options{
+				HandlerOptions: slog.HandlerOptions{
+					ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+						switch {
+						case a.Value.Kind() == slog.KindLogValuer:
+							return slog.String(a.Key, "unresolved")
+						case a.Key == "code":
+							return slog.Attr{Key: a.Key, Value: a.Value.Group()[0].Value}
+						}
+						return a
+					},
+				},
+			},
+			attrs: []slog.Attr{slog.Any("err", &codeError{code: 7}), slog.Any("code", &codeError{code: 8})},
+			want:  `{"time":"2024-01-01T12:00:00.000Z","level":"INFO","msg":"msg","err":{"message":"code 7","type":"*logjson.codeError","value":{"code":7}},"code":8}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			var h slog.Handler = NewJSONHandler(buf, tt.opts)
+			if tt.build != nil {
+				h = tt.build(h)
+			}
+			r := slog.NewRecord(testTime, slog.LevelInfo, "msg", 0)
+			r.AddAttrs(tt.attrs...)
+			if err := h.Handle(t.Context(), r); err != nil {
+				t.Fatalf("handle record: %v", err)
+			}
+			got := buf.String()
+			difftest.AssertSame(t, "JSONHandler mismatch", tt.want+"\n", got)
+			if !json.Valid([]byte(got)) {
+				t.Errorf("invalid JSON: %s", got)
+			}
+		})
+	}
+}
+
+func TestJSONHandler_AddSource(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h := NewJSONHandler(buf, &Options{HandlerOptions: slog.HandlerOptions{AddSource: true}})
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	_, file, line, _ := runtime.Caller(0)
+	r := slog.NewRecord(testTime, slog.LevelWarn, "msg", pcs[0])
+	if err := h.Handle(t.Context(), r); err != nil {
+		t.Fatalf("handle record: %v", err)
+	}
+
+	want := `{"time":"2024-01-01T12:00:00.000Z","level":"WARN","msg":"msg","source":{"function":"github.com/jschaf/observe/log/logjson.TestJSONHandler_AddSource","file":"` +
+		file + `","line":` + strconv.Itoa(line-1) + "}}\n"
+	difftest.AssertSame(t, "JSONHandler mismatch", want, buf.String())
+}
+
+func TestGCPOptions(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  string
+	}{
+		{slog.LevelDebug, "DEBUG"},
+		{slog.LevelInfo, "INFO"},
+		{slog.LevelWarn, "WARNING"},
+		{slog.LevelWarn + 2, "WARNING"},
+		{slog.LevelError, "ERROR"},
+		{slog.LevelError + 4, "CRITICAL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			opts := GCPOptions()
+			h := NewJSONHandler(buf, &opts)
+			if err := h.Handle(t.Context(), slog.NewRecord(testTime, tt.level, "msg", 0)); err != nil {
+				t.Fatalf("handle record: %v", err)
+			}
+			want := `{"time":"2024-01-01T12:00:00.000Z","severity":"` + tt.want + `","message":"msg"}` + "\n"
+			difftest.AssertSame(t, "JSONHandler mismatch", want, buf.String())
+		})
+	}
+}
+
+func TestJSONHandler_Enabled(t *testing.T) {
+	h := NewJSONHandler(&bytes.Buffer{}, &Options{HandlerOptions: slog.HandlerOptions{Level: slog.LevelWarn}})
+	difftest.AssertSame(t, "info enabled", false, h.Enabled(t.Context(), slog.LevelInfo))
+	difftest.AssertSame(t, "warn enabled", true, h.Enabled(t.Context(), slog.LevelWarn))
+}
+
+func TestJSONHandler_ErrorStack(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := slog.New(NewJSONHandler(buf, nil))
+	l.Error("msg", "err", logerr.New("boom"))
+
+	var got struct {
+		Err struct {
+			Message string
+			Stack   []logerr.Frame
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal %s: %v", buf.String(), err)
+	}
+	difftest.AssertSame(t, "message", "boom", got.Err.Message)
+	if len(got.Err.Stack) == 0 {
+		t.Fatalf("got no stack: %s", buf.String())
+	}
+	top := got.Err.Stack[0]
+	difftest.AssertSame(t, "function", "github.com/jschaf/observe/log/logjson.TestJSONHandler_ErrorStack", top.Function)
+	difftest.AssertSame(t, "file", "json_handler_test.go", filepath.Base(top.File))
+}
+
+type userValuer struct{ name string }
+
+func (u userValuer) LogValue() slog.Value { return slog.StringValue(u.name) }
+
+type codeError struct{ code int }
+
+func (e *codeError) Error() string { return "code " + strconv.Itoa(e.code) }
+
+func (e *codeError) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("code", e.code))
+}
+
+func BenchmarkJSONHandler_Handle(b *testing.B) {
+	ctx := b.Context()
+	buf := &bytes.Buffer{}
+	h := NewJSONHandler(buf, nil).WithAttrs([]slog.Attr{slog.String("svc", "api")}).WithGroup("req")
+
+	r := slog.NewRecord(testTime, slog.LevelInfo, "request handled", 0)
+	r.AddAttrs(
+		slog.String("method", "GET"),
+		slog.String("path", "/items/\"123\""),
+		slog.Int("status", 200),
+		slog.Duration("dur", 3*time.Millisecond),
+		slog.Float64("ratio", 0.25),
+		slog.Bool("cached", true),
+		slog.Time("start", testTime),
+		slog.Any("err", errors.New("boom")),
+		slog.Group("user", slog.String("id", "u1")),
+	)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		buf.Reset() // reset the buffer to avoid accumulation of data
+		err := h.Handle(ctx, r)
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package logotlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jschaf/observe/internal/pbwire"
+)
+
+// Field numbers of the OTLP request messages.
+const (
+	requestResourceLogs  = 1 // ExportLogsServiceRequest.resource_logs
+	resourceLogsResource = 1 // ResourceLogs.resource
+	resourceLogsScope    = 2 // ResourceLogs.scope_logs
+	resourceAttributes   = 1 // Resource.attributes
+	scopeLogsScope       = 1 // ScopeLogs.scope
+	scopeLogsLogRecords  = 2 // ScopeLogs.log_records
+	scopeNameField       = 1 // InstrumentationScope.name
+)
+
+var errShutdown = errors.New("otlp log handler shut down")
+
+// exporter batches encoded log records and exports them. Shared by a Handler
+// and the handlers derived with WithAttrs and WithGroup.
+type exporter struct {
+	cfg config
+	// resource and scope are the encoded Resource and InstrumentationScope
+	// fields of each request.
+	resource []byte
+	scope    []byte
+
+	mu     sync.Mutex // guards the fields below
+	queue  []byte     // encoded LogRecord fields
+	ends   []int      // end offset of each record in queue
+	closed bool
+
+	exportMu sync.Mutex // serializes exports to keep records in order
+	dropped  atomic.Uint64
+	full     chan struct{} // signals a full batch
+	stop     chan struct{}
+	done     chan struct{} // closed when the goroutine exits
+	// ctx cancels background exports if shutdown times out.
+	ctx    context.Context //nolint:containedctx // cancels background exports
+	cancel context.CancelFunc
+}
+
+func newExporter(cfg config) *exporter {
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &exporter{
+		cfg:    cfg,
+		full:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	var pos int
+	e.resource, pos = pbwire.BeginMessage(nil, resourceLogsResource)
+	for _, a := range cfg.resource {
+		e.resource = appendKeyValue(e.resource, resourceAttributes, "", a)
+	}
+	e.resource = pbwire.EndMessage(e.resource, pos)
+	e.scope, pos = pbwire.BeginMessage(nil, scopeLogsScope)
+	e.scope = pbwire.AppendString(e.scope, scopeNameField, scopeName)
+	e.scope = pbwire.EndMessage(e.scope, pos)
+	go e.run()
+	return e
+}
+
+// add copies the encoded record into the queue.
+func (e *exporter) add(rec []byte) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return errShutdown
+	}
+	if len(e.ends) >= e.cfg.maxQueueSize {
+		e.mu.Unlock()
+		e.dropped.Add(1)
+		return nil
+	}
+	e.queue = append(e.queue, rec...)
+	e.ends = append(e.ends, len(e.queue))
+	full := len(e.ends) >= e.cfg.batchSize
+	e.mu.Unlock()
+	if full {
+		select {
+		case e.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.cfg.batchTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-e.full:
+		case <-ticker.C:
+		}
+		if err := e.flush(e.ctx); err != nil && e.cfg.onError != nil {
+			e.cfg.onError(err)
+		}
+	}
+}
+
+// flush exports batches until the queue is empty. Returns the first error,
+// dropping the batch that failed.
+func (e *exporter) flush(ctx context.Context) error {
+	e.exportMu.Lock()
+	defer e.exportMu.Unlock()
+	for {
+		req := e.takeBatch()
+		if req == nil {
+			return nil
+		}
+		if err := e.export(ctx, req); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *exporter) shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.stop)
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		e.cancel()
+		return fmt.Errorf("shutdown otlp log handler: %w", ctx.Err())
+	}
+	defer e.cancel()
+	return e.flush(ctx)
+}
+
+// takeBatch removes up to a batch of records from the queue and returns them
+// encoded as an ExportLogsServiceRequest, or nil if the queue is empty.
+func (e *exporter) takeBatch() []byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := min(len(e.ends), e.cfg.batchSize)
+	if n == 0 {
+		return nil
+	}
+	end := e.ends[n-1]
+	// Write the message lengths up front so the records are copied once.
+	var hdr [2 * binary.MaxVarintLen64]byte
+	scopeLen := len(e.scope) + end
+	scopeHdr := pbwire.AppendMessageHeader(hdr[:0], resourceLogsScope, scopeLen)
+	resourceLen := len(e.resource) + len(scopeHdr) + scopeLen
+	b := make([]byte, 0, len(hdr)+resourceLen)
+	b = pbwire.AppendMessageHeader(b, requestResourceLogs, resourceLen)
+	b = append(b, e.resource...)
+	b = append(b, scopeHdr...)
+	b = append(b, e.scope...)
+	b = append(b, e.queue[:end]...)
+
+	e.queue = append(e.queue[:0], e.queue[end:]...)
+	for i := n; i < len(e.ends); i++ {
+		e.ends[i-n] = e.ends[i] - end
+	}
+	e.ends = e.ends[:len(e.ends)-n]
+	return b
+}
+
+// export sends the request, retrying retryable failures with exponential
+// backoff.
+func (e *exporter) export(ctx context.Context, req []byte) error {
+	backoff := e.cfg.retryBackoff
+	for attempt := 1; ; attempt++ {
+		wait, retry, err := e.send(ctx, req)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= e.cfg.maxAttempts {
+			return err
+		}
+		if wait <= 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("export logs: %w", errors.Join(err, ctx.Err()))
+		}
+	}
+}
+
+// send posts the request once. Returns whether to retry and how long the
+// server asked to wait before retrying, if at all.
+func (e *exporter) send(ctx context.Context, body []byte) (wait time.Duration, retry bool, err error) {
+	sendCtx, cancel := context.WithTimeout(ctx, e.cfg.exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(sendCtx, http.MethodPost, e.cfg.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, fmt.Errorf("create export logs request: %w", err)
+	}
+	for k, vs := range e.cfg.headers {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	resp, err := e.cfg.client.Do(req)
+	if err != nil {
+		// Retry network errors and timeouts unless the caller gave up.
+		return 0, ctx.Err() == nil, fmt.Errorf("export logs: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // reuse the connection
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, false, nil
+	}
+	err = fmt.Errorf("export logs: unexpected status %s", resp.Status)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return retryAfter(resp.Header.Get("Retry-After")), true, err
+	default:
+		return 0, false, err
+	}
+}
+
+// retryAfter parses the seconds of a Retry-After header, or returns zero.
+func retryAfter(s string) time.Duration {
+	secs, err := strconv.Atoi(s)
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
@@ -0,0 +1,229 @@
+// Package logotlp provides a slog.Handler that exports records as
+// OpenTelemetry log records using OTLP/HTTP with protobuf encoding.
+// https://opentelemetry.io/docs/specs/otlp/
+package logotlp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jschaf/observe/internal/logkey"
+	"github.com/jschaf/observe/internal/pbwire"
+	"github.com/jschaf/observe/trace"
+)
+
+// scopeName is the instrumentation scope of exported records.
+const scopeName = "github.com/jschaf/observe/log/logotlp"
+
+// Field numbers of the OTLP LogRecord message.
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/logs/v1/logs.proto
+const (
+	logRecordTime           = 1
+	logRecordSeverityNumber = 2
+	logRecordSeverityText   = 3
+	logRecordBody           = 5
+	logRecordAttributes     = 6
+	logRecordFlags          = 8
+	logRecordTraceID        = 9
+	logRecordSpanID         = 10
+	logRecordObservedTime   = 11
+)
+
+// Field numbers of the OTLP AnyValue message.
+const (
+	anyValueString = 1
+	anyValueBool   = 2
+	anyValueInt    = 3
+	anyValueDouble = 4
+	anyValueBytes  = 7
+)
+
+// Handler is a slog.Handler that encodes records as OTLP log records and
+// exports them in batches from a background goroutine. Flattens groups into
+// dotted attribute keys, like "req.method". Records logged with a context
+// containing a span include the trace and span ID.
+//
+// Call Shutdown to export the remaining records and stop the goroutine.
+type Handler struct {
+	exp    *exporter
+	attrs  []byte // encoded attributes from WithAttrs
+	prefix string // dotted group prefix from WithGroup
+}
+
+// NewHandler returns a handler that exports records to an OTLP/HTTP
+// collector.
+func NewHandler(opts ...Option) *Handler {
+	return &Handler{exp: newExporter(newConfig(opts))}
+}
+
+//nolint:gochecknoglobals
+var bufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+func (h *Handler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.exp.cfg.level.Level()
+}
+
+// Handle encodes the record and queues it for export. Drops the record if
+// the queue is full. Returns an error if the handler is shut down.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	bp, _ := bufPool.Get().(*[]byte)
+	b := h.appendRecord((*bp)[:0], ctx, r)
+	err := h.exp.add(b)
+	// To reduce peak allocation, return only smaller buffers to the pool.
+	if cap(b) <= 16<<10 {
+		*bp = b
+		bufPool.Put(bp)
+	}
+	return err
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = append([]byte(nil), h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = appendKeyValue(h2.attrs, logRecordAttributes, h.prefix, a)
+	}
+	return &h2
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// Flush exports the queued records, waiting until the export finishes or
+// ctx is done.
+func (h *Handler) Flush(ctx context.Context) error {
+	return h.exp.flush(ctx)
+}
+
+// Shutdown stops accepting records, exports the queued records, and stops
+// the background goroutine.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	return h.exp.shutdown(ctx)
+}
+
+// Dropped returns the number of records dropped because the queue was full.
+func (h *Handler) Dropped() uint64 {
+	return h.exp.dropped.Load()
+}
+
+// appendRecord appends the record as a LogRecord field of a ScopeLogs
+// message.
+func (h *Handler) appendRecord(b []byte, ctx context.Context, r slog.Record) []byte {
+	b, pos := pbwire.BeginMessage(b, scopeLogsLogRecords)
+	if !r.Time.IsZero() {
+		b = pbwire.AppendFixed64(b, logRecordTime, uint64(r.Time.UnixNano())) //nolint:gosec // after 1970
+	}
+	b = pbwire.AppendFixed64(b, logRecordObservedTime, uint64(time.Now().UnixNano())) //nolint:gosec // after 1970
+	b = pbwire.AppendVarint(b, logRecordSeverityNumber, severityNumber(r.Level))
+	b = pbwire.AppendString(b, logRecordSeverityText, r.Level.String())
+	b, body := pbwire.BeginMessage(b, logRecordBody)
+	b = pbwire.AppendString(b, anyValueString, r.Message)
+	b = pbwire.EndMessage(b, body)
+	b = append(b, h.attrs...)
+
+	sc := trace.SpanFromContext(ctx).Context()
+	r.Attrs(func(a slog.Attr) bool {
+		// The trace correlation attrs duplicate the trace fields.
+		if sc.IsValid() && logkey.IsTrace(a.Key) {
+			return true
+		}
+		b = appendKeyValue(b, logRecordAttributes, h.prefix, a)
+		return true
+	})
+	if sc.IsValid() {
+		b = pbwire.AppendFixed32(b, logRecordFlags, uint32(sc.Flags))
+		traceID := sc.TraceID.Raw()
+		b = pbwire.AppendBytes(b, logRecordTraceID, traceID[:])
+		spanID := sc.SpanID.Raw()
+		b = pbwire.AppendBytes(b, logRecordSpanID, spanID[:])
+	}
+	return pbwire.EndMessage(b, pos)
+}
+
+// severityNumber maps a level to an OTLP severity number. The slog levels
+// map to the first severity number of the matching OTLP range, like
+// slog.LevelWarn to WARN (13), and levels between them to the numbers in
+// between, like slog.LevelWarn+1 to WARN2 (14).
+// https://opentelemetry.io/docs/specs/otel/logs/data-model-appendix/#appendix-b-severitynumber-example-mappings
+func severityNumber(l slog.Level) uint64 {
+	return uint64(min(max(int(l)+9, 1), 24)) //nolint:gosec // clamped to 1..24
+}
+
+// appendKeyValue appends the attr as KeyValue fields with the field number,
+// flattening groups into keys prefixed by the group names.
+func appendKeyValue(b []byte, num int, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return b
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			b = appendKeyValue(b, num, prefix, ga)
+		}
+		return b
+	}
+	b, pos := pbwire.BeginMessage(b, num)
+	b, key := pbwire.BeginMessage(b, 1)
+	b = append(b, prefix...)
+	b = append(b, a.Key...)
+	b = pbwire.EndMessage(b, key)
+	b, val := pbwire.BeginMessage(b, 2)
+	b = appendAnyValue(b, a.Value)
+	b = pbwire.EndMessage(b, val)
+	return pbwire.EndMessage(b, pos)
+}
+
+// appendAnyValue appends the fields of an AnyValue message.
+func appendAnyValue(b []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return pbwire.AppendString(b, anyValueString, v.String())
+	case slog.KindBool:
+		return pbwire.AppendBool(b, anyValueBool, v.Bool())
+	case slog.KindInt64:
+		return pbwire.AppendInt64(b, anyValueInt, v.Int64())
+	case slog.KindUint64:
+		if n := v.Uint64(); n <= math.MaxInt64 {
+			return pbwire.AppendInt64(b, anyValueInt, int64(n))
+		}
+		return pbwire.AppendDouble(b, anyValueDouble, float64(v.Uint64()))
+	case slog.KindFloat64:
+		return pbwire.AppendDouble(b, anyValueDouble, v.Float64())
+	case slog.KindDuration:
+		return pbwire.AppendInt64(b, anyValueInt, int64(v.Duration()))
+	case slog.KindTime:
+		return pbwire.AppendString(b, anyValueString, v.Time().Format(time.RFC3339Nano))
+	case slog.KindAny, slog.KindGroup, slog.KindLogValuer:
+		switch x := v.Any().(type) {
+		case []byte:
+			return pbwire.AppendBytes(b, anyValueBytes, x)
+		case error:
+			return pbwire.AppendString(b, anyValueString, x.Error())
+		default:
+			return pbwire.AppendString(b, anyValueString, fmt.Sprint(x))
+		}
+	default:
+		return pbwire.AppendString(b, anyValueString, v.String())
+	}
+}
@@ -0,0 +1,461 @@
+package logotlp
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+	"github.com/jschaf/observe/internal/pbwire"
+	"github.com/jschaf/observe/log"
+	"github.com/jschaf/observe/trace"
+)
+
+func TestHandler(t *testing.T) {
+	c := newTestCollector(t)
+	h := NewHandler(
+		WithEndpoint(c.url),
+		WithHeaders(http.Header{"Authorization": {"Bearer token"}}),
+		WithResource(slog.String("service.name", "api")),
+		WithLevel(slog.LevelDebug),
+	)
+	l := slog.New(h)
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	l.Debug("debug")
+	l.With("a", 1).WithGroup("req").Info("info",
+		"method", "GET",
+		slog.Group("user", "id", uint64(7), "admin", true),
+		"latency", 1500*time.Millisecond,
+		"ratio", 0.5,
+		"body", []byte("raw"),
+		"err", errors.New("boom"),
+		"at", ts,
+	)
+	l.Log(t.Context(), slog.LevelWarn+1, "warn2")
+	if err := h.Shutdown(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := c.requests()
+	difftest.AssertSame(t, "request count", 1, len(reqs))
+	difftest.AssertSame(t, "content type", "application/x-protobuf", reqs[0].contentType)
+	difftest.AssertSame(t, "authorization", "Bearer token", reqs[0].auth)
+	difftest.AssertSame(t, "resource", []string{"service.name=api"}, reqs[0].resource)
+	difftest.AssertSame(t, "scope", scopeName, reqs[0].scope)
+	got := make([]string, 0, len(reqs[0].records))
+	for _, r := range reqs[0].records {
+		got = append(got, r.String())
+	}
+	want := []string{
+		"sev=5 DEBUG body=debug",
+		"sev=9 INFO body=info a=1 req.method=GET req.user.id=7 req.user.admin=true req.latency=1500000000 req.ratio=0.5 req.body=[]byte(raw) req.err=boom req.at=2024-01-02T03:04:05Z",
+		"sev=14 WARN+1 body=warn2",
+	}
+	difftest.AssertSame(t, "records", want, got)
+	for i, r := range reqs[0].records {
+		if r.time == 0 || r.observed == 0 {
+			t.Errorf("record %d: got time %d and observed time %d, want both set", i, r.time, r.observed)
+		}
+	}
+}
+
+func TestHandler_Trace(t *testing.T) {
+	c := newTestCollector(t)
+	h := NewHandler(WithEndpoint(c.url))
+	tr := trace.NewTracer()
+	ctx, span := tr.Start(t.Context(), "root")
+	sc := span.Context()
+
+	// Logging through the log package adds trace correlation attrs, which
+	// the trace fields replace.
+	slog.SetDefault(slog.New(h))
+	t.Cleanup(func() { slog.SetDefault(slog.New(slog.DiscardHandler)) })
+	log.Info(ctx, "traced", slog.Int("n", 1))
+	log.Info(t.Context(), "untraced")
+	if err := h.Shutdown(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	records := c.requests()[0].records
+	difftest.AssertSame(t, "record count", 2, len(records))
+	difftest.AssertSame(t, "traced", "sev=9 INFO body=traced n=1", records[0].String())
+	difftest.AssertSame(t, "trace id", sc.TraceID.String(), records[0].traceID)
+	difftest.AssertSame(t, "span id", sc.SpanID.String(), records[0].spanID)
+	difftest.AssertSame(t, "flags", uint64(sc.Flags), records[0].flags)
+	difftest.AssertSame(t, "untraced", "sev=9 INFO body=untraced", records[1].String())
+	difftest.AssertSame(t, "untraced trace id", "", records[1].traceID)
+}
+
+func TestHandler_Batch(t *testing.T) {
+	c := newTestCollector(t)
+	h := NewHandler(WithEndpoint(c.url), WithBatch(2, time.Hour))
+	l := slog.New(h)
+	for i := range 5 {
+		l.Info(fmt.Sprintf("m%d", i))
+	}
+	// The full batches export in the background.
+	waitFor(t, func() bool { return len(c.requests()) >= 2 })
+	if err := h.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	var sizes []int
+	for _, req := range c.requests() {
+		sizes = append(sizes, len(req.records))
+	}
+	difftest.AssertSame(t, "batch sizes", []int{2, 2, 1}, sizes)
+	if err := h.Shutdown(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Handle(t.Context(), slog.Record{}); err == nil {
+		t.Error("got nil error after shutdown, want error")
+	}
+}
+
+func TestHandler_BatchTimeout(t *testing.T) {
+	c := newTestCollector(t)
+	h := NewHandler(WithEndpoint(c.url), WithBatch(100, time.Millisecond))
+	t.Cleanup(func() { _ = h.Shutdown(t.Context()) })
+	slog.New(h).Info("m")
+	waitFor(t, func() bool { return len(c.requests()) == 1 })
+}
+
+func TestHandler_InvalidOptions(t *testing.T) {
+	c := newTestCollector(t)
+	// Non-positive values use the defaults instead of stalling or panicking.
+	h := NewHandler(WithEndpoint(c.url), WithBatch(0, 0), WithMaxQueueSize(-1), WithRetry(0, -time.Second))
+	slog.New(h).Info("m")
+	if err := h.Shutdown(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	difftest.AssertSame(t, "dropped", uint64(0), h.Dropped())
+	difftest.AssertSame(t, "requests", 1, len(c.requests()))
+}
+
+func TestHandler_MaxQueueSize(t *testing.T) {
+	c := newTestCollector(t)
+	h := NewHandler(WithEndpoint(c.url), WithBatch(2, time.Hour), WithMaxQueueSize(3))
+	c.block()
+	l := slog.New(h)
+	// The first batch is stuck exporting, so the queue holds 3 more.
+	l.Info("m0")
+	l.Info("m1")
+	waitFor(t, func() bool { return c.pending.Load() == 1 })
+	for i := 2; i < 7; i++ {
+		l.Info(fmt.Sprintf("m%d", i))
+	}
+	difftest.AssertSame(t, "dropped", uint64(2), h.Dropped())
+	c.unblock()
+	if err := h.Shutdown(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	var bodies []string
+	for _, req := range c.requests() {
+		for _, r := range req.records {
+			bodies = append(bodies, r.body)
+		}
+	}
+	difftest.AssertSame(t, "bodies", []string{"m0", "m1", "m2", "m3", "m4"}, bodies)
+}
+
+func TestHandler_Retry(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantErr   bool
+		wantCalls int
+	}{
+		{"ok", []int{200}, false, 1},
+		{"retry 503", []int{503, 503, 200}, false, 3},
+		{"retry 429", []int{429, 200}, false, 2},
+		{"no retry 400", []int{400}, true, 1},
+		{"max attempts", []int{503, 503, 503, 503}, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCollector(t)
+			c.statuses = tt.statuses
+			var errs []error
+			h := NewHandler(
+				WithEndpoint(c.url),
+				WithRetry(3, time.Millisecond),
+				WithErrorHandler(func(err error) { errs = append(errs, err) }),
+			)
+			slog.New(h).Info("m")
+			err := h.Shutdown(t.Context())
+			difftest.AssertSame(t, "error", tt.wantErr, err != nil)
+			difftest.AssertSame(t, "calls", tt.wantCalls, int(c.calls.Load()))
+			difftest.AssertSame(t, "background errors", 0, len(errs))
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"2", 2 * time.Second},
+		{"", 0},
+		{"-1", 0},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header); got != tt.want {
+			t.Errorf("retryAfter(%q) = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestSeverityNumber(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  uint64
+	}{
+		{slog.LevelDebug - 8, 1},
+		{slog.LevelDebug - 4, 1},
+		{slog.LevelDebug, 5},
+		{slog.LevelInfo, 9},
+		{slog.LevelInfo + 1, 10},
+		{slog.LevelWarn, 13},
+		{slog.LevelError, 17},
+		{slog.LevelError + 4, 21},
+		{slog.LevelError + 8, 24},
+		{math.MaxInt8, 24},
+	}
+	for _, tt := range tests {
+		difftest.AssertSame(t, tt.level.String(), tt.want, severityNumber(tt.level))
+	}
+}
+
+func BenchmarkHandler_Handle(b *testing.B) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	b.Cleanup(srv.Close)
+	h := NewHandler(WithEndpoint(srv.URL))
+	b.Cleanup(func() { _ = h.Shutdown(b.Context()) })
+	l := slog.New(h.WithAttrs([]slog.Attr{slog.String("service", "api")}))
+	ctx := b.Context()
+	b.ReportAllocs()
+	for b.Loop() {
+		l.LogAttrs(ctx, slog.LevelInfo, "request", slog.String("method", "GET"), slog.Int("status", 200))
+	}
+}
+
+// testCollector is an OTLP/HTTP collector that decodes export requests.
+type testCollector struct {
+	t        *testing.T
+	url      string
+	statuses []int // response statuses in order, then 200
+	calls    atomic.Int32
+	pending  atomic.Int32 // requests waiting on the gate
+	gate     chan struct{}
+
+	mu   sync.Mutex
+	reqs []testRequest
+}
+
+type testRequest struct {
+	contentType string
+	auth        string
+	resource    []string
+	scope       string
+	records     []testRecord
+}
+
+type testRecord struct {
+	time, observed uint64
+	severity       uint64
+	severityText   string
+	body           string
+	attrs          []string
+	flags          uint64
+	traceID        string
+	spanID         string
+}
+
+func (r testRecord) String() string {
+	return strings.Join(append([]string{fmt.Sprintf("sev=%d %s body=%s", r.severity, r.severityText, r.body)}, r.attrs...), " ")
+}
+
+func newTestCollector(t *testing.T) *testCollector {
+	t.Helper()
+	c := &testCollector{t: t}
+	srv := httptest.NewServer(http.HandlerFunc(c.serveHTTP))
+	t.Cleanup(srv.Close)
+	c.url = srv.URL + "/v1/logs"
+	return c
+}
+
+func (c *testCollector) block() { c.gate = make(chan struct{}) }
+
+func (c *testCollector) unblock() { close(c.gate) }
+
+func (c *testCollector) requests() []testRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.reqs)
+}
+
+func (c *testCollector) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	n := int(c.calls.Add(1))
+	if c.gate != nil {
+		c.pending.Add(1)
+		<-c.gate
+	}
+	if n <= len(c.statuses) && c.statuses[n-1] != http.StatusOK {
+		w.WriteHeader(c.statuses[n-1])
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		c.t.Errorf("read body: %v", err)
+		return
+	}
+	req := testRequest{contentType: r.Header.Get("Content-Type"), auth: r.Header.Get("Authorization")}
+	if err := decodeRequest(body, &req); err != nil {
+		c.t.Errorf("decode request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.reqs = append(c.reqs, req)
+	c.mu.Unlock()
+}
+
+// forEachField calls f for each field in the message b.
+func forEachField(b []byte, f func(pbwire.Field) error) error {
+	for len(b) > 0 {
+		field, rest, err := pbwire.ConsumeField(b)
+		if err != nil {
+			return err
+		}
+		if err := f(field); err != nil {
+			return err
+		}
+		b = rest
+	}
+	return nil
+}
+
+func decodeRequest(b []byte, req *testRequest) error {
+	return forEachField(b, func(f pbwire.Field) error { // ExportLogsServiceRequest
+		return forEachField(f.Bytes, func(f pbwire.Field) error { // ResourceLogs
+			switch f.Num {
+			case resourceLogsResource:
+				return forEachField(f.Bytes, func(f pbwire.Field) error {
+					kv, err := decodeKeyValue(f.Bytes)
+					req.resource = append(req.resource, kv)
+					return err
+				})
+			case resourceLogsScope:
+				return forEachField(f.Bytes, func(f pbwire.Field) error { // ScopeLogs
+					switch f.Num {
+					case scopeLogsScope:
+						return forEachField(f.Bytes, func(f pbwire.Field) error {
+							req.scope = string(f.Bytes)
+							return nil
+						})
+					case scopeLogsLogRecords:
+						r, err := decodeRecord(f.Bytes)
+						req.records = append(req.records, r)
+						return err
+					}
+					return nil
+				})
+			}
+			return nil
+		})
+	})
+}
+
+func decodeRecord(b []byte) (testRecord, error) {
+	var r testRecord
+	err := forEachField(b, func(f pbwire.Field) error {
+		switch f.Num {
+		case logRecordTime:
+			r.time = f.Varint
+		case logRecordObservedTime:
+			r.observed = f.Varint
+		case logRecordSeverityNumber:
+			r.severity = f.Varint
+		case logRecordSeverityText:
+			r.severityText = string(f.Bytes)
+		case logRecordBody:
+			v, err := decodeAnyValue(f.Bytes)
+			r.body = v
+			return err
+		case logRecordAttributes:
+			kv, err := decodeKeyValue(f.Bytes)
+			r.attrs = append(r.attrs, kv)
+			return err
+		case logRecordFlags:
+			r.flags = f.Varint
+		case logRecordTraceID:
+			r.traceID = hex.EncodeToString(f.Bytes)
+		case logRecordSpanID:
+			r.spanID = hex.EncodeToString(f.Bytes)
+		}
+		return nil
+	})
+	return r, err
+}
+
+// decodeKeyValue decodes a KeyValue message as "key=value".
+func decodeKeyValue(b []byte) (string, error) {
+	var key, val string
+	err := forEachField(b, func(f pbwire.Field) error {
+		if f.Num == 1 {
+			key = string(f.Bytes)
+			return nil
+		}
+		v, err := decodeAnyValue(f.Bytes)
+		val = v
+		return err
+	})
+	return key + "=" + val, err
+}
+
+func decodeAnyValue(b []byte) (string, error) {
+	var s string
+	err := forEachField(b, func(f pbwire.Field) error {
+		switch f.Num {
+		case anyValueString:
+			s = string(f.Bytes)
+		case anyValueBool:
+			s = fmt.Sprint(f.Varint == 1)
+		case anyValueInt:
+			s = fmt.Sprint(int64(f.Varint)) //nolint:gosec // two's complement
+		case anyValueDouble:
+			s = fmt.Sprint(math.Float64frombits(f.Varint))
+		case anyValueBytes:
+			s = "[]byte(" + string(f.Bytes) + ")"
+		default:
+			return fmt.Errorf("unexpected AnyValue field %d", f.Num)
+		}
+		return nil
+	})
+	return s, err
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,149 @@
+package logotlp
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type config struct {
+	endpoint      string
+	headers       http.Header
+	client        *http.Client
+	level         slog.Leveler
+	resource      []slog.Attr
+	batchSize     int
+	batchTimeout  time.Duration
+	maxQueueSize  int
+	maxAttempts   int
+	retryBackoff  time.Duration
+	exportTimeout time.Duration
+	onError       func(error)
+}
+
+// Option configures a Handler.
+type Option func(config) config
+
+func newConfig(opts []Option) config {
+	def := config{
+		endpoint:      "http://localhost:4318/v1/logs",
+		client:        http.DefaultClient,
+		level:         slog.LevelInfo,
+		batchSize:     512,
+		batchTimeout:  5 * time.Second,
+		maxQueueSize:  2048,
+		maxAttempts:   5,
+		retryBackoff:  100 * time.Millisecond,
+		exportTimeout: 10 * time.Second,
+	}
+	cfg := def
+	for _, opt := range opts {
+		cfg = opt(cfg)
+	}
+	// Use the defaults for non-positive sizes and durations, which would
+	// stall or panic the export goroutine.
+	if cfg.batchSize <= 0 {
+		cfg.batchSize = def.batchSize
+	}
+	if cfg.batchTimeout <= 0 {
+		cfg.batchTimeout = def.batchTimeout
+	}
+	if cfg.maxQueueSize <= 0 {
+		cfg.maxQueueSize = def.maxQueueSize
+	}
+	if cfg.maxAttempts <= 0 {
+		cfg.maxAttempts = def.maxAttempts
+	}
+	if cfg.retryBackoff <= 0 {
+		cfg.retryBackoff = def.retryBackoff
+	}
+	cfg.maxQueueSize = max(cfg.maxQueueSize, cfg.batchSize)
+	return cfg
+}
+
+// WithEndpoint sets the URL of the OTLP/HTTP logs endpoint. Defaults to
+// http://localhost:4318/v1/logs.
+func WithEndpoint(url string) Option {
+	return func(cfg config) config {
+		cfg.endpoint = url
+		return cfg
+	}
+}
+
+// WithHeaders sets headers to send with each export request, like for
+// authentication.
+func WithHeaders(h http.Header) Option {
+	return func(cfg config) config {
+		cfg.headers = h
+		return cfg
+	}
+}
+
+// WithHTTPClient sets the client for export requests. Defaults to
+// http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cfg config) config {
+		cfg.client = c
+		return cfg
+	}
+}
+
+// WithLevel sets the minimum level of exported records. Defaults to
+// slog.LevelInfo.
+func WithLevel(l slog.Leveler) Option {
+	return func(cfg config) config {
+		cfg.level = l
+		return cfg
+	}
+}
+
+// WithResource sets the attributes of the resource producing the logs,
+// like service.name.
+func WithResource(attrs ...slog.Attr) Option {
+	return func(cfg config) config {
+		cfg.resource = attrs
+		return cfg
+	}
+}
+
+// WithBatch sets the maximum number of records per export and the maximum
+// time a record waits before export. Defaults to 512 records and 5 seconds,
+// which also replace non-positive values.
+func WithBatch(size int, timeout time.Duration) Option {
+	return func(cfg config) config {
+		cfg.batchSize = size
+		cfg.batchTimeout = timeout
+		return cfg
+	}
+}
+
+// WithMaxQueueSize sets the maximum number of records waiting for export.
+// Drops records beyond the limit, like when the collector is down. Defaults
+// to 2048, which also replaces non-positive values.
+func WithMaxQueueSize(n int) Option {
+	return func(cfg config) config {
+		cfg.maxQueueSize = n
+		return cfg
+	}
+}
+
+// WithRetry sets the maximum number of attempts for each export and the
+// backoff before the first retry, which doubles for each later retry.
+// Defaults to 5 attempts and 100 milliseconds, which also replace
+// non-positive values.
+func WithRetry(maxAttempts int, backoff time.Duration) Option {
+	return func(cfg config) config {
+		cfg.maxAttempts = maxAttempts
+		cfg.retryBackoff = backoff
+		return cfg
+	}
+}
+
+// WithErrorHandler sets the function to call with errors from background
+// exports. Defaults to ignoring errors.
+func WithErrorHandler(f func(error)) Option {
+	return func(cfg config) config {
+		cfg.onError = f
+		return cfg
+	}
+}
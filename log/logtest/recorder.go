@@ -0,0 +1,262 @@
+// Package logtest provides a slog.Handler that records logs to test what code
+// logs.
+package logtest
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+	"github.com/jschaf/observe/internal/slogbind"
+)
+
+// Record is a log record captured by a Recorder.
+type Record struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	// Attrs are the resolved attrs of the record, including attrs bound by
+	// WithAttrs, nested in the groups from WithGroup. Like the slog
+	// handlers, omits empty attrs and empty groups and inlines groups with an
+	// empty key.
+	Attrs []slog.Attr
+}
+
+// Attr returns the value of the attr with the key, where a dotted key like
+// "req.method" names an attr in a group.
+func (r Record) Attr(key string) (slog.Value, bool) {
+	attrs := r.Attrs
+	for {
+		if i := indexKey(attrs, key); i >= 0 {
+			return attrs[i].Value, true
+		}
+		group, rest, ok := strings.Cut(key, ".")
+		if !ok {
+			return slog.Value{}, false
+		}
+		i := indexKey(attrs, group)
+		if i < 0 || attrs[i].Value.Kind() != slog.KindGroup {
+			return slog.Value{}, false
+		}
+		attrs, key = attrs[i].Value.Group(), rest
+	}
+}
+
+func indexKey(attrs []slog.Attr, key string) int {
+	return slices.IndexFunc(attrs, func(a slog.Attr) bool { return a.Key == key })
+}
+
+// String formats the record without the time, like
+// "INFO request method=GET req.status=200", for comparing in tests.
+func (r Record) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(r.Level.String())
+	sb.WriteByte(' ')
+	sb.WriteString(r.Message)
+	appendAttrs(&sb, "", r.Attrs)
+	return sb.String()
+}
+
+func appendAttrs(sb *strings.Builder, prefix string, attrs []slog.Attr) {
+	for _, a := range attrs {
+		if a.Value.Kind() == slog.KindGroup {
+			appendAttrs(sb, prefix+a.Key+".", a.Value.Group())
+			continue
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(prefix)
+		sb.WriteString(a.Key)
+		sb.WriteByte('=')
+		sb.WriteString(a.Value.String())
+	}
+}
+
+// Recorder is a slog.Handler that records all logs in memory. Handlers
+// derived by WithAttrs and WithGroup record into the same records. Safe for
+// concurrent use.
+type Recorder struct {
+	store *store
+	binds slogbind.Bindings
+}
+
+type store struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewRecorder returns an empty recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{store: &store{}}
+}
+
+// Install returns a recorder installed as slog.Default for the rest of the
+// test. Restores the previous default logger, including the output of the
+// log package, when the test completes. Tests using Install must not run in
+// parallel.
+func Install(t testing.TB) *Recorder {
+	t.Helper()
+	prev, w, flags := slog.Default(), log.Writer(), log.Flags()
+	rec := NewRecorder()
+	slog.SetDefault(slog.New(rec))
+	t.Cleanup(func() {
+		slog.SetDefault(prev)
+		// SetDefault redirects the log package to the new handler, but not
+		// back to the original writer for the default handler.
+		log.SetOutput(w)
+		log.SetFlags(flags)
+	})
+	return rec
+}
+
+// AssertRecords reports a test error with a diff if the records, formatted
+// by Record.String, differ from want.
+func AssertRecords(t testing.TB, rec *Recorder, want ...string) {
+	t.Helper()
+	difftest.AssertSame(t, "log records", want, rec.Strings())
+}
+
+// Enabled returns true to record logs at all levels.
+func (r *Recorder) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle records the record with the attrs bound by WithAttrs.
+func (r *Recorder) Handle(_ context.Context, rec slog.Record) error {
+	attrs := make([]slog.Attr, 0, rec.NumAttrs())
+	rec.Attrs(func(a slog.Attr) bool {
+		attrs = appendResolved(attrs, a)
+		return true
+	})
+	attrs = r.binds.Nest(attrs)
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.records = append(r.store.records, Record{
+		Time:    rec.Time,
+		Level:   rec.Level,
+		Message: rec.Message,
+		Attrs:   attrs,
+	})
+	return nil
+}
+
+func (r *Recorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var resolved []slog.Attr
+	for _, a := range attrs {
+		resolved = appendResolved(resolved, a)
+	}
+	if len(resolved) == 0 {
+		return r
+	}
+	return &Recorder{store: r.store, binds: r.binds.WithAttrs(resolved)}
+}
+
+func (r *Recorder) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return r
+	}
+	return &Recorder{store: r.store, binds: r.binds.WithGroup(name)}
+}
+
+// appendResolved appends the attr with its value resolved, omitting empty
+// attrs and empty groups and inlining groups with an empty key.
+func appendResolved(attrs []slog.Attr, a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return append(attrs, a)
+	}
+	var group []slog.Attr
+	for _, ga := range a.Value.Group() {
+		group = appendResolved(group, ga)
+	}
+	switch {
+	case len(group) == 0:
+		return attrs
+	case a.Key == "":
+		return append(attrs, group...)
+	default:
+		return append(attrs, slog.Attr{Key: a.Key, Value: slog.GroupValue(group...)})
+	}
+}
+
+// Records returns a copy of the recorded records, oldest first.
+func (r *Recorder) Records() []Record {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return slices.Clone(r.store.records)
+}
+
+// Reset removes all recorded records.
+func (r *Recorder) Reset() {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.records = nil
+}
+
+// Filter returns the records for which keep returns true, oldest first.
+func (r *Recorder) Filter(keep func(Record) bool) []Record {
+	var out []Record
+	for _, rec := range r.Records() {
+		if keep(rec) {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// ByLevel returns the records at the level or above.
+func (r *Recorder) ByLevel(level slog.Level) []Record {
+	return r.Filter(func(rec Record) bool { return rec.Level >= level })
+}
+
+// ByMessage returns the records with the message.
+func (r *Recorder) ByMessage(msg string) []Record {
+	return r.Filter(func(rec Record) bool { return rec.Message == msg })
+}
+
+// ByAttr returns the records with an attr with the key, as accepted by
+// Record.Attr, and value. Compares values like slog.AnyValue(value), so
+// ByAttr("status", 200) matches slog.Int("status", 200).
+func (r *Recorder) ByAttr(key string, value any) []Record {
+	want := slog.AnyValue(value).Resolve()
+	return r.Filter(func(rec Record) bool {
+		got, ok := rec.Attr(key)
+		return ok && equalValues(got, want)
+	})
+}
+
+// Strings returns the records formatted by Record.String, oldest first.
+func (r *Recorder) Strings() []string {
+	recs := r.Records()
+	out := make([]string, len(recs))
+	for i, rec := range recs {
+		out[i] = rec.String()
+	}
+	return out
+}
+
+// equalValues is like slog.Value.Equal but doesn't panic on incomparable
+// values of KindAny, like slices.
+func equalValues(a, b slog.Value) bool {
+	switch {
+	case a.Kind() != b.Kind():
+		return false
+	case a.Kind() == slog.KindAny:
+		return reflect.DeepEqual(a.Any(), b.Any())
+	case a.Kind() == slog.KindGroup:
+		return slices.EqualFunc(a.Group(), b.Group(), func(x, y slog.Attr) bool {
+			return x.Key == y.Key && equalValues(x.Value, y.Value)
+		})
+	default:
+		return a.Equal(b)
+	}
+}
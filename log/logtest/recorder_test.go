@@ -0,0 +1,149 @@
+package logtest
+
+import (
+	"log"
+	"log/slog"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestRecorder(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *slog.Logger)
+		want []string
+	}{
+		{
+			name: "attrs",
+			log:  func(l *slog.Logger) { l.Info("msg", "a", 1, "b", "x") },
+			want: []string{"INFO msg a=1 b=x"},
+		},
+		{
+			name: "with attrs",
+			log:  func(l *slog.Logger) { l.With("svc", "api").Warn("msg", "a", 1) },
+			want: []string{"WARN msg svc=api a=1"},
+		},
+		{
+			name: "groups",
+			log: func(l *slog.Logger) {
+				l.With("svc", "api").WithGroup("req").With("id", 7).WithGroup("user").Info("msg", "name", "alice")
+			},
+			want: []string{"INFO msg svc=api req.id=7 req.user.name=alice"},
+		},
+		{
+			name: "empty group",
+			log:  func(l *slog.Logger) { l.With("a", 1).WithGroup("g").Info("msg") },
+			want: []string{"INFO msg a=1"},
+		},
+		{
+			name: "inline group",
+			log:  func(l *slog.Logger) { l.Info("msg", slog.Group("", "a", 1), slog.Group("g"), slog.Attr{}) },
+			want: []string{"INFO msg a=1"},
+		},
+		{
+			name: "log valuer",
+			log:  func(l *slog.Logger) { l.With("v", testValuer{}).Info("msg", "w", testValuer{}) },
+			want: []string{"INFO msg v.x=1 w.x=1"},
+		},
+		{
+			name: "levels",
+			log: func(l *slog.Logger) {
+				l.Debug("d")
+				l.Error("e")
+			},
+			want: []string{"DEBUG d", "ERROR e"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := NewRecorder()
+			tt.log(slog.New(rec))
+			AssertRecords(t, rec, tt.want...)
+		})
+	}
+}
+
+type testValuer struct{}
+
+func (testValuer) LogValue() slog.Value { return slog.GroupValue(slog.Int("x", 1)) }
+
+func TestRecorder_Query(t *testing.T) {
+	rec := NewRecorder()
+	l := slog.New(rec)
+	l.Debug("start", "n", 1)
+	l.WithGroup("req").Info("request", "status", 200, "tags", []string{"a"})
+	l.Warn("slow", "status", 200, "ms", 900)
+	l.Error("request", "status", 500)
+
+	tests := []struct {
+		name string
+		got  []Record
+		want []string
+	}{
+		{"level", rec.ByLevel(slog.LevelWarn), []string{"slow", "request"}},
+		{"message", rec.ByMessage("request"), []string{"request", "request"}},
+		{"attr", rec.ByAttr("status", 200), []string{"slow"}},
+		{"group attr", rec.ByAttr("req.status", 200), []string{"request"}},
+		{"slice attr", rec.ByAttr("req.tags", []string{"a"}), []string{"request"}},
+		{"missing attr", rec.ByAttr("status", "200"), nil},
+		{"filter", rec.Filter(func(r Record) bool { return len(r.Attrs) > 1 }), []string{"slow"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msgs []string
+			for _, r := range tt.got {
+				msgs = append(msgs, r.Message)
+			}
+			difftest.AssertSame(t, "messages", tt.want, msgs)
+		})
+	}
+
+	rec.Reset()
+	difftest.AssertSame(t, "after reset", 0, len(rec.Records()))
+}
+
+func TestRecord_Attr(t *testing.T) {
+	r := Record{Attrs: []slog.Attr{
+		slog.String("a", "1"),
+		slog.String("b.c", "2"),
+		slog.Group("g", slog.String("h", "3"), slog.Group("i", slog.String("j", "4"))),
+	}}
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"a", "1", true},
+		{"b.c", "2", true},
+		{"g.h", "3", true},
+		{"g.i.j", "4", true},
+		{"g", "[h=3 i=[j=4]]", true},
+		{"a.b", "", false},
+		{"g.x", "", false},
+		{"x", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := r.Attr(tt.key)
+		difftest.AssertSame(t, tt.key+" ok", tt.wantOK, ok)
+		if ok {
+			difftest.AssertSame(t, tt.key, tt.want, got.String())
+		}
+	}
+}
+
+func TestInstall(t *testing.T) {
+	prev, w := slog.Default(), log.Writer()
+	t.Run("install", func(t *testing.T) {
+		rec := Install(t)
+		slog.Info("slog", "a", 1)
+		log.Print("log")
+		AssertRecords(t, rec, "INFO slog a=1", "INFO log")
+	})
+	if slog.Default() != prev {
+		t.Error("slog.Default not restored after test")
+	}
+	if log.Writer() != w {
+		t.Error("log.Writer not restored after test")
+	}
+}
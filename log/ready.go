@@ -0,0 +1,122 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/jschaf/observe/internal/logkey"
+)
+
+// ReadyKey is the key of the attr marking records logged by Ready. Handlers
+// without special support render it like any bool attr, like "ready":true in
+// JSON. DevHandler renders it as a green "ready" level.
+const ReadyKey = logkey.Ready
+
+//nolint:gochecknoglobals
+var readyAttrs = []slog.Attr{slog.Bool(ReadyKey, true)}
+
+// Ready logs at [slog.LevelInfo] that the program is ready, like after a
+// server starts listening, and marks the unnamed component ready in
+// DefaultReadiness. To wait for Ready in addition to the components of
+// Logger.Ready, register the unnamed component with
+// DefaultReadiness().Register("").
+func Ready(ctx context.Context, msg string, attrs ...slog.Attr) {
+	defaultReadiness.MarkReady("")
+	log(ctx, slog.LevelInfo, msg, readyAttrs, attrs)
+}
+
+// Ready logs at [slog.LevelInfo] that the logger's component is ready, like
+// after a database connects, and marks the component, named by the logger
+// name, ready in DefaultReadiness.
+func (l *Logger) Ready(ctx context.Context, msg string, attrs ...slog.Attr) {
+	defaultReadiness.MarkReady(l.name)
+	l.log(ctx, slog.LevelInfo, msg, slices.Concat(readyAttrs, attrs))
+}
+
+// Readiness tracks whether registered components are ready, like to serve a
+// readiness probe. Components are named by the logger that logs ready, like
+// "db" for Named("db").Ready, or the empty name for the package-level Ready.
+// The zero value is ready, with no registered components.
+type Readiness struct {
+	mu         sync.Mutex
+	registered []string        // in registration order
+	ready      map[string]bool // components that are ready, registered or not
+	// awaitAny makes IsReady report false until a component is marked ready,
+	// registered or not, with the unnamed component pending.
+	awaitAny bool
+}
+
+//nolint:gochecknoglobals
+var defaultReadiness = newDefaultReadiness()
+
+// newDefaultReadiness returns a readiness that awaits any component, so that
+// a probe doesn't report ready before the program logs ready.
+func newDefaultReadiness() *Readiness {
+	return &Readiness{awaitAny: true}
+}
+
+// DefaultReadiness returns the readiness marked by Ready and Logger.Ready.
+// Unlike the zero Readiness, DefaultReadiness is not ready until the program
+// logs ready with Ready or Logger.Ready, and reports the unnamed component
+// pending until then. Afterward, it's ready once all components registered
+// with Register are ready. The unnamed component of the package-level Ready
+// is only required if registered, like with Register("").
+func DefaultReadiness() *Readiness {
+	return defaultReadiness
+}
+
+// Register adds components that must be ready before IsReady reports true.
+// Components that were marked ready before registering stay ready.
+func (r *Readiness) Register(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		if !slices.Contains(r.registered, name) {
+			r.registered = append(r.registered, name)
+		}
+	}
+}
+
+// MarkReady marks the component ready without logging.
+func (r *Readiness) MarkReady(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ready == nil {
+		r.ready = make(map[string]bool)
+	}
+	r.ready[name] = true
+}
+
+// IsReady reports whether all registered components are ready.
+func (r *Readiness) IsReady() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.awaitAny && len(r.ready) == 0 {
+		return false
+	}
+	for _, name := range r.registered {
+		if !r.ready[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// Pending returns the registered components that are not ready, in
+// registration order.
+func (r *Readiness) Pending() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pending []string
+	if r.awaitAny && len(r.ready) == 0 && !slices.Contains(r.registered, "") {
+		pending = append(pending, "")
+	}
+	for _, name := range r.registered {
+		if !r.ready[name] {
+			pending = append(pending, name)
+		}
+	}
+	return pending
+}
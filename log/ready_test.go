@@ -0,0 +1,95 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestReady(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { defaultReadiness = newDefaultReadiness() })
+	ctx := t.Context()
+
+	Ready(ctx, "listening", slog.Int("port", 8080))
+	checkLogOutput(t, buf.String(), `time=`+textTimeRE+` level=INFO msg=listening ready=true port=8080`)
+	buf.Reset()
+
+	Named("db").With(slog.String("shard", "a")).Ready(ctx, "connected")
+	checkLogOutput(t, buf.String(), `time=`+textTimeRE+` level=INFO msg=connected logger=db shard=a ready=true`)
+
+	r := DefaultReadiness()
+	r.Register("", "db", "cache")
+	difftest.AssertSame(t, "pending", []string{"cache"}, r.Pending())
+	difftest.AssertSame(t, "ready", false, r.IsReady())
+}
+
+func TestReady_Disabled(t *testing.T) {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError})))
+	t.Cleanup(func() { defaultReadiness = newDefaultReadiness() })
+	defaultReadiness.Register("db")
+
+	// Marks ready even if the level disables the record.
+	Named("db").Ready(t.Context(), "connected")
+	Ready(t.Context(), "listening")
+	difftest.AssertSame(t, "ready", true, defaultReadiness.IsReady())
+}
+
+func TestDefaultReadiness_Empty(t *testing.T) {
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	t.Cleanup(func() { defaultReadiness = newDefaultReadiness() })
+	r := DefaultReadiness()
+	difftest.AssertSame(t, "pending before ready", []string{""}, r.Pending())
+	difftest.AssertSame(t, "ready before ready", false, r.IsReady())
+
+	// Programs that only use Logger.Ready become ready.
+	Named("db").Ready(t.Context(), "connected")
+	difftest.AssertSame(t, "pending after Logger.Ready", 0, len(r.Pending()))
+	difftest.AssertSame(t, "ready after Logger.Ready", true, r.IsReady())
+}
+
+func TestDefaultReadiness_RegisterUnnamed(t *testing.T) {
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	t.Cleanup(func() { defaultReadiness = newDefaultReadiness() })
+	r := DefaultReadiness()
+	r.Register("")
+
+	// Logger.Ready doesn't mark the registered unnamed component ready.
+	Named("db").Ready(t.Context(), "connected")
+	difftest.AssertSame(t, "pending after Logger.Ready", []string{""}, r.Pending())
+	difftest.AssertSame(t, "ready after Logger.Ready", false, r.IsReady())
+
+	Ready(t.Context(), "listening")
+	difftest.AssertSame(t, "ready after Ready", true, r.IsReady())
+}
+
+func TestReadiness(t *testing.T) {
+	r := &Readiness{}
+	difftest.AssertSame(t, "zero ready", true, r.IsReady())
+
+	r.MarkReady("db")
+	r.Register("db", "http", "db")
+	difftest.AssertSame(t, "pending", []string{"http"}, r.Pending())
+	difftest.AssertSame(t, "not ready", false, r.IsReady())
+
+	r.MarkReady("http")
+	difftest.AssertSame(t, "pending after", 0, len(r.Pending()))
+	difftest.AssertSame(t, "ready", true, r.IsReady())
+}
+
+func TestReadyKey_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, nil))
+	l.LogAttrs(t.Context(), slog.LevelInfo, "m", readyAttrs...)
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got[ReadyKey] != true {
+		t.Errorf("got %s=%v, want true", ReadyKey, got[ReadyKey])
+	}
+}
@@ -0,0 +1,281 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SampleOptions configures a SampleHandler.
+type SampleOptions struct {
+	// First is the number of records with the same level and message to pass
+	// in each interval.
+	First int
+	// Thereafter passes every Thereafter-th record after the first First
+	// records in each interval. If zero, drops all records after First.
+	Thereafter int
+	// Interval is the sampling interval. Intervals start at multiples of
+	// Interval since the Unix epoch. Defaults to one second.
+	Interval time.Duration
+	// SummaryInterval is the minimum time between summary records counting
+	// the dropped records. Defaults to one minute.
+	SummaryInterval time.Duration
+}
+
+// SummaryMessage is the message of the summary record emitted by a
+// SampleHandler.
+const SummaryMessage = "log records dropped by sampling"
+
+// SampleHandler is a slog.Handler that samples records by level and message
+// to protect downstream handlers from floods of records, like from a hot
+// loop logging errors. In each interval, passes the first First records for
+// a key, then every Thereafter-th record.
+//
+// Periodically emits a summary record, at slog.LevelWarn with the message
+// SummaryMessage, counting the dropped records. The first record handled
+// after the summary interval emits the summary and, if no records arrive, a
+// background goroutine emits it within two summary intervals. Call Close to
+// stop the goroutine.
+type SampleHandler struct {
+	next    slog.Handler
+	sampler *sampler
+}
+
+// sampler counts records per key and the records dropped since the last
+// summary. Loggers from WithAttrs and WithGroup share the counters, so a
+// message logged through several of them draws on one budget.
+type sampler struct {
+	opts SampleOptions
+	// root is the next handler given to NewSampleHandler, without attrs or
+	// groups, since the counts in a summary span all derived loggers.
+	root        slog.Handler
+	mu          sync.RWMutex
+	counters    map[sampleKey]*sampleCounter // guarded by mu
+	nextSummary atomic.Int64                 // unix nanos of the next summary, or 0 if unset
+	dropped     [4]atomic.Uint64
+
+	stop     chan struct{} // closed by Close to stop the summary goroutine
+	stopOnce sync.Once
+	done     chan struct{} // closed when the summary goroutine exits
+}
+
+// sampleKey is the level and message that a sampleCounter counts.
+type sampleKey struct {
+	level slog.Level
+	msg   string
+}
+
+// sampleCounter counts the records for a key in an interval. The state packs
+// the interval number into the high 32 bits and the count into the low 32
+// bits, so that a single CompareAndSwap resets and counts.
+type sampleCounter struct {
+	state atomic.Uint64
+}
+
+// NewSampleHandler returns a handler that samples records before passing
+// them to next, and starts a goroutine that emits summaries when no records
+// arrive. If opts is nil, passes the first 100 records per second for each
+// key, then every 100th record.
+func NewSampleHandler(next slog.Handler, opts *SampleOptions) *SampleHandler {
+	if opts == nil {
+		opts = &SampleOptions{First: 100, Thereafter: 100}
+	}
+	s := &sampler{
+		opts:     *opts,
+		root:     next,
+		counters: make(map[sampleKey]*sampleCounter),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if s.opts.Interval <= 0 {
+		s.opts.Interval = time.Second
+	}
+	if s.opts.SummaryInterval <= 0 {
+		s.opts.SummaryInterval = time.Minute
+	}
+	go s.run()
+	return &SampleHandler{next: next, sampler: s}
+}
+
+func (h *SampleHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h *SampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	s := h.sampler
+	now := t.UnixNano()
+	if !s.sample(now, r.Level, r.Message) {
+		s.dropped[levelIndex(r.Level)].Add(1)
+		s.maybeSummarize(ctx, now)
+		return nil
+	}
+	s.maybeSummarize(ctx, now)
+	return h.next.Handle(ctx, r) //nolint:wrapcheck // transparent wrapper
+}
+
+func (h *SampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SampleHandler{next: h.next.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h *SampleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SampleHandler{next: h.next.WithGroup(name), sampler: h.sampler}
+}
+
+// Close stops the summary goroutine and emits a summary of the records
+// dropped since the last summary, if any. Closing a handler closes the
+// handlers derived from it with WithAttrs and WithGroup, and vice versa.
+func (h *SampleHandler) Close() {
+	s := h.sampler
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+	s.summarize(context.Background(), time.Now().UnixNano())
+}
+
+// run emits the summary on each summary interval, if due, until Close, so
+// that summaries appear even if no records arrive.
+func (s *sampler) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.SummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.maybeSummarize(context.Background(), now.UnixNano())
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// sample reports whether to pass a record with the level and message at
+// now, in unix nanos.
+func (s *sampler) sample(now int64, level slog.Level, msg string) bool {
+	n := s.counter(level, msg).inc(s.interval(now))
+	first := uint64(s.opts.First) //nolint:gosec // negative is nonsensical
+	if uint64(n) <= first {
+		return true
+	}
+	every := uint64(s.opts.Thereafter) //nolint:gosec // negative is nonsensical
+	return every > 0 && (uint64(n)-first)%every == 0
+}
+
+// interval returns the number of the sampling interval containing now, in
+// unix nanos, truncated to 32 bits.
+func (s *sampler) interval(now int64) uint32 {
+	return uint32(now / int64(s.opts.Interval)) //nolint:gosec // only compared
+}
+
+// counter returns the counter for the level and message, creating it if
+// needed.
+func (s *sampler) counter(level slog.Level, msg string) *sampleCounter {
+	k := sampleKey{level: level, msg: msg}
+	s.mu.RLock()
+	c := s.counters[k]
+	s.mu.RUnlock()
+	if c != nil {
+		return c
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c = s.counters[k]; c == nil {
+		c = &sampleCounter{}
+		s.counters[k] = c
+	}
+	return c
+}
+
+// prune removes the counters not used in the interval containing now, so
+// that messages with varying text don't grow the counters without bound.
+// A record racing with prune may count on a removed counter, passing an
+// extra record.
+func (s *sampler) prune(now int64) {
+	cur := s.interval(now)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, c := range s.counters {
+		if uint32(c.state.Load()>>32) != cur {
+			delete(s.counters, k)
+		}
+	}
+}
+
+// inc increments the count, resetting it to 1 if the interval changed.
+// Returns the count, which saturates at math.MaxUint32.
+func (c *sampleCounter) inc(interval uint32) uint32 {
+	for {
+		old := c.state.Load()
+		n := uint32(old) + 1 //nolint:gosec // the low bits hold the count
+		switch {
+		case uint32(old>>32) != interval:
+			n = 1
+		case n == 0:
+			n = math.MaxUint32
+		}
+		if c.state.CompareAndSwap(old, uint64(interval)<<32|uint64(n)) {
+			return n
+		}
+	}
+}
+
+// maybeSummarize emits a summary of the dropped records if the summary
+// interval passed. Only one goroutine emits each summary.
+func (s *sampler) maybeSummarize(ctx context.Context, now int64) {
+	next := s.nextSummary.Load()
+	if next == 0 {
+		s.nextSummary.CompareAndSwap(0, now+int64(s.opts.SummaryInterval))
+		return
+	}
+	if now < next || !s.nextSummary.CompareAndSwap(next, now+int64(s.opts.SummaryInterval)) {
+		return
+	}
+	s.prune(now)
+	s.summarize(ctx, now)
+}
+
+// summarize emits a summary of the records dropped since the last summary,
+// if any.
+func (s *sampler) summarize(ctx context.Context, now int64) {
+	var total uint64
+	byLevel := make([]any, 0, len(s.dropped))
+	for i := range s.dropped {
+		n := s.dropped[i].Swap(0)
+		if n == 0 {
+			continue
+		}
+		total += n
+		byLevel = append(byLevel, slog.Uint64(levelNames[i], n))
+	}
+	if total == 0 || !s.root.Enabled(ctx, slog.LevelWarn) {
+		return
+	}
+	r := slog.NewRecord(time.Unix(0, now), slog.LevelWarn, SummaryMessage, 0)
+	r.AddAttrs(slog.Uint64("dropped", total), slog.Group("dropped_by_level", byLevel...))
+	_ = s.root.Handle(ctx, r)
+}
+
+//nolint:gochecknoglobals
+var levelNames = [4]string{"debug", "info", "warn", "error"}
+
+// levelIndex returns the index into levelNames for the level.
+func levelIndex(l slog.Level) int {
+	switch {
+	case l < slog.LevelInfo:
+		return 0
+	case l < slog.LevelWarn:
+		return 1
+	case l < slog.LevelError:
+		return 2
+	default:
+		return 3
+	}
+}
@@ -0,0 +1,204 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestSampleHandler(t *testing.T) {
+	rec := &recordHandler{}
+	h := NewSampleHandler(rec, &SampleOptions{First: 2, Thereafter: 3, Interval: time.Second, SummaryInterval: time.Hour})
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	handle := func(t time.Time, level slog.Level, msg string, i int) {
+		r := slog.NewRecord(t, level, msg, 0)
+		r.AddAttrs(slog.Int("i", i))
+		_ = h.Handle(context.Background(), r)
+	}
+	for i := range 10 {
+		handle(start, slog.LevelError, "hot", i)
+	}
+	handle(start, slog.LevelError, "other", 0)
+	handle(start, slog.LevelWarn, "hot", 0)
+	handle(start.Add(time.Second), slog.LevelError, "hot", 10)
+
+	difftest.AssertSame(t, "passed records", []string{
+		"ERROR hot i=0",
+		"ERROR hot i=1",
+		"ERROR hot i=4",
+		"ERROR hot i=7",
+		"ERROR other i=0",
+		"WARN hot i=0",
+		"ERROR hot i=10", // new interval
+	}, rec.strings())
+}
+
+func TestSampleHandler_DistinctKeys(t *testing.T) {
+	rec := &recordHandler{}
+	h := NewSampleHandler(rec, &SampleOptions{First: 1, Interval: time.Second, SummaryInterval: time.Hour})
+	now := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	// The keys hashed to the same counter when counters were hash buckets.
+	_ = h.Handle(context.Background(), slog.NewRecord(now, slog.LevelDebug, "debug 5354", 0))
+	_ = h.Handle(context.Background(), slog.NewRecord(now, slog.LevelError, "db down", 0))
+	difftest.AssertSame(t, "passed records", []string{"DEBUG debug 5354", "ERROR db down"}, rec.strings())
+}
+
+func TestSampleHandler_DropAll(t *testing.T) {
+	rec := &recordHandler{}
+	h := NewSampleHandler(rec, &SampleOptions{First: 1})
+	for i := range 5 {
+		r := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+		r.AddAttrs(slog.Int("i", i))
+		_ = h.WithGroup("g").WithAttrs(nil).Handle(context.Background(), r)
+	}
+	difftest.AssertSame(t, "passed records", []string{"INFO msg i=0"}, rec.strings())
+}
+
+func TestSampleHandler_Summary(t *testing.T) {
+	rec := &recordHandler{}
+	h := NewSampleHandler(rec, &SampleOptions{First: 1, Interval: time.Second, SummaryInterval: time.Minute})
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	handle := func(t time.Time, level slog.Level, msg string) {
+		_ = h.Handle(context.Background(), slog.NewRecord(t, level, msg, 0))
+	}
+
+	for range 3 {
+		handle(start, slog.LevelError, "err")
+		handle(start, slog.LevelDebug, "dbg")
+	}
+	handle(start.Add(30*time.Second), slog.LevelError, "err")
+	handle(start.Add(30*time.Second), slog.LevelError, "err") // dropped
+	handle(start.Add(time.Minute), slog.LevelInfo, "info")
+	handle(start.Add(90*time.Second), slog.LevelInfo, "info") // nothing dropped since
+	handle(start.Add(3*time.Minute), slog.LevelInfo, "info")
+
+	difftest.AssertSame(t, "records", []string{
+		"ERROR err",
+		"DEBUG dbg",
+		"ERROR err",
+		"WARN " + SummaryMessage + " dropped=5 dropped_by_level.debug=2 dropped_by_level.error=3",
+		"INFO info",
+		"INFO info",
+		"INFO info",
+	}, rec.strings())
+}
+
+func TestSampleHandler_SummaryIdle(t *testing.T) {
+	rec := &recordHandler{}
+	h := NewSampleHandler(rec, &SampleOptions{First: 1, SummaryInterval: 10 * time.Millisecond})
+	defer h.Close()
+	for range 3 {
+		_ = h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelError, "err", 0))
+	}
+
+	// No records arrive after the drops, so the goroutine emits the summary.
+	want := []string{
+		"ERROR err",
+		"WARN " + SummaryMessage + " dropped=2 dropped_by_level.error=2",
+	}
+	waitFor(t, func() bool { return len(rec.strings()) == len(want) })
+	difftest.AssertSame(t, "records", want, rec.strings())
+}
+
+func TestSampleHandler_Close(t *testing.T) {
+	rec := &recordHandler{}
+	h := NewSampleHandler(rec, &SampleOptions{First: 1, SummaryInterval: time.Hour})
+	for range 3 {
+		_ = h.WithGroup("g").Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "info", 0))
+	}
+	h.Close()
+	h.Close() // no new drops, so no summary
+
+	difftest.AssertSame(t, "records", []string{
+		"INFO info",
+		"WARN " + SummaryMessage + " dropped=2 dropped_by_level.info=2",
+	}, rec.strings())
+}
+
+func TestSampleHandler_Concurrent(t *testing.T) {
+	rec := &recordHandler{}
+	h := NewSampleHandler(rec, &SampleOptions{First: 10, Thereafter: 100, Interval: time.Hour})
+	now := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	const goroutines, perGoroutine = 8, 1000
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perGoroutine {
+				_ = h.Handle(context.Background(), slog.NewRecord(now, slog.LevelError, "hot", 0))
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := len(rec.strings())
+	want := 10 + (goroutines*perGoroutine-10)/100
+	if got != want {
+		t.Errorf("got %d passed records, want %d", got, want)
+	}
+}
+
+// recordHandler is a slog.Handler that records the records it handles.
+type recordHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r.Clone())
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordHandler) WithGroup(string) slog.Handler { return h }
+
+// strings returns the records formatted as "LEVEL msg key=value", flattening
+// groups into dotted keys.
+func (h *recordHandler) strings() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	strs := make([]string, 0, len(h.records))
+	for _, r := range h.records {
+		s := r.Level.String() + " " + r.Message
+		var appendAttr func(prefix string, a slog.Attr)
+		appendAttr = func(prefix string, a slog.Attr) {
+			if a.Value.Kind() == slog.KindGroup {
+				for _, ga := range a.Value.Group() {
+					appendAttr(prefix+a.Key+".", ga)
+				}
+				return
+			}
+			s += fmt.Sprintf(" %s%s=%v", prefix, a.Key, a.Value)
+		}
+		r.Attrs(func(a slog.Attr) bool {
+			appendAttr("", a)
+			return true
+		})
+		strs = append(strs, s)
+	}
+	return strs
+}
+
+func BenchmarkSampleHandler_Handle(b *testing.B) {
+	ctx := b.Context()
+	h := NewSampleHandler(slog.DiscardHandler, &SampleOptions{First: 1, Interval: time.Hour, SummaryInterval: time.Hour})
+	r := slog.NewRecord(time.Now(), slog.LevelError, "hot loop error", 0)
+	r.AddAttrs(slog.Int("a", 1))
+	b.ReportAllocs()
+
+	for b.Loop() {
+		_ = h.Handle(ctx, r)
+	}
+}
@@ -0,0 +1,214 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jschaf/observe/internal/logkey"
+	"github.com/jschaf/observe/log/logerr"
+	"github.com/jschaf/observe/trace"
+)
+
+// SpanEventHandler is a slog.Handler that mirrors records onto the active
+// span in the context as span events, then passes records to the next
+// handler. Records at slog.LevelError with an error attribute also set the
+// span status to Error and add an exception event for each error.
+type SpanEventHandler struct {
+	next   slog.Handler
+	level  slog.Leveler
+	attrs  []trace.Attr // attrs bound with WithAttrs, flattened
+	errs   []error      // errors bound with WithAttrs
+	prefix string       // dotted group prefix from WithGroup, like "a.b."
+}
+
+// Keys for the attributes of exception span events. Follows the OpenTelemetry
+// semantic conventions, adding the LogValue of errors that implement
+// slog.LogValuer and the wrapped errors.
+const (
+	exceptionEvent         = "exception"
+	exceptionTypeKey       = "exception.type"
+	exceptionMessageKey    = "exception.message"
+	exceptionStacktraceKey = "exception.stacktrace"
+	exceptionValuePrefix   = "exception.value."
+	exceptionCausesKey     = "exception.causes"
+)
+
+// NewSpanEventHandler returns a handler that records span events for records
+// at or above level and passes all records to next. If level is nil, records
+// span events for slog.LevelWarn and above.
+func NewSpanEventHandler(next slog.Handler, level slog.Leveler) *SpanEventHandler {
+	if level == nil {
+		level = slog.LevelWarn
+	}
+	return &SpanEventHandler{next: next, level: level}
+}
+
+func (h *SpanEventHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.next.Enabled(ctx, l) {
+		return true
+	}
+	return l >= h.level.Level() && trace.SpanFromContext(ctx).IsRecording()
+}
+
+func (h *SpanEventHandler) Handle(ctx context.Context, r slog.Record) error {
+	if span := trace.SpanFromContext(ctx); r.Level >= h.level.Level() && span.IsRecording() {
+		h.addEvent(span, r)
+	}
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r) //nolint:wrapcheck // transparent wrapper
+}
+
+func (h *SpanEventHandler) addEvent(span *trace.Span, r slog.Record) {
+	attrs := make([]trace.Attr, 0, len(h.attrs)+r.NumAttrs()+1)
+	attrs = append(attrs, trace.String(slog.LevelKey, r.Level.String()))
+	attrs = append(attrs, h.attrs...)
+	errs := h.errs
+	r.Attrs(func(attr slog.Attr) bool {
+		if logkey.IsTrace(attr.Key) {
+			return true // redundant on the span
+		}
+		attrs, errs = appendTraceAttrs(attrs, errs, h.prefix, attr)
+		return true
+	})
+	span.AddEvent(r.Message, attrs...)
+	if r.Level >= slog.LevelError && len(errs) > 0 {
+		for _, err := range errs {
+			span.AddEvent(exceptionEvent, exceptionAttrs(err)...)
+		}
+		span.SetStatus(trace.StatusError, errs[len(errs)-1].Error())
+	}
+}
+
+// exceptionAttrs returns the attrs of an exception span event for the error.
+func exceptionAttrs(err error) []trace.Attr {
+	d := logerr.Describe(err)
+	attrs := []trace.Attr{
+		trace.String(exceptionTypeKey, d.Type),
+		trace.String(exceptionMessageKey, d.Message),
+	}
+	if stack := logerr.Stack(err); len(stack) > 0 {
+		attrs = append(attrs, trace.String(exceptionStacktraceKey, logerr.FormatStack(stack)))
+	}
+	if !d.Value.Equal(slog.Value{}) {
+		if d.Value.Kind() == slog.KindGroup {
+			for _, a := range d.Value.Group() {
+				attrs, _ = appendTraceAttrs(attrs, nil, exceptionValuePrefix, a)
+			}
+		} else {
+			attrs = append(attrs, traceAttr(strings.TrimSuffix(exceptionValuePrefix, "."), d.Value))
+		}
+	}
+	if causes := appendCauses(nil, d.Causes); len(causes) > 0 {
+		attrs = append(attrs, trace.Strings(exceptionCausesKey, causes))
+	}
+	return attrs
+}
+
+// appendCauses appends the causes and their causes, depth first, as
+// "message (type)".
+func appendCauses(dst []string, causes []logerr.Detail) []string {
+	for _, c := range causes {
+		dst = append(dst, c.Message+" ("+c.Type+")")
+		dst = appendCauses(dst, c.Causes)
+	}
+	return dst
+}
+
+func (h *SpanEventHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.next = h.next.WithAttrs(attrs)
+	h2.attrs = slices.Clip(h.attrs) // force a copy on append
+	h2.errs = slices.Clip(h.errs)
+	for _, attr := range attrs {
+		h2.attrs, h2.errs = appendTraceAttrs(h2.attrs, h2.errs, h.prefix, attr)
+	}
+	return &h2
+}
+
+func (h *SpanEventHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.next = h.next.WithGroup(name)
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// appendTraceAttrs converts a slog.Attr into trace attributes, flattening
+// groups into dotted keys. Appends error values to errs.
+func appendTraceAttrs(dst []trace.Attr, errs []error, prefix string, attr slog.Attr) ([]trace.Attr, []error) {
+	v := logerr.Resolve(attr.Value)
+	if v.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = prefix + attr.Key + "." // empty keys inline the group
+		}
+		for _, ga := range v.Group() {
+			dst, errs = appendTraceAttrs(dst, errs, groupPrefix, ga)
+		}
+		return dst, errs
+	}
+	if attr.Key == "" {
+		return dst, errs // slog ignores empty attrs
+	}
+	if err := logerr.ErrorOf(v); err != nil {
+		errs = append(errs, err)
+	}
+	return append(dst, traceAttr(prefix+attr.Key, v)), errs
+}
+
+// traceAttr converts a resolved slog.Value into a trace.Attr.
+func traceAttr(key string, v slog.Value) trace.Attr {
+	switch v.Kind() {
+	case slog.KindString:
+		return trace.String(key, v.String())
+	case slog.KindInt64:
+		return trace.Int64(key, v.Int64())
+	case slog.KindUint64:
+		u := v.Uint64()
+		if u > math.MaxInt64 {
+			return trace.String(key, v.String())
+		}
+		return trace.Int64(key, int64(u))
+	case slog.KindFloat64:
+		return trace.Float64(key, v.Float64())
+	case slog.KindBool:
+		return trace.Bool(key, v.Bool())
+	case slog.KindDuration:
+		return trace.String(key, v.Duration().String())
+	case slog.KindTime:
+		return trace.String(key, v.Time().Format(time.RFC3339Nano))
+	case slog.KindAny:
+		switch a := v.Any().(type) {
+		case error:
+			return trace.String(key, a.Error())
+		case []string:
+			return trace.Strings(key, a)
+		case []int:
+			return trace.Ints(key, a)
+		case []int64:
+			return trace.Int64s(key, a)
+		case []float64:
+			return trace.Float64s(key, a)
+		case []bool:
+			return trace.Bools(key, a)
+		default:
+			return trace.String(key, fmt.Sprint(a))
+		}
+	case slog.KindGroup, slog.KindLogValuer:
+		if err := logerr.ErrorOf(v); err != nil {
+			return trace.String(key, err.Error())
+		}
+		return trace.String(key, v.String()) // unreachable for resolved, non-group values
+	default:
+		return trace.String(key, v.String())
+	}
+}
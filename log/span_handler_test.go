@@ -0,0 +1,193 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+	"github.com/jschaf/observe/log/logerr"
+	"github.com/jschaf/observe/trace"
+)
+
+func TestSpanEventHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewSpanEventHandler(slog.NewTextHandler(&buf, nil), nil)
+	slog.SetDefault(slog.New(h))
+
+	tr := &trace.Tracer{}
+	ctx, span := tr.Start(t.Context(), "test-span")
+
+	Info(ctx, "not mirrored", slog.Int("a", 1))
+	Warn(ctx, "slow request",
+		slog.Duration("dur", 3*time.Second),
+		slog.Group("req", slog.String("method", "GET"), slog.Group("url", slog.String("path", "/foo"))),
+		slog.Any("ids", []int64{1, 2}),
+		slog.Uint64("n", 7),
+	)
+	span.End()
+
+	events := span.Events()
+	if len(events) != 1 {
+		t.Fatalf("want 1 span event, got %d", len(events))
+	}
+	difftest.AssertSame(t, "event name mismatch", "slow request", events[0].Name)
+	difftest.AssertSame(t, "event attrs mismatch", []string{
+		"level=WARN",
+		"dur=3s",
+		"req.method=GET",
+		"req.url.path=/foo",
+		"ids=[1,2]",
+		"n=7",
+	}, attrStrings(events[0].Attrs))
+	difftest.AssertSame(t, "span status mismatch", uint8(trace.StatusUnset), uint8(span.Status().Code))
+
+	// Records still reach the next handler.
+	if got := strings.Count(buf.String(), "\n"); got != 2 {
+		t.Errorf("want 2 records in next handler, got %d:\n%s", got, buf.String())
+	}
+}
+
+func TestSpanEventHandler_ErrorStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      slog.Level
+		attrs      []slog.Attr
+		wantStatus trace.Status
+	}{
+		{
+			name:       "error with error attr",
+			level:      slog.LevelError,
+			attrs:      []slog.Attr{slog.Any("err", errors.New("boom"))},
+			wantStatus: trace.Status{Code: trace.StatusError, Description: "boom"},
+		},
+		{
+			name:  "error without error attr",
+			level: slog.LevelError,
+			attrs: []slog.Attr{slog.String("err", "boom")},
+		},
+		{
+			name:  "warn with error attr",
+			level: slog.LevelWarn,
+			attrs: []slog.Attr{slog.Any("err", errors.New("boom"))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slog.SetDefault(slog.New(NewSpanEventHandler(slog.DiscardHandler, nil)))
+			tr := &trace.Tracer{}
+			ctx, span := tr.Start(t.Context(), "test-span")
+			Log(ctx, tt.level, "failed", tt.attrs...)
+			span.End()
+
+			difftest.AssertSame(t, "span status code mismatch", uint8(tt.wantStatus.Code), uint8(span.Status().Code))
+			difftest.AssertSame(t, "span status description mismatch", tt.wantStatus.Description, span.Status().Description)
+		})
+	}
+}
+
+func TestSpanEventHandler_WithAttrs(t *testing.T) {
+	h := NewSpanEventHandler(slog.DiscardHandler, slog.LevelInfo)
+	l := slog.New(h).With(slog.String("svc", "api")).WithGroup("db").With(slog.Any("err", errors.New("conn reset")))
+
+	tr := &trace.Tracer{}
+	ctx, span := tr.Start(t.Context(), "test-span")
+	l.ErrorContext(ctx, "query failed", slog.Int("attempt", 2))
+	span.End()
+
+	events := span.Events()
+	if len(events) != 2 {
+		t.Fatalf("want 2 span events, got %d", len(events))
+	}
+	difftest.AssertSame(t, "event attrs mismatch", []string{
+		"level=ERROR",
+		"svc=api",
+		"db.err=conn reset",
+		"db.attempt=2",
+	}, attrStrings(events[0].Attrs))
+	difftest.AssertSame(t, "exception event name mismatch", "exception", events[1].Name)
+	difftest.AssertSame(t, "span status description mismatch", "conn reset", span.Status().Description)
+}
+
+func TestSpanEventHandler_Exception(t *testing.T) {
+	slog.SetDefault(slog.New(NewSpanEventHandler(slog.DiscardHandler, nil)))
+	tr := &trace.Tracer{}
+	ctx, span := tr.Start(t.Context(), "test-span")
+	Warn(ctx, "retrying", slog.Any("err", io.EOF))
+	Error(ctx, "failed",
+		slog.Any("err", fmt.Errorf("query: %w", &codeError{code: 7})),
+		slog.Group("db", slog.Any("close_err", logerr.New("conn reset"))),
+	)
+	span.End()
+
+	events := span.Events()
+	names := make([]string, len(events))
+	for i, ev := range events {
+		names[i] = ev.Name
+	}
+	difftest.AssertSame(t, "event names mismatch", []string{"retrying", "failed", "exception", "exception"}, names)
+	difftest.AssertSame(t, "wrapped exception attrs mismatch", []string{
+		"exception.type=*fmt.wrapError",
+		"exception.message=query: code 7",
+		`exception.causes=["code 7 (*log.codeError)"]`,
+	}, attrStrings(events[2].Attrs))
+	difftest.AssertSame(t, "event err attr mismatch", "err=query: code 7", events[1].Attrs[1].String())
+
+	stackAttrs := attrStrings(events[3].Attrs)
+	difftest.AssertSame(t, "stack exception attrs mismatch", []string{
+		"exception.type=*errors.errorString",
+		"exception.message=conn reset",
+	}, stackAttrs[:2])
+	wantStack := "exception.stacktrace=github.com/jschaf/observe/log.TestSpanEventHandler_Exception\n\t"
+	if len(stackAttrs) != 3 || !strings.HasPrefix(stackAttrs[2], wantStack) {
+		t.Errorf("got attrs %q, want stacktrace with prefix %q", stackAttrs, wantStack)
+	}
+	difftest.AssertSame(t, "span status description mismatch", "conn reset", span.Status().Description)
+}
+
+func TestExceptionAttrs_LogValuer(t *testing.T) {
+	difftest.AssertSame(t, "attrs", []string{
+		"exception.type=*log.codeError",
+		"exception.message=code 7",
+		"exception.value.code=7",
+	}, attrStrings(exceptionAttrs(&codeError{code: 7})))
+}
+
+type codeError struct{ code int }
+
+func (e *codeError) Error() string { return "code " + strconv.Itoa(e.code) }
+
+func (e *codeError) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("code", e.code))
+}
+
+func TestSpanEventHandler_Enabled(t *testing.T) {
+	h := NewSpanEventHandler(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}), nil)
+	tr := &trace.Tracer{}
+	ctx, span := tr.Start(t.Context(), "test-span")
+	defer span.End()
+
+	if !h.Enabled(ctx, slog.LevelWarn) {
+		t.Errorf("want enabled for warn with a recording span")
+	}
+	if h.Enabled(t.Context(), slog.LevelWarn) {
+		t.Errorf("want disabled for warn without a span")
+	}
+	if h.Enabled(ctx, slog.LevelInfo) {
+		t.Errorf("want disabled for info")
+	}
+}
+
+func attrStrings(attrs []trace.Attr) []string {
+	strs := make([]string, len(attrs))
+	for i, attr := range attrs {
+		strs[i] = attr.String()
+	}
+	return strs
+}
@@ -0,0 +1,302 @@
+package log
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/jschaf/observe/trace"
+)
+
+// TailOptions configures a TailHandler.
+type TailOptions struct {
+	// Threshold is the level below which records are buffered. Defaults to
+	// slog.LevelInfo, buffering debug records.
+	Threshold slog.Leveler
+	// MaxRecords is the maximum number of buffered records per trace. Drops
+	// the oldest records beyond the limit. Defaults to 256.
+	MaxRecords int
+	// MaxTraces is the maximum number of traces with buffered records. Drops
+	// the buffer of the oldest trace beyond the limit. Defaults to 1024.
+	MaxTraces int
+}
+
+// DroppedBufferMessage is the message of the record emitted before flushing
+// a trace buffer that dropped records because it was full.
+const DroppedBufferMessage = "log records dropped from full tail buffer"
+
+// TailHandler is a slog.Handler that buffers detailed records for each trace
+// and only passes them to the next handler if the request fails. Gives the
+// detail of debug logging for failing requests without the volume for
+// successful requests.
+//
+// TailHandler is also a trace.SpanProcessor and must be registered with the
+// tracer, like with trace.NewTracer(h), to learn when traces start and end.
+// When a local root span starts, the handler buffers records below the
+// threshold logged with a context containing a span in the trace. The
+// handler flushes buffered records in order to the next handler when a
+// record at slog.LevelError is logged for the trace or when the root span
+// ends with an Error status. Afterward, records for the trace pass through
+// unbuffered. If the root span ends without an error, the handler discards
+// the buffered records.
+//
+// Records without a trace, or for traces started before registering the
+// handler, pass through to the next handler as usual.
+type TailHandler struct {
+	next    slog.Handler
+	buffers *tailBuffers
+}
+
+// tailBuffers holds the record buffers by trace ID, evicting the oldest
+// trace first. The tracer calls OnStart and OnEnd on the registered handler,
+// not the loggers from WithAttrs and WithGroup, so they all use the same
+// buffers.
+type tailBuffers struct {
+	threshold  slog.Leveler
+	maxRecords int
+	maxTraces  int
+	// root is the next handler given to NewTailHandler. It logs the
+	// DroppedBufferMessage warning ahead of a flush, while the buffered
+	// records go to the handlers saved in each tailRecord.
+	root slog.Handler
+
+	mu     sync.Mutex
+	traces map[trace.TraceID]*list.Element // values are *tailBuffer
+	order  list.List                       // oldest trace first
+}
+
+// tailBuffer is the ring of buffered records for a trace.
+type tailBuffer struct {
+	traceID trace.TraceID
+	rootID  trace.SpanID
+	// flushed is non-nil once the trace is flushed, and closed once the
+	// buffered records are handled, so later records can wait for them.
+	flushed chan struct{}
+	records []tailRecord // ring buffer
+	head    int          // index of the oldest record
+	count   int
+	dropped int
+}
+
+// tailRecord is a buffered record with the handler to pass it to, which
+// includes the attrs and groups from WithAttrs and WithGroup.
+type tailRecord struct {
+	next slog.Handler
+	r    slog.Record
+}
+
+// NewTailHandler returns a handler that buffers records per trace before
+// passing them to next. Register the handler as a span processor.
+func NewTailHandler(next slog.Handler, opts *TailOptions) *TailHandler {
+	if opts == nil {
+		opts = &TailOptions{}
+	}
+	b := &tailBuffers{
+		threshold:  opts.Threshold,
+		maxRecords: opts.MaxRecords,
+		maxTraces:  opts.MaxTraces,
+		root:       next,
+		traces:     make(map[trace.TraceID]*list.Element),
+	}
+	if b.threshold == nil {
+		b.threshold = slog.LevelInfo
+	}
+	if b.maxRecords <= 0 {
+		b.maxRecords = 256
+	}
+	if b.maxTraces <= 0 {
+		b.maxTraces = 1024
+	}
+	return &TailHandler{next: next, buffers: b}
+}
+
+func (h *TailHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.next.Enabled(ctx, l) {
+		return true
+	}
+	return l < h.buffers.threshold.Level() && trace.SpanFromContext(ctx).Context().IsValid()
+}
+
+func (h *TailHandler) Handle(ctx context.Context, r slog.Record) error {
+	traceID := trace.SpanFromContext(ctx).Context().TraceID
+	if !traceID.IsValid() {
+		return h.handleNext(ctx, r)
+	}
+	if r.Level < h.buffers.threshold.Level() {
+		buffered, flushed := h.buffers.add(traceID, h.next, r)
+		switch {
+		case buffered:
+			return nil
+		case flushed != nil:
+			// Keep the order after the buffered records.
+			<-flushed
+			return h.next.Handle(ctx, r) //nolint:wrapcheck // transparent wrapper
+		default:
+			return h.handleNext(ctx, r)
+		}
+	}
+	if r.Level >= slog.LevelError {
+		h.buffers.flush(ctx, traceID, trace.SpanID{})
+	} else if flushed := h.buffers.flushed(traceID); flushed != nil {
+		// Another goroutine may be flushing, so keep the order after the
+		// buffered records.
+		<-flushed
+	}
+	return h.handleNext(ctx, r)
+}
+
+func (h *TailHandler) handleNext(ctx context.Context, r slog.Record) error {
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r) //nolint:wrapcheck // transparent wrapper
+}
+
+func (h *TailHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &TailHandler{next: h.next.WithAttrs(attrs), buffers: h.buffers}
+}
+
+func (h *TailHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &TailHandler{next: h.next.WithGroup(name), buffers: h.buffers}
+}
+
+// OnStart starts buffering records for the trace of a local root span.
+func (h *TailHandler) OnStart(_ context.Context, span *trace.Span) {
+	if parent := span.Parent(); parent.IsValid() && !parent.Remote {
+		return // not a local root
+	}
+	h.buffers.start(span.Context())
+}
+
+// OnEnd flushes the buffered records for the trace of a local root span if
+// the span ended with an Error status, and discards them otherwise.
+func (h *TailHandler) OnEnd(span *trace.Span) {
+	sc := span.Context()
+	if span.Status().Code == trace.StatusError {
+		h.buffers.flush(context.Background(), sc.TraceID, sc.SpanID)
+	}
+	h.buffers.remove(sc.TraceID, sc.SpanID)
+}
+
+func (b *tailBuffers) start(sc trace.Context) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.traces[sc.TraceID]; ok {
+		return // another local root of the same trace
+	}
+	for b.order.Len() >= b.maxTraces {
+		oldest, _ := b.order.Remove(b.order.Front()).(*tailBuffer)
+		delete(b.traces, oldest.traceID)
+	}
+	buf := &tailBuffer{traceID: sc.TraceID, rootID: sc.SpanID}
+	b.traces[sc.TraceID] = b.order.PushBack(buf)
+}
+
+// add buffers the record for the trace. Returns whether the record was
+// buffered, and, if not and the trace was flushed, a channel closed once the
+// buffered records are handled.
+func (b *tailBuffers) add(traceID trace.TraceID, next slog.Handler, r slog.Record) (buffered bool, flushed <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	elem, ok := b.traces[traceID]
+	if !ok {
+		return false, nil
+	}
+	buf, _ := elem.Value.(*tailBuffer)
+	if buf.flushed != nil {
+		return false, buf.flushed
+	}
+	if buf.records == nil {
+		buf.records = make([]tailRecord, b.maxRecords)
+	}
+	rec := tailRecord{next: next, r: r.Clone()}
+	if buf.count == len(buf.records) {
+		buf.records[buf.head] = rec // overwrite the oldest
+		buf.head = (buf.head + 1) % len(buf.records)
+		buf.dropped++
+		return true, nil
+	}
+	buf.records[(buf.head+buf.count)%len(buf.records)] = rec
+	buf.count++
+	return true, nil
+}
+
+// flushed returns the channel closed once the buffered records for the
+// trace are handled, or nil if the trace isn't flushed.
+func (b *tailBuffers) flushed(traceID trace.TraceID) <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	elem, ok := b.traces[traceID]
+	if !ok {
+		return nil
+	}
+	buf, _ := elem.Value.(*tailBuffer)
+	return buf.flushed
+}
+
+// flush passes the buffered records for the trace to their handlers, and
+// marks the trace as flushed so later records pass through. Handles the
+// records without holding the lock, so later records for the trace wait on
+// the flushed channel instead. If another goroutine is flushing the trace,
+// waits for it. If rootID is valid, only flushes if it's the local root
+// span of the trace.
+func (b *tailBuffers) flush(ctx context.Context, traceID trace.TraceID, rootID trace.SpanID) {
+	b.mu.Lock()
+	elem, ok := b.traces[traceID]
+	if !ok {
+		b.mu.Unlock()
+		return
+	}
+	buf, _ := elem.Value.(*tailBuffer)
+	if rootID.IsValid() && buf.rootID != rootID {
+		b.mu.Unlock()
+		return
+	}
+	if done := buf.flushed; done != nil {
+		b.mu.Unlock()
+		<-done
+		return
+	}
+	pending := *buf
+	done := make(chan struct{})
+	buf.flushed = done
+	buf.records, buf.head, buf.count, buf.dropped = nil, 0, 0, 0
+	b.mu.Unlock()
+	b.handle(ctx, &pending)
+	close(done)
+}
+
+// remove stops buffering for the trace, discarding any buffered records, if
+// rootID is the local root span of the trace.
+func (b *tailBuffers) remove(traceID trace.TraceID, rootID trace.SpanID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	elem, ok := b.traces[traceID]
+	if !ok {
+		return
+	}
+	buf, _ := elem.Value.(*tailBuffer)
+	if buf.rootID != rootID {
+		return
+	}
+	b.order.Remove(elem)
+	delete(b.traces, traceID)
+}
+
+// handle passes the records of a buffer taken from the shared state, in
+// order, to their handlers.
+func (b *tailBuffers) handle(ctx context.Context, buf *tailBuffer) {
+	if buf.dropped > 0 {
+		r := slog.NewRecord(buf.records[buf.head].r.Time, slog.LevelWarn, DroppedBufferMessage, 0)
+		r.AddAttrs(slog.Int("dropped", buf.dropped))
+		_ = b.root.Handle(ctx, r)
+	}
+	for i := range buf.count {
+		rec := buf.records[(buf.head+i)%len(buf.records)]
+		_ = rec.next.Handle(ctx, rec.r)
+	}
+}
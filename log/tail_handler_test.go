@@ -0,0 +1,236 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jschaf/observe/internal/difftest"
+	"github.com/jschaf/observe/trace"
+)
+
+func TestTailHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, tr *trace.Tracer, l *slog.Logger, root *trace.Span)
+		want []string
+	}{
+		{
+			name: "success discards",
+			run: func(ctx context.Context, _ *trace.Tracer, l *slog.Logger, root *trace.Span) {
+				l.DebugContext(ctx, "d1")
+				l.InfoContext(ctx, "i1")
+				root.End()
+			},
+			want: []string{"level=INFO msg=i1"},
+		},
+		{
+			name: "error record flushes",
+			run: func(ctx context.Context, _ *trace.Tracer, l *slog.Logger, root *trace.Span) {
+				l.DebugContext(ctx, "d1")
+				l.InfoContext(ctx, "i1")
+				l.DebugContext(ctx, "d2")
+				l.ErrorContext(ctx, "e1")
+				l.DebugContext(ctx, "d3")
+				root.End()
+			},
+			want: []string{
+				"level=INFO msg=i1",
+				"level=DEBUG msg=d1",
+				"level=DEBUG msg=d2",
+				"level=ERROR msg=e1",
+				"level=DEBUG msg=d3",
+			},
+		},
+		{
+			name: "error status flushes",
+			run: func(ctx context.Context, _ *trace.Tracer, l *slog.Logger, root *trace.Span) {
+				l.DebugContext(ctx, "d1")
+				l.WithGroup("g").With("a", 1).DebugContext(ctx, "d2", "b", 2)
+				root.SetStatus(trace.StatusError, "boom")
+				root.End()
+			},
+			want: []string{"level=DEBUG msg=d1", "level=DEBUG msg=d2 g.a=1 g.b=2"},
+		},
+		{
+			name: "child span doesn't flush",
+			run: func(ctx context.Context, tr *trace.Tracer, l *slog.Logger, root *trace.Span) {
+				ctx, child := tr.Start(ctx, "child")
+				l.DebugContext(ctx, "d1")
+				child.SetStatus(trace.StatusError, "boom")
+				child.End()
+				root.End()
+			},
+			want: nil,
+		},
+		{
+			name: "no trace passes through",
+			run: func(_ context.Context, _ *trace.Tracer, l *slog.Logger, root *trace.Span) {
+				l.Debug("d1")
+				l.Info("i1")
+				root.End()
+			},
+			want: []string{"level=INFO msg=i1"},
+		},
+		{
+			name: "full buffer drops oldest",
+			run: func(ctx context.Context, _ *trace.Tracer, l *slog.Logger, root *trace.Span) {
+				for i := range 5 {
+					l.DebugContext(ctx, fmt.Sprintf("d%d", i))
+				}
+				l.ErrorContext(ctx, "e1")
+				root.End()
+			},
+			want: []string{
+				`level=WARN msg="` + DroppedBufferMessage + `" dropped=2`,
+				"level=DEBUG msg=d2",
+				"level=DEBUG msg=d3",
+				"level=DEBUG msg=d4",
+				"level=ERROR msg=e1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			h := NewTailHandler(newTestTextHandler(buf), &TailOptions{MaxRecords: 3})
+			tr := trace.NewTracer(h)
+			ctx, root := tr.Start(t.Context(), "root")
+			tt.run(ctx, tr, slog.New(h), &root)
+			difftest.AssertSame(t, "records", tt.want, logLines(buf))
+		})
+	}
+}
+
+func TestTailHandler_RemoteParent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h := NewTailHandler(newTestTextHandler(buf), nil)
+	tr := trace.NewTracer(h)
+	remote := trace.Context{Remote: true}
+	remote.TraceID, _ = trace.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	remote.SpanID, _ = trace.ParseSpanID("00f067aa0ba902b7")
+	ctx, root := tr.Start(trace.ContextWithRemote(t.Context(), remote), "root")
+	l := slog.New(h)
+
+	l.DebugContext(ctx, "d1")
+	l.ErrorContext(ctx, "e1")
+	root.End()
+	difftest.AssertSame(t, "records", []string{"level=DEBUG msg=d1", "level=ERROR msg=e1"}, logLines(buf))
+}
+
+func TestTailHandler_MaxTraces(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h := NewTailHandler(newTestTextHandler(buf), &TailOptions{MaxTraces: 1})
+	tr := trace.NewTracer(h)
+	l := slog.New(h)
+
+	ctx1, root1 := tr.Start(t.Context(), "root1")
+	l.DebugContext(ctx1, "d1")
+	ctx2, root2 := tr.Start(t.Context(), "root2") // evicts root1
+	l.DebugContext(ctx2, "d2")
+	l.DebugContext(ctx1, "d1 after eviction") // passes through as usual
+	l.ErrorContext(ctx1, "e1")
+	l.ErrorContext(ctx2, "e2")
+	root1.End()
+	root2.End()
+
+	difftest.AssertSame(t, "records", []string{"level=ERROR msg=e1", "level=DEBUG msg=d2", "level=ERROR msg=e2"}, logLines(buf))
+}
+
+func TestTailHandler_FlushOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		flush func(ctx context.Context, l *slog.Logger, root *trace.Span)
+	}{
+		{
+			name:  "error record",
+			flush: func(ctx context.Context, l *slog.Logger, _ *trace.Span) { l.ErrorContext(ctx, "e1") },
+		},
+		{
+			name: "root span error",
+			flush: func(_ context.Context, _ *slog.Logger, root *trace.Span) {
+				root.SetStatus(trace.StatusError, "boom")
+				root.End()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			gate := &msgGateHandler{Handler: newTestTextHandler(buf), msg: "d1", started: make(chan struct{}), release: make(chan struct{})}
+			h := NewTailHandler(gate, nil)
+			l := slog.New(h)
+			ctx, root := trace.NewTracer(h).Start(t.Context(), "root")
+			defer root.End()
+			l.DebugContext(ctx, "d1")
+			l.DebugContext(ctx, "d2")
+
+			var wg sync.WaitGroup
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				tt.flush(ctx, l, &root) // flushes, waiting in the gate on d1
+			}()
+			<-gate.started
+			go func() {
+				defer wg.Done()
+				l.DebugContext(ctx, "d3") // must wait for the flush
+			}()
+			go func() {
+				defer wg.Done()
+				l.WarnContext(ctx, "w1") // must wait for the flush
+			}()
+			time.Sleep(10 * time.Millisecond) // let d3 and w1 reach the handler
+			close(gate.release)
+			wg.Wait()
+
+			got := logLines(buf)
+			if len(got) < 2 {
+				t.Fatalf("got %d records, want at least 2: %q", len(got), got)
+			}
+			difftest.AssertSame(t, "flushed records", []string{"level=DEBUG msg=d1", "level=DEBUG msg=d2"}, got[:2])
+		})
+	}
+}
+
+// msgGateHandler is a slog.Handler that blocks records with the message,
+// signaling started, until release is closed.
+type msgGateHandler struct {
+	slog.Handler
+	msg     string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *msgGateHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Message == h.msg {
+		close(h.started)
+		<-h.release
+	}
+	return h.Handler.Handle(ctx, r) //nolint:wrapcheck // test wrapper
+}
+
+// newTestTextHandler returns a text handler at slog.LevelInfo that omits the
+// time.
+func newTestTextHandler(buf *bytes.Buffer) slog.Handler {
+	return slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+}
+
+func logLines(buf *bytes.Buffer) []string {
+	if buf.Len() == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
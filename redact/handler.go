@@ -0,0 +1,57 @@
+package redact
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Handler is a slog.Handler that masks sensitive values in records with a
+// Redactor before passing them to the next handler. Masks the attrs, from
+// the record and from WithAttrs, and substrings of the message matching the
+// value patterns. Passes records without sensitive values as is, without
+// allocating.
+type Handler struct {
+	next slog.Handler
+	r    *Redactor
+}
+
+// NewHandler returns a handler that redacts records with r before passing
+// them to next.
+func NewHandler(next slog.Handler, r *Redactor) *Handler {
+	return &Handler{next: next, r: r}
+}
+
+func (h *Handler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	msg, changed := h.r.replace(r.Message)
+	if !changed {
+		r.Attrs(func(a slog.Attr) bool {
+			_, changed = h.r.attr(a)
+			return !changed
+		})
+	}
+	if !changed {
+		return h.next.Handle(ctx, r) //nolint:wrapcheck // transparent wrapper
+	}
+	r2 := slog.NewRecord(r.Time, r.Level, msg, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		r2.AddAttrs(h.r.Attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, r2) //nolint:wrapcheck // transparent wrapper
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	attrs, _ = h.r.attrs(attrs)
+	return &Handler{next: h.next.WithAttrs(attrs), r: h.r}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{next: h.next.WithGroup(name), r: h.r}
+}
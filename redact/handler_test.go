@@ -0,0 +1,85 @@
+package redact
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+	"github.com/jschaf/observe/internal/race"
+)
+
+func TestHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := slog.New(NewHandler(newTestTextHandler(buf), New(nil)))
+
+	l.Info("plain", "user", "alice")
+	l.Info("login", "user", "alice", "password", "hunter2")
+	l.Info("sent to bob@example.com", "n", 1)
+	l.With("api_key", "k1", "region", "us").WithGroup("req").Info("call", "token", "t1", "id", 7)
+
+	want := []string{
+		`level=INFO msg=plain user=alice`,
+		`level=INFO msg=login user=alice password=[REDACTED]`,
+		`level=INFO msg="sent to [REDACTED]" n=1`,
+		`level=INFO msg=call api_key=[REDACTED] region=us req.token=[REDACTED] req.id=7`,
+	}
+	difftest.AssertSame(t, "lines", want, strings.Split(strings.TrimSpace(buf.String()), "\n"))
+}
+
+func TestHandler_Allocs(t *testing.T) {
+	if race.Enabled {
+		t.Skip("race detector adds allocations")
+	}
+	next := &countHandler{}
+	h := NewHandler(next, New(nil))
+	l := slog.New(h)
+	ctx := t.Context()
+	allocs := testing.AllocsPerRun(100, func() {
+		l.LogAttrs(ctx, slog.LevelInfo, "request", slog.String("method", "GET"), slog.Int("status", 200))
+	})
+	if allocs != 0 {
+		t.Errorf("got %.0f allocs for a record without sensitive values, want 0", allocs)
+	}
+}
+
+func BenchmarkHandler_Handle(b *testing.B) {
+	h := NewHandler(&countHandler{}, New(nil))
+	l := slog.New(h)
+	ctx := b.Context()
+	b.ReportAllocs()
+	for b.Loop() {
+		l.LogAttrs(ctx, slog.LevelInfo, "request",
+			slog.String("method", "GET"),
+			slog.String("path", "/users/123"),
+			slog.Int("status", 200),
+		)
+	}
+}
+
+func newTestTextHandler(buf *bytes.Buffer) slog.Handler {
+	return slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+}
+
+// countHandler counts handled records.
+type countHandler struct{ n int }
+
+func (h *countHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *countHandler) Handle(context.Context, slog.Record) error {
+	h.n++
+	return nil
+}
+
+func (h *countHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *countHandler) WithGroup(string) slog.Handler { return h }
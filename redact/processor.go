@@ -0,0 +1,28 @@
+package redact
+
+import (
+	"context"
+
+	"github.com/jschaf/observe/trace"
+)
+
+// SpanProcessor is a trace.SpanProcessor that masks sensitive values in the
+// attributes of ended spans and their events with a Redactor. Register it
+// before processors that export spans, like:
+//
+//	trace.NewTracer(redact.NewSpanProcessor(r), exporter)
+type SpanProcessor struct {
+	r *Redactor
+}
+
+// NewSpanProcessor returns a span processor that redacts spans with r.
+func NewSpanProcessor(r *Redactor) *SpanProcessor {
+	return &SpanProcessor{r: r}
+}
+
+func (p *SpanProcessor) OnStart(context.Context, *trace.Span) {}
+
+// OnEnd masks sensitive values in the span attributes.
+func (p *SpanProcessor) OnEnd(span *trace.Span) {
+	span.ReplaceAttrs(p.r.TraceAttr)
+}
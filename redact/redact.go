@@ -0,0 +1,318 @@
+// Package redact masks sensitive data, like passwords and tokens, in log
+// records and span attributes.
+package redact
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jschaf/observe/trace"
+)
+
+// DefaultMask replaces redacted values by default.
+const DefaultMask = "[REDACTED]"
+
+// DefaultKeys are the key patterns redacted by default.
+//
+//nolint:gochecknoglobals
+var DefaultKeys = []string{
+	"password", "passwd", "secret", "*_secret", "authorization", "cookie",
+	"set-cookie", "*token*", "api_key", "apikey", "*private_key*",
+}
+
+// Patterns of sensitive values.
+//
+//nolint:gochecknoglobals
+var (
+	// CreditCard matches Visa, Mastercard, American Express, and Discover
+	// card numbers, optionally separated by spaces or dashes.
+	CreditCard = regexp.MustCompile(`\b(?:(?:4\d{3}|5[1-5]\d{2}|2[2-7]\d{2}|6(?:011|5\d{2}))(?:[ -]?\d{4}){3}|3[47]\d{2}[ -]?\d{6}[ -]?\d{5})\b`)
+	// JWT matches JSON Web Tokens, whose header and payload start with the
+	// base64 encoding of `{"`.
+	JWT = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`)
+	// Email matches email addresses.
+	Email = regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)
+)
+
+// DefaultValues returns the value patterns redacted by default.
+func DefaultValues() []*regexp.Regexp {
+	return []*regexp.Regexp{CreditCard, JWT, Email}
+}
+
+// Options configures a Redactor.
+type Options struct {
+	// Keys are case-insensitive patterns of keys whose values to redact,
+	// where "*" matches any characters, like "*token*". A pattern matches the
+	// whole key or the part after the last dot, so "authorization" matches
+	// "http.request.header.authorization". Defaults to DefaultKeys if nil.
+	Keys []string
+	// Values are patterns of sensitive substrings of string values to
+	// redact. Defaults to DefaultValues if nil.
+	Values []*regexp.Regexp
+	// Mask replaces redacted values. Defaults to DefaultMask.
+	Mask string
+}
+
+// Redactor masks sensitive values by key pattern, by value pattern, and
+// values wrapped in Redacted. Leaves other values as is, without allocating,
+// except for span string slices, which need an iterator to scan.
+type Redactor struct {
+	keys   []string       // lowercase patterns
+	values *regexp.Regexp // all value patterns, or nil
+	mask   string
+}
+
+// New returns a Redactor. If opts is nil, uses the defaults.
+func New(opts *Options) *Redactor {
+	if opts == nil {
+		opts = &Options{}
+	}
+	r := &Redactor{mask: opts.Mask}
+	if r.mask == "" {
+		r.mask = DefaultMask
+	}
+	keys := opts.Keys
+	if keys == nil {
+		keys = DefaultKeys
+	}
+	for _, k := range keys {
+		r.keys = append(r.keys, strings.ToLower(k))
+	}
+	values := opts.Values
+	if values == nil {
+		values = DefaultValues()
+	}
+	if len(values) > 0 {
+		// Combine the patterns to scan each value once.
+		exprs := make([]string, len(values))
+		for i, re := range values {
+			exprs[i] = "(?:" + re.String() + ")"
+		}
+		r.values = regexp.MustCompile(strings.Join(exprs, "|"))
+	}
+	return r
+}
+
+// Attr returns the attr with sensitive values masked, including in groups.
+func (r *Redactor) Attr(a slog.Attr) slog.Attr {
+	a, _ = r.attr(a)
+	return a
+}
+
+// ReplaceAttr returns the attr with sensitive values masked, like Attr. The
+// groups don't affect matching. Use it as the ReplaceAttr function of a
+// slog.HandlerOptions to redact records with any handler:
+//
+//	r := redact.New(nil)
+//	h := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{ReplaceAttr: r.ReplaceAttr})
+func (r *Redactor) ReplaceAttr(_ []string, a slog.Attr) slog.Attr {
+	return r.Attr(a)
+}
+
+// TraceAttr returns the span attribute with sensitive values masked,
+// including elements of string slices.
+func (r *Redactor) TraceAttr(a trace.Attr) trace.Attr {
+	if r.matchKey(a.Key) {
+		return trace.String(a.Key, r.mask)
+	}
+	switch a.Value.Kind() {
+	case trace.KindString:
+		if s, ok := r.replace(a.Value.String()); ok {
+			return trace.String(a.Key, s)
+		}
+	case trace.KindStringSlice:
+		if r.matchStrings(a.Value) {
+			out, _ := r.strings(slices.Collect(a.Value.Strings()))
+			return trace.Strings(a.Key, out)
+		}
+	default:
+	}
+	return a
+}
+
+// matchStrings reports whether an element of the string slice value matches
+// a value pattern.
+func (r *Redactor) matchStrings(v trace.Value) bool {
+	for s := range v.Strings() {
+		if r.matchValue(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replace returns s with the substrings matching the value patterns masked.
+func (r *Redactor) Replace(s string) string {
+	s, _ = r.replace(s)
+	return s
+}
+
+// attr returns the attr with sensitive values masked and whether it changed
+// the attr.
+func (r *Redactor) attr(a slog.Attr) (slog.Attr, bool) {
+	if r.matchKey(a.Key) {
+		return slog.String(a.Key, r.mask), true
+	}
+	v, ok := r.value(a.Value)
+	if !ok {
+		return a, false
+	}
+	return slog.Attr{Key: a.Key, Value: v}, true
+}
+
+func (r *Redactor) value(v slog.Value) (slog.Value, bool) {
+	if v.Kind() == slog.KindLogValuer {
+		if _, ok := v.Any().(redactedMarker); ok {
+			return slog.StringValue(r.mask), true
+		}
+		// Keep the unresolved value if unchanged so that the next handler
+		// resolves it as usual.
+		return r.value(v.Resolve())
+	}
+	switch v.Kind() {
+	case slog.KindString:
+		if s, ok := r.replace(v.String()); ok {
+			return slog.StringValue(s), true
+		}
+	case slog.KindGroup:
+		if attrs, ok := r.attrs(v.Group()); ok {
+			return slog.GroupValue(attrs...), true
+		}
+	case slog.KindAny:
+		if ss, ok := v.Any().([]string); ok {
+			if out, ok := r.strings(ss); ok {
+				return slog.AnyValue(out), true
+			}
+		}
+	default:
+	}
+	return v, false
+}
+
+// attrs returns a copy of attrs with sensitive values masked, or attrs and
+// false if none changed.
+func (r *Redactor) attrs(attrs []slog.Attr) ([]slog.Attr, bool) {
+	var out []slog.Attr
+	for i, a := range attrs {
+		ra, ok := r.attr(a)
+		if ok && out == nil {
+			out = make([]slog.Attr, i, len(attrs))
+			copy(out, attrs[:i])
+		}
+		if out != nil {
+			out = append(out, ra)
+		}
+	}
+	if out == nil {
+		return attrs, false
+	}
+	return out, true
+}
+
+// strings returns a copy of ss with sensitive values masked, or ss and false
+// if none changed.
+func (r *Redactor) strings(ss []string) ([]string, bool) {
+	var out []string
+	for i, s := range ss {
+		rs, ok := r.replace(s)
+		if ok && out == nil {
+			out = make([]string, i, len(ss))
+			copy(out, ss[:i])
+		}
+		if out != nil {
+			out = append(out, rs)
+		}
+	}
+	if out == nil {
+		return ss, false
+	}
+	return out, true
+}
+
+func (r *Redactor) replace(s string) (string, bool) {
+	if !r.matchValue(s) {
+		return s, false
+	}
+	return r.values.ReplaceAllLiteralString(s, r.mask), true
+}
+
+func (r *Redactor) matchValue(s string) bool {
+	return r.values != nil && r.values.MatchString(s)
+}
+
+// matchKey reports whether the key, or the part after its last dot, matches
+// a key pattern.
+func (r *Redactor) matchKey(key string) bool {
+	last := key[strings.LastIndexByte(key, '.')+1:]
+	for _, p := range r.keys {
+		if matchPattern(p, key) || (len(last) < len(key) && matchPattern(p, last)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern reports whether s matches the lowercase pattern, where "*"
+// matches any characters, ignoring ASCII case.
+func matchPattern(pattern, s string) bool {
+	p, i := 0, 0
+	star, mark := -1, 0 // the last star in pattern and where it matched in s
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, i
+			p++
+		case p < len(pattern) && pattern[p] == toLower(s[i]):
+			p++
+			i++
+		case star >= 0:
+			// Backtrack, extending the match of the last star by one.
+			p = star + 1
+			mark++
+			i = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+func toLower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// Redacted wraps a sensitive value so that it's masked when logged or
+// formatted, like a password read from config. A Redactor masks it with
+// its mask.
+type Redacted[T any] struct {
+	v T
+}
+
+// Wrap returns v wrapped in a Redacted.
+func Wrap[T any](v T) Redacted[T] {
+	return Redacted[T]{v: v}
+}
+
+// Value returns the wrapped value.
+func (r Redacted[T]) Value() T { return r.v }
+
+func (Redacted[T]) String() string { return DefaultMask }
+
+func (Redacted[T]) GoString() string { return DefaultMask }
+
+func (Redacted[T]) LogValue() slog.Value { return slog.StringValue(DefaultMask) }
+
+func (Redacted[T]) MarshalText() ([]byte, error) { return []byte(DefaultMask), nil }
+
+func (Redacted[T]) redacted() {}
+
+// redactedMarker identifies Redacted values of any type.
+type redactedMarker interface{ redacted() }
@@ -105,6 +105,34 @@ func (k valueKind) String() string {
 	return valueKindStrings[k]
 }
 
+// Kind is the kind of a [Value].
+type Kind uint8
+
+// Kinds of [Value].
+const (
+	KindUnset        = Kind(kindUnset)
+	KindBool         = Kind(kindBool)
+	KindFloat64      = Kind(kindFloat64)
+	KindInt64        = Kind(kindInt64)
+	KindString       = Kind(kindString)
+	KindBoolSlice    = Kind(kindBoolSlice)
+	KindFloat64Slice = Kind(kindFloat64Slice)
+	KindInt64Slice   = Kind(kindInt64Slice)
+	KindStringSlice  = Kind(kindStringSlice)
+)
+
+func (k Kind) String() string {
+	if int(k) >= len(valueKindStrings) {
+		return "Kind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return valueKindStrings[k]
+}
+
+// Kind returns the kind of v.
+func (v Value) Kind() Kind {
+	return Kind(v.kind())
+}
+
 // boolValue returns a [Value] for a bool.
 func boolValue(v bool) Value {
 	u := uint64(0)
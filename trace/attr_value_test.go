@@ -73,3 +73,12 @@ func TestValueNoAlloc(t *testing.T) {
 	_ = b
 	_ = s
 }
+
+func TestKind_String(t *testing.T) {
+	if got, want := KindStringSlice.String(), "StringSlice"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := Kind(200).String(), "Kind(200)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
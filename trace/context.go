@@ -1,5 +1,7 @@
 package trace
 
+import "context"
+
 // Context identifies a span in a trace.
 type Context struct {
 	TraceID TraceID
@@ -13,3 +15,35 @@ func (sc Context) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsVa
 
 // IsSampled returns if Flags has the sampled bit set.
 func (sc Context) IsSampled() bool { return sc.Flags.IsSampled() }
+
+type (
+	spanCtxKey   struct{}
+	remoteCtxKey struct{}
+)
+
+// ContextWithSpan returns a copy of ctx with the span as the active span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanCtxKey{}, span)
+}
+
+// SpanFromContext returns the active span in ctx. Returns nil if ctx has no
+// span. All Span methods are safe to call on a nil span.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanCtxKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemote returns a copy of ctx with a remote span context, like
+// one extracted from HTTP headers. The next span started from ctx becomes a
+// child of the remote span.
+func ContextWithRemote(ctx context.Context, sc Context) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteCtxKey{}, sc)
+}
+
+// RemoteFromContext returns the remote span context in ctx. Returns the
+// zero-value of Context if ctx has no remote span context.
+func RemoteFromContext(ctx context.Context) Context {
+	sc, _ := ctx.Value(remoteCtxKey{}).(Context)
+	return sc
+}
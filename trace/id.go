@@ -1,6 +1,7 @@
 package trace
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math"
 	"math/rand/v2"
@@ -27,6 +28,15 @@ func (t TraceID) String() string {
 	return string(a[:])
 }
 
+// Raw returns the 16-byte big-endian binary form of a TraceID, like for
+// OTLP.
+func (t TraceID) Raw() [16]byte {
+	var a [16]byte
+	binary.BigEndian.PutUint64(a[:8], t.n.Hi)
+	binary.BigEndian.PutUint64(a[8:], t.n.Lo)
+	return a
+}
+
 // SpanID is the unique identifier for a span within a trace.
 type SpanID struct {
 	n uint64
@@ -45,6 +55,13 @@ func (s SpanID) String() string {
 	return string(a[:])
 }
 
+// Raw returns the 8-byte big-endian binary form of a SpanID, like for OTLP.
+func (s SpanID) Raw() [8]byte {
+	var a [8]byte
+	binary.BigEndian.PutUint64(a[:], s.n)
+	return a
+}
+
 func genTraceID() TraceID {
 	return TraceID{n: hextbl.Uint128{Hi: rand.Uint64(), Lo: rand.Uint64()}} //nolint:gosec
 }
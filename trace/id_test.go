@@ -396,3 +396,21 @@ func BenchmarkParseSpanID(b *testing.B) {
 func newTraceID(hi, lo uint64) TraceID {
 	return TraceID{n: hextbl.Uint128{Hi: hi, Lo: lo}}
 }
+
+func TestRaw(t *testing.T) {
+	traceID, err := ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spanID, err := ParseSpanID("00f067aa0ba902b7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	traceRaw, spanRaw := traceID.Raw(), spanID.Raw()
+	if got := hex.EncodeToString(traceRaw[:]); got != traceID.String() {
+		t.Errorf("TraceID.Raw() = %s, want %s", got, traceID.String())
+	}
+	if got := hex.EncodeToString(spanRaw[:]); got != spanID.String() {
+		t.Errorf("SpanID.Raw() = %s, want %s", got, spanID.String())
+	}
+}
@@ -0,0 +1,14 @@
+package trace
+
+import "context"
+
+// SpanProcessor receives spans from a Tracer when they start and end, like
+// for exporting spans to a backend.
+// https://opentelemetry.io/docs/specs/otel/trace/sdk/#span-processor
+type SpanProcessor interface {
+	// OnStart is called when a span starts. ctx is the context passed to
+	// [Tracer.Start]. The span is recording.
+	OnStart(ctx context.Context, span *Span)
+	// OnEnd is called once after a span ends. The span is no longer recording.
+	OnEnd(span *Span)
+}
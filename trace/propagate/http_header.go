@@ -28,9 +28,10 @@ func (h HTTPHeader) ExtractContext() trace.Context {
 		return trace.Context{}
 	}
 
+	// Multiple tracestate headers combine into a single State.
 	tracestate := m[headerTracestate]
 	if len(tracestate) > 0 {
-		state, err := trace.ParseState(tracestate[0])
+		state, err := trace.ParseStateValues(tracestate)
 		if err != nil {
 			// Ignore the error. A tracestate parse error must not affect parsing
 			// the traceparent according to the spec.
@@ -2,6 +2,8 @@ package propagate_test
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/jschaf/observe/internal/difftest"
@@ -123,6 +125,65 @@ func TestHTTPHeader_ExtractContext(t *testing.T) {
 	}
 }
 
+func TestHTTPHeader_ExtractContext_MultipleTracestate(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		name        string
+		tracestates []string
+		want        http.Header
+	}{
+		{
+			name:        "combines values",
+			tracestates: []string{"key1=value1", "key2=value2,key3=value3"},
+			want: http.Header{
+				headerTraceparent: []string{traceparent},
+				headerTracestate:  []string{"key1=value1,key2=value2,key3=value3"},
+			},
+		},
+		{
+			name:        "ignores duplicate keys across values",
+			tracestates: []string{"key1=value1", "key1=value2"},
+			want: http.Header{
+				headerTraceparent: []string{traceparent},
+			},
+		},
+		{
+			name:        "ignores too many members across values",
+			tracestates: []string{genMembers("a", 20), genMembers("b", 13)},
+			want: http.Header{
+				headerTraceparent: []string{traceparent},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set(headerTraceparent, traceparent)
+			for _, ts := range tt.tracestates {
+				h.Add(headerTracestate, ts)
+			}
+			got := propagate.HTTPHeader(h).ExtractContext()
+
+			gotHdr := http.Header{}
+			propagate.HTTPHeader(gotHdr).InjectContext(got)
+			difftest.AssertSame(t, "InjectHeader mismatch", tt.want, gotHdr)
+		})
+	}
+}
+
+// genMembers returns a tracestate string with n list-members using prefix for
+// the keys.
+func genMembers(prefix string, n int) string {
+	sb := strings.Builder{}
+	for i := range n {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(prefix + strconv.Itoa(i) + "=" + strconv.Itoa(i))
+	}
+	return sb.String()
+}
+
 func BenchmarkExtractHTTPHeaderContext(b *testing.B) {
 	h := http.Header{}
 	h.Set(headerTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
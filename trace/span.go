@@ -1,6 +1,8 @@
 package trace
 
 import (
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/jschaf/observe/internal/epoch"
@@ -11,9 +13,223 @@ import (
 // https://opentelemetry.io/docs/specs/otel/trace/api/#span
 type Span struct {
 	tracer    *Tracer     // immutable tracer that created this span
-	name      string      // immutable display name of the span
+	sc        Context     // immutable span context
+	parent    Context     // immutable parent span context; invalid for roots
+	kind      SpanKind    // immutable kind
 	start     epoch.Nanos // immutable start time
 	lifecycle *lifecycle
+	data      *spanData
+}
+
+// spanData is the mutable data of a span, shared by all copies of a Span.
+type spanData struct {
+	mu     sync.Mutex
+	name   string
+	attrs  []Attr
+	events []Event
+	status Status
+}
+
+// Event is a named, timestamped annotation on a span.
+// https://opentelemetry.io/docs/specs/otel/trace/api/#add-events
+type Event struct {
+	Name  string
+	Time  time.Time
+	Attrs []Attr
+}
+
+// SpanKind describes the relationship between the span, its parents, and its
+// children in a trace.
+// https://opentelemetry.io/docs/specs/otel/trace/api/#spankind
+type SpanKind uint8
+
+const (
+	SpanKindInternal SpanKind = iota // an internal operation; the default
+	SpanKindServer                   // handles a synchronous remote request
+	SpanKindClient                   // sends a synchronous remote request
+	SpanKindProducer                 // initiates an asynchronous request
+	SpanKindConsumer                 // handles an asynchronous request
+)
+
+//nolint:gochecknoglobals // string literals for string func
+var spanKindStrings = []string{
+	"Internal",
+	"Server",
+	"Client",
+	"Producer",
+	"Consumer",
+}
+
+func (k SpanKind) String() string {
+	if int(k) >= len(spanKindStrings) {
+		return "Unknown"
+	}
+	return spanKindStrings[k]
+}
+
+// StatusCode is the status of a span.
+// https://opentelemetry.io/docs/specs/otel/trace/api/#set-status
+type StatusCode uint8
+
+const (
+	StatusUnset StatusCode = iota // the default status
+	StatusOK                      // the operation completed successfully
+	StatusError                   // the operation contains an error
+)
+
+// Status is the status code and an optional description of a span.
+type Status struct {
+	Code        StatusCode
+	Description string
+}
+
+// Context returns the span context identifying the span. If the span is nil,
+// it returns the zero-value of Context.
+func (s *Span) Context() Context {
+	if s == nil {
+		return Context{}
+	}
+	return s.sc
+}
+
+// Parent returns the span context of the parent span. Returns the zero-value
+// of Context if the span is a root span or nil.
+func (s *Span) Parent() Context {
+	if s == nil {
+		return Context{}
+	}
+	return s.parent
+}
+
+// Kind returns the kind of the span. If the span is nil, it returns
+// SpanKindInternal.
+func (s *Span) Kind() SpanKind {
+	if s == nil {
+		return SpanKindInternal
+	}
+	return s.kind
+}
+
+// Name returns the display name of the span.
+func (s *Span) Name() string {
+	if s == nil {
+		return ""
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	return s.data.name
+}
+
+// SetName updates the display name of the span. Ignored if the span is not
+// recording.
+// https://opentelemetry.io/docs/specs/otel/trace/api/#updatename
+func (s *Span) SetName(name string) {
+	if !s.IsRecording() {
+		return
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	s.data.name = name
+}
+
+// SetAttrs sets attributes on the span, replacing any existing attribute with
+// the same key. Ignored if the span is not recording.
+// https://opentelemetry.io/docs/specs/otel/trace/api/#set-attributes
+func (s *Span) SetAttrs(attrs ...Attr) {
+	if !s.IsRecording() {
+		return
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	for _, attr := range attrs {
+		i := slices.IndexFunc(s.data.attrs, func(a Attr) bool { return a.Key == attr.Key })
+		if i >= 0 {
+			s.data.attrs[i] = attr
+			continue
+		}
+		s.data.attrs = append(s.data.attrs, attr)
+	}
+}
+
+// Attrs returns a copy of the attributes set on the span.
+func (s *Span) Attrs() []Attr {
+	if s == nil {
+		return nil
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	return slices.Clone(s.data.attrs)
+}
+
+// AddEvent adds an event with the current time to the span. Ignored if the
+// span is not recording.
+func (s *Span) AddEvent(name string, attrs ...Attr) {
+	if !s.IsRecording() {
+		return
+	}
+	ev := Event{Name: name, Time: time.Now(), Attrs: slices.Clone(attrs)}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	s.data.events = append(s.data.events, ev)
+}
+
+// Events returns a copy of the events added to the span.
+func (s *Span) Events() []Event {
+	if s == nil {
+		return nil
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	return slices.Clone(s.data.events)
+}
+
+// ReplaceAttrs replaces each attribute of the span and its events with the
+// result of f. Unlike SetAttrs, works after the span ends so that a span
+// processor can rewrite attributes in OnEnd, like to redact secrets, before
+// later processors export the span.
+func (s *Span) ReplaceAttrs(f func(Attr) Attr) {
+	if s == nil {
+		return
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	for i, a := range s.data.attrs {
+		s.data.attrs[i] = f(a)
+	}
+	for _, ev := range s.data.events {
+		for i, a := range ev.Attrs {
+			ev.Attrs[i] = f(a)
+		}
+	}
+}
+
+// SetStatus sets the status of the span. An OK status is final and overrides
+// Error; an Unset status is ignored. The description is only kept for the
+// Error status. Ignored if the span is not recording.
+// https://opentelemetry.io/docs/specs/otel/trace/api/#set-status
+func (s *Span) SetStatus(code StatusCode, desc string) {
+	if code == StatusUnset || !s.IsRecording() {
+		return
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	if s.data.status.Code == StatusOK {
+		return
+	}
+	if code != StatusError {
+		desc = ""
+	}
+	s.data.status = Status{Code: code, Description: desc}
+}
+
+// Status returns the status of the span.
+func (s *Span) Status() Status {
+	if s == nil {
+		return Status{}
+	}
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	return s.data.status
 }
 
 // IsRecording returns true if the span currently records data. Returns false
@@ -74,4 +290,7 @@ func (s *Span) End(opts ...SpanEndOption) {
 	if !s.lifecycle.stopRecording(cfg.endTime) {
 		return // if the span was already stopped, ignore the End call
 	}
+	for _, p := range s.tracer.spanProcessors() {
+		p.OnEnd(s)
+	}
 }
@@ -31,6 +31,121 @@ func TestSpan_End(t *testing.T) {
 	})
 }
 
+func TestSpan_SetAttrs(t *testing.T) {
+	span := startTestSpan(t)
+	span.SetAttrs(trace.String("foo", "bar"), trace.Int("n", 1))
+	span.SetAttrs(trace.Int("n", 2), trace.Bool("ok", true))
+	difftest.AssertSame(t, "attrs mismatch", []string{"foo=bar", "n=2", "ok=true"}, attrStrings(span.Attrs()))
+
+	span.End()
+	span.SetAttrs(trace.String("after", "end"))
+	difftest.AssertSame(t, "attrs after End mismatch", []string{"foo=bar", "n=2", "ok=true"}, attrStrings(span.Attrs()))
+}
+
+func TestSpan_ReplaceAttrs(t *testing.T) {
+	span := startTestSpan(t)
+	span.SetAttrs(trace.String("foo", "bar"), trace.Int("n", 1))
+	span.AddEvent("ev", trace.String("foo", "baz"))
+	span.End()
+	span.ReplaceAttrs(func(a trace.Attr) trace.Attr {
+		if a.Key == "foo" {
+			return trace.String(a.Key, "x")
+		}
+		return a
+	})
+	difftest.AssertSame(t, "attrs mismatch", []string{"foo=x", "n=1"}, attrStrings(span.Attrs()))
+	difftest.AssertSame(t, "event attrs mismatch", []string{"foo=x"}, attrStrings(span.Events()[0].Attrs))
+}
+
+func TestSpan_AddEvent(t *testing.T) {
+	span := startTestSpan(t)
+	attrs := []trace.Attr{trace.String("foo", "bar")}
+	span.AddEvent("first", attrs...)
+	attrs[0] = trace.String("mutated", "after")
+	span.AddEvent("second")
+	span.End()
+	span.AddEvent("after end")
+
+	events := span.Events()
+	names := make([]string, len(events))
+	for i, ev := range events {
+		names[i] = ev.Name
+		if ev.Time.IsZero() {
+			t.Errorf("event %q has zero time", ev.Name)
+		}
+	}
+	difftest.AssertSame(t, "event names mismatch", []string{"first", "second"}, names)
+	difftest.AssertSame(t, "event attrs mismatch", []string{"foo=bar"}, attrStrings(events[0].Attrs))
+}
+
+func TestSpan_SetName(t *testing.T) {
+	span := startTestSpan(t)
+	span.SetName("renamed")
+	difftest.AssertSame(t, "name mismatch", "renamed", span.Name())
+	span.End()
+	span.SetName("after end")
+	difftest.AssertSame(t, "name after End mismatch", "renamed", span.Name())
+}
+
+func TestSpan_SetStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     []trace.Status
+		want    trace.Status
+		wantEnd bool
+	}{
+		{
+			name: "default unset",
+			want: trace.Status{},
+		},
+		{
+			name: "error",
+			set:  []trace.Status{{Code: trace.StatusError, Description: "boom"}},
+			want: trace.Status{Code: trace.StatusError, Description: "boom"},
+		},
+		{
+			name: "ok drops description",
+			set:  []trace.Status{{Code: trace.StatusOK, Description: "fine"}},
+			want: trace.Status{Code: trace.StatusOK},
+		},
+		{
+			name: "unset ignored",
+			set:  []trace.Status{{Code: trace.StatusError, Description: "boom"}, {Code: trace.StatusUnset}},
+			want: trace.Status{Code: trace.StatusError, Description: "boom"},
+		},
+		{
+			name: "ok is final",
+			set:  []trace.Status{{Code: trace.StatusOK}, {Code: trace.StatusError, Description: "boom"}},
+			want: trace.Status{Code: trace.StatusOK},
+		},
+		{
+			name: "ok overrides error",
+			set:  []trace.Status{{Code: trace.StatusError, Description: "boom"}, {Code: trace.StatusOK}},
+			want: trace.Status{Code: trace.StatusOK},
+		},
+		{
+			name:    "ignored after end",
+			set:     []trace.Status{{Code: trace.StatusError, Description: "boom"}},
+			want:    trace.Status{},
+			wantEnd: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := startTestSpan(t)
+			if tt.wantEnd {
+				span.End()
+			}
+			for _, st := range tt.set {
+				span.SetStatus(st.Code, st.Description)
+			}
+			got := span.Status()
+			difftest.AssertSame(t, "status code mismatch", uint8(tt.want.Code), uint8(got.Code))
+			difftest.AssertSame(t, "status description mismatch", tt.want.Description, got.Description)
+		})
+	}
+}
+
 func TestSpan_End_Race(t *testing.T) {
 	span := startTestSpan(t)
 
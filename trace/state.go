@@ -6,6 +6,7 @@ import (
 	"iter"
 	"slices"
 	"strings"
+	"unsafe"
 )
 
 // State provides vendor-specific trace information as key-value pairs called
@@ -20,9 +21,11 @@ type State struct {
 	isClean bool   // isClean means s doesn't have extra whitespace
 }
 
+// maxMembers is the maximum number of list-members in a tracestate.
+const maxMembers = 32
+
 // ParseState parses a tracestate string into State.
 func ParseState(ts string) (State, error) {
-	const maxMembers = 32
 	if len(ts) == 0 {
 		return State{}, nil
 	}
@@ -77,6 +80,37 @@ func ParseState(ts string) (State, error) {
 	return State{s: ts, isClean: isClean}, nil
 }
 
+// ParseStateValues parses multiple tracestate header values into a single
+// State. Combines the values as if joined by commas, as required by the spec
+// for multiple tracestate headers. The 32 list-member limit and duplicate key
+// check apply across all values.
+// https://www.w3.org/TR/trace-context-1/#tracestate-header-field-values
+func ParseStateValues(vals []string) (State, error) {
+	switch len(vals) {
+	case 0:
+		return State{}, nil
+	case 1:
+		return ParseState(vals[0])
+	}
+
+	n := 0
+	for _, v := range vals {
+		n += len(v) + 1
+	}
+	buf := make([]byte, 0, n)
+	for _, v := range vals {
+		if len(v) == 0 {
+			continue // an empty header value has no list-members
+		}
+		if len(buf) > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, v...)
+	}
+	// buf is never modified after this point, so share its memory.
+	return ParseState(unsafe.String(unsafe.SliceData(buf), len(buf)))
+}
+
 func checkDupes(ts string, dupeLenBits *bitset) error {
 	// Note: we're checking for duplicates in the hash of the key, not the key
 	// itself. We may have false positives, but it should be vanishingly rare.
@@ -134,11 +168,144 @@ func (st State) String() string {
 	return sb.String()
 }
 
+// Len returns the number of list-members in the State.
+func (st State) Len() int {
+	n := 0
+	for range st.Members() {
+		n++
+	}
+	return n
+}
+
+// Get returns the value of the list-member with key. Returns false if no
+// list-member has the key.
+func (st State) Get(key string) (string, bool) {
+	for pos := range splitMembers(st.s) {
+		if pos.isEmpty() || !pos.isValid() {
+			continue
+		}
+		if pos.keyString(st.s) == key {
+			return pos.valString(st.s), true
+		}
+	}
+	return "", false
+}
+
+// Insert returns a new State with the key-value list-member at the front
+// (left-most position), as required by the spec when a vendor modifies its
+// entry. Removes any existing list-member with the same key. If the new State
+// exceeds 32 list-members, drops the right-most list-members.
+// https://www.w3.org/TR/trace-context-1/#mutating-the-tracestate-field
+func (st State) Insert(key, val string) (State, error) {
+	buf := make([]byte, 0, len(key)+1+len(val)+1+len(st.s))
+	buf = append(buf, key...)
+	buf = append(buf, '=')
+	buf = append(buf, val...)
+	pos := memberPos{keyLo: 0, keyHi: len(key), valLo: len(key) + 1, valHi: len(buf)}
+	if err := checkNewMember(unsafe.String(unsafe.SliceData(buf), len(buf)), pos); err != nil {
+		return st, err
+	}
+
+	count := 1
+	for pos := range splitMembers(st.s) {
+		if count == maxMembers {
+			break // evict the right-most list-members
+		}
+		if pos.isEmpty() || !pos.isValid() || pos.keyString(st.s) == key {
+			continue
+		}
+		buf = append(buf, ',')
+		buf = append(buf, pos.memberString(st.s)...)
+		count++
+	}
+	// buf is never modified after this point, so share its memory.
+	return State{s: unsafe.String(unsafe.SliceData(buf), len(buf)), isClean: true}, nil
+}
+
+// Delete returns a new State without the list-member with key. Returns st
+// unchanged if no list-member has the key.
+func (st State) Delete(key string) State {
+	if _, ok := st.Get(key); !ok {
+		return st
+	}
+	buf := make([]byte, 0, len(st.s))
+	for pos := range splitMembers(st.s) {
+		if pos.isEmpty() || !pos.isValid() || pos.keyString(st.s) == key {
+			continue
+		}
+		if len(buf) > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, pos.memberString(st.s)...)
+	}
+	return State{s: string(buf), isClean: true}
+}
+
 // MarshalJSON marshals the TraceState into JSON.
 func (st State) MarshalJSON() ([]byte, error) {
 	return json.Marshal(st.String())
 }
 
+// StateBuilder builds a State from list-members without concatenating
+// strings. List-members appear in the order added. The zero value is ready to
+// use.
+type StateBuilder struct {
+	buf         []byte
+	count       int
+	seenLenBits bitset
+	dupeLenBits bitset
+	err         error
+}
+
+// Add appends a key-value list-member to the right of the existing
+// list-members. Invalid list-members cause [StateBuilder.Build] to return an
+// error.
+func (b *StateBuilder) Add(key, val string) *StateBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.count++
+	if b.count > maxMembers {
+		b.err = fmt.Errorf("too many members")
+		return b
+	}
+	lo := len(b.buf)
+	if lo > 0 {
+		b.buf = append(b.buf, ',')
+		lo++
+	}
+	b.buf = append(b.buf, key...)
+	b.buf = append(b.buf, '=')
+	b.buf = append(b.buf, val...)
+	ts := unsafe.String(unsafe.SliceData(b.buf), len(b.buf))
+	pos := memberPos{keyLo: lo, keyHi: lo + len(key), valLo: lo + len(key) + 1, valHi: len(b.buf)}
+	if err := checkNewMember(ts, pos); err != nil {
+		b.err = err
+		return b
+	}
+	l := pos.keyLen()
+	if b.seenLenBits.hasBit(l) {
+		b.dupeLenBits.setBit(l)
+	}
+	b.seenLenBits.setBit(l)
+	return b
+}
+
+// Build returns the State containing all added list-members. Returns an error
+// if any list-member was invalid or if keys are duplicated.
+func (b *StateBuilder) Build() (State, error) {
+	if b.err != nil {
+		return State{}, b.err
+	}
+	ts := string(b.buf)
+	if b.dupeLenBits.hasAny() {
+		if err := checkDupes(ts, &b.dupeLenBits); err != nil {
+			return State{}, err
+		}
+	}
+	return State{s: ts, isClean: true}, nil
+}
+
 // bitset tracks up to 64 values. Clamps all values to 0-63.
 type bitset uint64
 
@@ -266,6 +433,20 @@ func checkKey(ts string, pos memberPos) bool {
 	return true
 }
 
+// checkNewMember validates a list-member built from a separate key and value.
+// Unlike members found by splitMembers, the value may contain a comma or
+// trailing whitespace, which the spec disallows.
+func checkNewMember(ts string, pos memberPos) error {
+	if !checkKey(ts, pos) {
+		return fmt.Errorf("invalid key: %q", pos.keyString(ts))
+	}
+	if !checkVal(ts, pos) || isSpace(ts[pos.valHi-1]) ||
+		strings.IndexByte(pos.valString(ts), ',') >= 0 {
+		return fmt.Errorf("invalid value: %q", pos.valString(ts))
+	}
+	return nil
+}
+
 func checkKeyRest(ts string, lo, hi int) bool {
 	if hi == lo {
 		return true
@@ -274,6 +274,79 @@ func TestParseState(t *testing.T) {
 	}
 }
 
+func TestParseStateValues(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want string
+		err  string
+	}{
+		{
+			name: "nil",
+			in:   nil,
+			want: "",
+		},
+		{
+			name: "single value",
+			in:   []string{"foo=1,bar=2"},
+			want: "foo=1,bar=2",
+		},
+		{
+			name: "two values",
+			in:   []string{"foo=1", "bar=2"},
+			want: "foo=1,bar=2",
+		},
+		{
+			name: "multiple lists",
+			in:   []string{"foo=1, bar=2", "baz=3,\tqux=4"},
+			want: "foo=1,bar=2,baz=3,qux=4",
+		},
+		{
+			name: "empty values",
+			in:   []string{"", "foo=1", "", "bar=2", ""},
+			want: "foo=1,bar=2",
+		},
+		{
+			name: "max members across values",
+			in:   []string{genTracestateSequence("foo%d=%d", 16), genTracestateSequence("bar%d=%d", 16)},
+			want: genTracestateSequence("foo%d=%d", 16) + "," + genTracestateSequence("bar%d=%d", 16),
+		},
+		{
+			name: "too many members across values",
+			in:   []string{genTracestateSequence("foo%d=%d", 16), genTracestateSequence("bar%d=%d", 17)},
+			err:  "too many members",
+		},
+		{
+			name: "duplicate key across values",
+			in:   []string{"foo=1,bar=2", "foo=3"},
+			err:  "duplicate key",
+		},
+		{
+			name: "invalid second value",
+			in:   []string{"foo=1", "BAR=2"},
+			err:  "invalid key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := trace.ParseStateValues(tt.in)
+			if err != nil {
+				if tt.err == "" {
+					t.Errorf("got parse state error: %v", err)
+				} else if !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("want error %q substring; not found in: %s", tt.err, err.Error())
+				}
+				return
+			}
+			if tt.err != "" {
+				t.Errorf("want error %q, got nil", tt.err)
+				return
+			}
+			difftest.AssertSame(t, "State.String() mismatch", tt.want, got.String())
+		})
+	}
+}
+
 func genTracestateSequence(tmpl string, n int) string {
 	seq := make([]string, n)
 	for i := range n {
@@ -417,3 +490,325 @@ func BenchmarkParseState(b *testing.B) {
 		})
 	}
 }
+
+func TestState_Get(t *testing.T) {
+	st, err := trace.ParseState("foo=1 , bar=2,baz=3")
+	if err != nil {
+		t.Fatalf("parse state: %v", err)
+	}
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{key: "foo", want: "1", wantOK: true},
+		{key: "bar", want: "2", wantOK: true},
+		{key: "baz", want: "3", wantOK: true},
+		{key: "ba", want: "", wantOK: false},
+		{key: "", want: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := st.Get(tt.key)
+			difftest.AssertSame(t, "State.Get() value mismatch", tt.want, got)
+			difftest.AssertSame(t, "State.Get() ok mismatch", tt.wantOK, ok)
+		})
+	}
+}
+
+func TestState_Insert(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		key   string
+		val   string
+		want  string
+		err   string
+	}{
+		{
+			name: "empty state",
+			key:  "foo",
+			val:  "1",
+			want: "foo=1",
+		},
+		{
+			name:  "new key moves to front",
+			state: "foo=1,bar=2",
+			key:   "baz",
+			val:   "3",
+			want:  "baz=3,foo=1,bar=2",
+		},
+		{
+			name:  "existing key moves to front",
+			state: "foo=1,bar=2,baz=3",
+			key:   "bar",
+			val:   "4",
+			want:  "bar=4,foo=1,baz=3",
+		},
+		{
+			name:  "existing first key",
+			state: "foo=1,bar=2",
+			key:   "foo",
+			val:   "5",
+			want:  "foo=5,bar=2",
+		},
+		{
+			name:  "cleans whitespace",
+			state: " foo=1 ,\tbar=2, ",
+			key:   "baz",
+			val:   "3",
+			want:  "baz=3,foo=1,bar=2",
+		},
+		{
+			name:  "tenant key",
+			state: "foo=1",
+			key:   "t@v",
+			val:   "2",
+			want:  "t@v=2,foo=1",
+		},
+		{
+			name:  "evicts right-most member",
+			state: genTracestateSequence("bar%d=%d", 32),
+			key:   "foo",
+			val:   "1",
+			want:  "foo=1," + genTracestateSequence("bar%d=%d", 31),
+		},
+		{
+			name:  "existing key at max members",
+			state: genTracestateSequence("bar%d=%d", 32),
+			key:   "bar31",
+			val:   "x",
+			want:  "bar31=x," + genTracestateSequence("bar%d=%d", 31),
+		},
+		{
+			name:  "invalid key",
+			state: "foo=1",
+			key:   "FOO",
+			val:   "1",
+			err:   "invalid key",
+		},
+		{
+			name: "empty key",
+			val:  "1",
+			err:  "invalid key",
+		},
+		{
+			name: "key with equal",
+			key:  "a=b",
+			val:  "1",
+			err:  "invalid key",
+		},
+		{
+			name: "empty value",
+			key:  "foo",
+			err:  "invalid value",
+		},
+		{
+			name: "value with comma",
+			key:  "foo",
+			val:  "1,bar=2",
+			err:  "invalid value",
+		},
+		{
+			name: "value with trailing space",
+			key:  "foo",
+			val:  "1 ",
+			err:  "invalid value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := trace.ParseState(tt.state)
+			if err != nil {
+				t.Fatalf("parse state: %v", err)
+			}
+			got, err := st.Insert(tt.key, tt.val)
+			if err != nil {
+				if tt.err == "" {
+					t.Errorf("got insert error: %v", err)
+				} else if !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("want error %q substring; not found in: %s", tt.err, err.Error())
+				}
+				difftest.AssertSame(t, "State modified on error", st.String(), got.String())
+				return
+			}
+			if tt.err != "" {
+				t.Errorf("want error %q, got nil", tt.err)
+				return
+			}
+			difftest.AssertSame(t, "State.Insert() mismatch", tt.want, got.String())
+			difftest.AssertSame(t, "original State modified", mustParseState(t, tt.state).String(), st.String())
+
+			// The result must be a valid tracestate.
+			if _, err := trace.ParseState(got.String()); err != nil {
+				t.Errorf("parse inserted state: %v", err)
+			}
+		})
+	}
+}
+
+func TestState_Delete(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		key   string
+		want  string
+	}{
+		{name: "empty state", key: "foo", want: ""},
+		{name: "missing key", state: "foo=1,bar=2", key: "baz", want: "foo=1,bar=2"},
+		{name: "first key", state: "foo=1,bar=2,baz=3", key: "foo", want: "bar=2,baz=3"},
+		{name: "middle key", state: "foo=1,bar=2,baz=3", key: "bar", want: "foo=1,baz=3"},
+		{name: "last key", state: "foo=1,bar=2,baz=3", key: "baz", want: "foo=1,bar=2"},
+		{name: "only key", state: "foo=1", key: "foo", want: ""},
+		{name: "with whitespace", state: " foo=1 ,\tbar=2, ", key: "foo", want: "bar=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := mustParseState(t, tt.state)
+			got := st.Delete(tt.key)
+			difftest.AssertSame(t, "State.Delete() mismatch", tt.want, got.String())
+			if _, ok := got.Get(tt.key); ok {
+				t.Errorf("State.Get(%q) found key after Delete", tt.key)
+			}
+		})
+	}
+}
+
+func TestStateBuilder(t *testing.T) {
+	tests := []struct {
+		name    string
+		members [][2]string
+		want    string
+		err     string
+	}{
+		{
+			name: "empty",
+			want: "",
+		},
+		{
+			name:    "single member",
+			members: [][2]string{{"foo", "1"}},
+			want:    "foo=1",
+		},
+		{
+			name:    "preserves order",
+			members: [][2]string{{"foo", "1"}, {"bar", "2"}, {"t@v", "3"}},
+			want:    "foo=1,bar=2,t@v=3",
+		},
+		{
+			name:    "duplicate key",
+			members: [][2]string{{"foo", "1"}, {"bar", "2"}, {"foo", "3"}},
+			err:     "duplicate key",
+		},
+		{
+			name:    "invalid key",
+			members: [][2]string{{"foo", "1"}, {"BAR", "2"}},
+			err:     "invalid key",
+		},
+		{
+			name:    "invalid value",
+			members: [][2]string{{"foo", "1,bar=2"}},
+			err:     "invalid value",
+		},
+		{
+			name:    "too many members",
+			members: genMembers(33),
+			err:     "too many members",
+		},
+		{
+			name:    "max members",
+			members: genMembers(32),
+			want:    genTracestateSequence("bar%d=%d", 32),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := trace.StateBuilder{}
+			for _, m := range tt.members {
+				b.Add(m[0], m[1])
+			}
+			got, err := b.Build()
+			if err != nil {
+				if tt.err == "" {
+					t.Errorf("got build error: %v", err)
+				} else if !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("want error %q substring; not found in: %s", tt.err, err.Error())
+				}
+				return
+			}
+			if tt.err != "" {
+				t.Errorf("want error %q, got nil", tt.err)
+				return
+			}
+			difftest.AssertSame(t, "StateBuilder.Build() mismatch", tt.want, got.String())
+		})
+	}
+}
+
+func mustParseState(t *testing.T, s string) trace.State {
+	t.Helper()
+	st, err := trace.ParseState(s)
+	if err != nil {
+		t.Fatalf("parse state %q: %v", s, err)
+	}
+	return st
+}
+
+func genMembers(n int) [][2]string {
+	members := make([][2]string, n)
+	for i := range n {
+		members[i] = [2]string{"bar" + strconv.Itoa(i), strconv.Itoa(i)}
+	}
+	return members
+}
+
+func BenchmarkState_Insert(b *testing.B) {
+	benches := []struct {
+		name  string
+		state string
+	}{
+		{
+			name:  "empty state",
+			state: "",
+		},
+		{
+			name:  "five realistic keys",
+			state: "redis/inc-count@tenant=foobar123,x-trace-id=deadbeefCafe1234,x-cache-hit=1,pg-req-id=1234567890,x-dd-trace-id=deadbeefCafe1234",
+		},
+		{
+			name:  "max keys",
+			state: genTracestateSequence("bar%d=%d", 32),
+		},
+	}
+	for _, bench := range benches {
+		st, err := trace.ParseState(bench.state)
+		if err != nil {
+			b.Fatalf("parse trace state: %v", err)
+		}
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				_, err := st.Insert("x-cache-hit", "0")
+				if err != nil {
+					b.Fatalf("insert trace state: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkStateBuilder(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		sb := trace.StateBuilder{}
+		sb.Add("redis/inc-count@tenant", "foobar123").
+			Add("x-trace-id", "deadbeefCafe1234").
+			Add("x-cache-hit", "1").
+			Add("pg-req-id", "1234567890").
+			Add("x-dd-trace-id", "deadbeefCafe1234")
+		_, err := sb.Build()
+		if err != nil {
+			b.Fatalf("build trace state: %v", err)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package tracehttp instruments net/http servers and clients with spans.
+package tracehttp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jschaf/observe/trace"
+	"github.com/jschaf/observe/trace/propagate"
+)
+
+type handler struct {
+	next   http.Handler
+	tracer *trace.Tracer
+}
+
+// NewHandler wraps next to start a server span for each request. The span is
+// a child of the traceparent and tracestate headers, if present, and is
+// available to next with [trace.SpanFromContext].
+//
+// The span is named from the route pattern matched by [http.ServeMux], like
+// "GET /items/{id}", so next is typically a ServeMux. The span ends after
+// next returns or panics.
+func NewHandler(next http.Handler, opts ...Option) http.Handler {
+	cfg := newConfig(opts)
+	return &handler{next: next, tracer: cfg.tracer}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if sc := propagate.HTTPHeader(r.Header).ExtractContext(); sc.IsValid() {
+		ctx = trace.ContextWithRemote(ctx, sc)
+	}
+	ctx, span := h.tracer.Start(ctx, r.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttrs(
+			trace.String(keyRequestMethod, r.Method),
+			trace.String(keyURLPath, r.URL.Path),
+			trace.String(keyClientAddress, clientAddress(r)),
+			trace.String(keyUserAgent, r.UserAgent()),
+		),
+	)
+
+	r = r.WithContext(ctx) // the mux sets the Pattern on this copy
+	var body *countingBody
+	if r.Body != nil && r.Body != http.NoBody {
+		body = &countingBody{rc: r.Body}
+		r.Body = body
+	}
+	rw := &responseWriter{w: w}
+
+	defer func() {
+		if p := recover(); p != nil {
+			span.SetStatus(trace.StatusError, fmt.Sprintf("panic: %v", p))
+			endSpan(&span, r, rw, body)
+			panic(p)
+		}
+		if rw.status == 0 {
+			rw.status = http.StatusOK // the server writes an implicit 200
+		}
+		endSpan(&span, r, rw, body)
+	}()
+
+	h.next.ServeHTTP(rw, r)
+}
+
+// endSpan records the response attributes and ends the span.
+func endSpan(span *trace.Span, r *http.Request, rw *responseWriter, body *countingBody) {
+	if route := routeFromPattern(r.Pattern); route != "" {
+		span.SetName(r.Method + " " + route)
+		span.SetAttrs(trace.String(keyRoute, route))
+	}
+	if body != nil {
+		span.SetAttrs(trace.Int64(keyRequestBodySize, body.n.Load()))
+	}
+	span.SetAttrs(trace.Int64(keyResponseBodySize, rw.written))
+	if rw.status != 0 {
+		span.SetAttrs(trace.Int(keyResponseStatusCode, rw.status))
+	}
+	if rw.status >= 500 {
+		span.SetStatus(trace.StatusError, http.StatusText(rw.status))
+	}
+	span.End()
+}
+
+// routeFromPattern returns the path of a ServeMux pattern, stripping the
+// optional method and host.
+//
+//	[METHOD ][HOST]/[PATH]
+func routeFromPattern(pattern string) string {
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i+1:], " \t")
+	}
+	if i := strings.IndexByte(pattern, '/'); i >= 0 {
+		return pattern[i:]
+	}
+	return ""
+}
+
+// clientAddress returns the host of the remote address without the port.
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// countingBody counts the bytes read from a request body.
+type countingBody struct {
+	rc io.ReadCloser
+	n  atomic.Int64 // handlers may read the body from another goroutine
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.n.Add(int64(n))
+	return n, err //nolint:wrapcheck // must return io.EOF unwrapped
+}
+
+func (b *countingBody) Close() error {
+	return b.rc.Close() //nolint:wrapcheck // transparent wrapper
+}
+
+// responseWriter records the status code and body size of a response. It
+// implements http.Flusher, http.Hijacker, and io.ReaderFrom by delegating to
+// the wrapped writer.
+type responseWriter struct {
+	w       http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (rw *responseWriter) Header() http.Header { return rw.w.Header() }
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter { return rw.w }
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.status < http.StatusOK {
+		rw.status = code // 1xx informational responses precede the final status
+	}
+	rw.w.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if rw.status < http.StatusOK {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.w.Write(p)
+	rw.written += int64(n)
+	return n, err //nolint:wrapcheck // transparent wrapper
+}
+
+// Flush implements http.Flusher. Does nothing if the wrapped writer doesn't
+// implement http.Flusher.
+func (rw *responseWriter) Flush() {
+	f, ok := rw.w.(http.Flusher)
+	if !ok {
+		return
+	}
+	if rw.status < http.StatusOK {
+		rw.status = http.StatusOK
+	}
+	f.Flush()
+}
+
+// Hijack implements http.Hijacker. Returns an error wrapping
+// http.ErrNotSupported if the wrapped writer doesn't implement http.Hijacker.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := rw.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijack %T: %w", rw.w, http.ErrNotSupported)
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, fmt.Errorf("hijack: %w", err)
+	}
+	return conn, brw, nil
+}
+
+// ReadFrom implements io.ReaderFrom, allowing the wrapped writer to use
+// sendfile or splice.
+func (rw *responseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if rw.status < http.StatusOK {
+		rw.status = http.StatusOK
+	}
+	var n int64
+	var err error
+	if rf, ok := rw.w.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(rw.w, src)
+	}
+	rw.written += n
+	return n, err //nolint:wrapcheck // transparent wrapper
+}
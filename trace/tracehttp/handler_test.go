@@ -0,0 +1,236 @@
+package tracehttp_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+	"github.com/jschaf/observe/trace"
+	"github.com/jschaf/observe/trace/tracehttp"
+)
+
+func TestNewHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		pattern    string
+		method     string
+		target     string
+		body       string
+		respond    func(w http.ResponseWriter, r *http.Request)
+		wantName   string
+		wantAttrs  []string
+		wantStatus trace.StatusCode
+	}{
+		{
+			name:    "route pattern",
+			pattern: "GET /items/{id}",
+			method:  http.MethodGet,
+			target:  "/items/123",
+			respond: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(w, "hello")
+			},
+			wantName: "GET /items/{id}",
+			wantAttrs: []string{
+				"http.request.method=GET",
+				"url.path=/items/123",
+				"client.address=192.0.2.1",
+				"user_agent.original=test-agent",
+				"http.route=/items/{id}",
+				"http.response.body.size=5",
+				"http.response.status_code=200",
+			},
+		},
+		{
+			name:    "request body",
+			pattern: "/upload",
+			method:  http.MethodPost,
+			target:  "/upload",
+			body:    "some data",
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+				w.WriteHeader(http.StatusCreated)
+			},
+			wantName: "POST /upload",
+			wantAttrs: []string{
+				"http.request.method=POST",
+				"url.path=/upload",
+				"client.address=192.0.2.1",
+				"user_agent.original=test-agent",
+				"http.route=/upload",
+				"http.request.body.size=9",
+				"http.response.body.size=0",
+				"http.response.status_code=201",
+			},
+		},
+		{
+			name:    "server error",
+			pattern: "GET /fail",
+			method:  http.MethodGet,
+			target:  "/fail",
+			respond: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			wantName: "GET /fail",
+			wantAttrs: []string{
+				"http.request.method=GET",
+				"url.path=/fail",
+				"client.address=192.0.2.1",
+				"user_agent.original=test-agent",
+				"http.route=/fail",
+				"http.response.body.size=0",
+				"http.response.status_code=503",
+			},
+			wantStatus: trace.StatusError,
+		},
+		{
+			name:    "client error is not a server error",
+			pattern: "GET /missing",
+			method:  http.MethodGet,
+			target:  "/missing",
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			},
+			wantName: "GET /missing",
+			wantAttrs: []string{
+				"http.request.method=GET",
+				"url.path=/missing",
+				"client.address=192.0.2.1",
+				"user_agent.original=test-agent",
+				"http.route=/missing",
+				"http.response.body.size=19",
+				"http.response.status_code=404",
+			},
+		},
+		{
+			name:     "implicit status",
+			pattern:  "GET example.com/empty",
+			method:   http.MethodGet,
+			target:   "http://example.com/empty",
+			respond:  func(http.ResponseWriter, *http.Request) {},
+			wantName: "GET /empty",
+			wantAttrs: []string{
+				"http.request.method=GET",
+				"url.path=/empty",
+				"client.address=192.0.2.1",
+				"user_agent.original=test-agent",
+				"http.route=/empty",
+				"http.response.body.size=0",
+				"http.response.status_code=200",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var span *trace.Span
+			mux := http.NewServeMux()
+			mux.HandleFunc(tt.pattern, func(w http.ResponseWriter, r *http.Request) {
+				span = trace.SpanFromContext(r.Context())
+				tt.respond(w, r)
+			})
+			h := tracehttp.NewHandler(mux)
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, tt.target, body)
+			req.Header.Set("User-Agent", "test-agent")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if span == nil {
+				t.Fatalf("no span in request context")
+			}
+			if span.IsRecording() {
+				t.Errorf("span should end after the handler returns")
+			}
+			difftest.AssertSame(t, "span name mismatch", tt.wantName, span.Name())
+			difftest.AssertSame(t, "span kind mismatch", "Server", span.Kind().String())
+			difftest.AssertSame(t, "span attrs mismatch", tt.wantAttrs, attrStrings(span.Attrs()))
+			difftest.AssertSame(t, "span status mismatch", uint8(tt.wantStatus), uint8(span.Status().Code))
+		})
+	}
+}
+
+func TestNewHandler_Propagation(t *testing.T) {
+	var span *trace.Span
+	h := tracehttp.NewHandler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		span = trace.SpanFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("Tracestate", "foo=1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	difftest.AssertSame(t, "span name mismatch", "GET", span.Name())
+	difftest.AssertSame(t, "trace ID mismatch", "4bf92f3577b34da6a3ce929d0e0e4736", span.Context().TraceID.String())
+	difftest.AssertSame(t, "parent span ID mismatch", "00f067aa0ba902b7", span.Parent().SpanID.String())
+	difftest.AssertSame(t, "tracestate mismatch", "foo=1", span.Context().State.String())
+	difftest.AssertSame(t, "remote parent mismatch", true, span.Parent().Remote)
+}
+
+func TestNewHandler_Panic(t *testing.T) {
+	var span *trace.Span
+	h := tracehttp.NewHandler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		span = trace.SpanFromContext(r.Context())
+		panic("boom")
+	}))
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("want re-panic with boom, got %v", p)
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	if span.IsRecording() {
+		t.Errorf("span should end after the handler panics")
+	}
+	difftest.AssertSame(t, "span status mismatch", uint8(trace.StatusError), uint8(span.Status().Code))
+	difftest.AssertSame(t, "span status description mismatch", "panic: boom", span.Status().Description)
+}
+
+func TestNewHandler_ResponseWriterInterfaces(t *testing.T) {
+	srv := httptest.NewServer(tracehttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Errorf("wrapped writer does not implement http.Flusher")
+		}
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Errorf("wrapped writer does not implement http.Hijacker")
+		}
+		rf, ok := w.(io.ReaderFrom)
+		if !ok {
+			t.Errorf("wrapped writer does not implement io.ReaderFrom")
+			return
+		}
+		_, _ = rf.ReadFrom(bytes.NewReader([]byte("read from")))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("flush with response controller: %v", err)
+		}
+	})))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	difftest.AssertSame(t, "body mismatch", "read from", string(got))
+}
+
+func attrStrings(attrs []trace.Attr) []string {
+	strs := make([]string, len(attrs))
+	for i, attr := range attrs {
+		strs[i] = attr.String()
+	}
+	return strs
+}
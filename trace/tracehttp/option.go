@@ -0,0 +1,55 @@
+package tracehttp
+
+import "github.com/jschaf/observe/trace"
+
+// Semantic convention attribute keys.
+// https://opentelemetry.io/docs/specs/semconv/http/http-spans/
+const (
+	keyClientAddress      = "client.address"
+	keyRequestMethod      = "http.request.method"
+	keyRequestBodySize    = "http.request.body.size"
+	keyResendCount        = "http.request.resend_count"
+	keyResponseBodySize   = "http.response.body.size"
+	keyResponseStatusCode = "http.response.status_code"
+	keyRoute              = "http.route"
+	keyServerAddress      = "server.address"
+	keyURLFull            = "url.full"
+	keyURLPath            = "url.path"
+	keyUserAgent          = "user_agent.original"
+)
+
+type config struct {
+	tracer      *trace.Tracer
+	clientTrace bool
+}
+
+// Option configures a handler or transport.
+type Option func(config) config
+
+func newConfig(opts []Option) config {
+	cfg := config{}
+	for _, opt := range opts {
+		cfg = opt(cfg)
+	}
+	if cfg.tracer == nil {
+		cfg.tracer = &trace.Tracer{}
+	}
+	return cfg
+}
+
+// WithTracer sets the tracer used to start spans.
+func WithTracer(t *trace.Tracer) Option {
+	return func(cfg config) config {
+		cfg.tracer = t
+		return cfg
+	}
+}
+
+// WithClientTrace records [net/http/httptrace.ClientTrace] events, like DNS lookups
+// and connection setup, as span events on client spans.
+func WithClientTrace() Option {
+	return func(cfg config) config {
+		cfg.clientTrace = true
+		return cfg
+	}
+}
@@ -0,0 +1,202 @@
+package tracehttp
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jschaf/observe/trace"
+	"github.com/jschaf/observe/trace/propagate"
+)
+
+type transport struct {
+	base        http.RoundTripper
+	tracer      *trace.Tracer
+	clientTrace bool
+}
+
+// NewTransport wraps base to start a client span for each request and inject
+// the span into the traceparent and tracestate headers. If base is nil, uses
+// [http.DefaultTransport].
+//
+// The span ends when the response body is fully read or closed, so callers
+// must close the body as required by [http.Client.Do].
+func NewTransport(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	cfg := newConfig(opts)
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, tracer: cfg.tracer, clientTrace: cfg.clientTrace}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attrs := []trace.Attr{
+		trace.String(keyRequestMethod, req.Method),
+		trace.String(keyURLFull, redactURL(req.URL)),
+		trace.String(keyServerAddress, req.URL.Hostname()),
+	}
+	if n := resendCount(req); n > 0 {
+		attrs = append(attrs, trace.Int(keyResendCount, n))
+	}
+	ctx, span := t.tracer.Start(req.Context(), req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttrs(attrs...),
+	)
+	if t.clientTrace {
+		ctx = httptrace.WithClientTrace(ctx, newClientTrace(&span))
+	}
+
+	req = req.Clone(ctx) // a RoundTripper must not modify the request
+	propagate.HTTPHeader(req.Header).InjectContext(span.Context())
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.SetStatus(trace.StatusError, err.Error())
+		span.End()
+		return nil, err //nolint:wrapcheck // transparent wrapper
+	}
+
+	span.SetAttrs(trace.Int(keyResponseStatusCode, resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(trace.StatusError, http.StatusText(resp.StatusCode))
+	}
+	if resp.Body == nil || resp.Body == http.NoBody || resp.StatusCode == http.StatusSwitchingProtocols {
+		// A 101 body is a writable connection, so don't wrap it.
+		span.End()
+		return resp, nil
+	}
+	resp.Body = &spanBody{rc: resp.Body, span: span}
+	return resp, nil
+}
+
+// spanBody ends the span when the response body is fully read or closed.
+type spanBody struct {
+	rc   io.ReadCloser
+	span trace.Span
+	n    atomic.Int64
+	once sync.Once
+}
+
+func (b *spanBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.n.Add(int64(n))
+	if err != nil {
+		b.end(err)
+	}
+	return n, err //nolint:wrapcheck // must return io.EOF unwrapped
+}
+
+func (b *spanBody) Close() error {
+	err := b.rc.Close()
+	b.end(nil)
+	return err //nolint:wrapcheck // transparent wrapper
+}
+
+func (b *spanBody) end(err error) {
+	b.once.Do(func() {
+		if err != nil && err != io.EOF { //nolint:errorlint // io.EOF is never wrapped by Read
+			b.span.SetStatus(trace.StatusError, err.Error())
+		}
+		b.span.SetAttrs(trace.Int64(keyResponseBodySize, b.n.Load()))
+		b.span.End()
+	})
+}
+
+// redactURL returns the URL with credentials and query values replaced by
+// "REDACTED", since they often contain secrets like signatures or tokens.
+func redactURL(u *url.URL) string {
+	if u.User == nil && u.RawQuery == "" {
+		return u.String()
+	}
+	redacted := *u
+	if u.User != nil {
+		if _, hasPass := u.User.Password(); hasPass {
+			redacted.User = url.UserPassword("REDACTED", "REDACTED")
+		} else {
+			redacted.User = url.User("REDACTED")
+		}
+	}
+	if u.RawQuery != "" {
+		sb := strings.Builder{}
+		for param := range strings.SplitSeq(u.RawQuery, "&") {
+			if sb.Len() > 0 {
+				sb.WriteByte('&')
+			}
+			key, _, hasVal := strings.Cut(param, "=")
+			sb.WriteString(key)
+			if hasVal {
+				sb.WriteString("=REDACTED")
+			}
+		}
+		redacted.RawQuery = sb.String()
+	}
+	return redacted.String()
+}
+
+type resendCtxKey struct{}
+
+// ContextWithResendCount returns a copy of ctx that counts the requests sent
+// with it. Use it in retry loops so that each retry records its attempt
+// number in the http.request.resend_count attribute.
+func ContextWithResendCount(ctx context.Context) context.Context {
+	return context.WithValue(ctx, resendCtxKey{}, new(atomic.Int64))
+}
+
+// resendCount returns the number of times the request was sent before. With
+// a context from ContextWithResendCount, counts every request sent with the
+// context, including retries and redirects. Otherwise, counts the redirects
+// that led to the request.
+func resendCount(req *http.Request) int {
+	if counter, ok := req.Context().Value(resendCtxKey{}).(*atomic.Int64); ok {
+		return int(counter.Add(1) - 1)
+	}
+	n := 0
+	for resp := req.Response; resp != nil && resp.Request != nil; resp = resp.Request.Response {
+		n++
+	}
+	return n
+}
+
+// newClientTrace returns a ClientTrace that records events on the span.
+func newClientTrace(span *trace.Span) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			span.AddEvent("http.dns.start", trace.String("net.host.name", info.Host))
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			span.AddEvent("http.dns.done", errAttrs(info.Err)...)
+		},
+		ConnectStart: func(network, addr string) {
+			span.AddEvent("http.connect.start", trace.String("network.transport", network), trace.String("network.peer.address", addr))
+		},
+		ConnectDone: func(network, addr string, err error) {
+			attrs := append(errAttrs(err), trace.String("network.transport", network), trace.String("network.peer.address", addr))
+			span.AddEvent("http.connect.done", attrs...)
+		},
+		TLSHandshakeStart: func() {
+			span.AddEvent("http.tls.start")
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			span.AddEvent("http.tls.done", errAttrs(err)...)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			span.AddEvent("http.conn.got", trace.Bool("reused", info.Reused))
+		},
+		GotFirstResponseByte: func() {
+			span.AddEvent("http.first_byte")
+		},
+	}
+}
+
+func errAttrs(err error) []trace.Attr {
+	if err == nil {
+		return nil
+	}
+	return []trace.Attr{trace.String("error", err.Error())}
+}
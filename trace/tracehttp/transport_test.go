@@ -0,0 +1,243 @@
+package tracehttp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+	"github.com/jschaf/observe/trace"
+	"github.com/jschaf/observe/trace/propagate"
+	"github.com/jschaf/observe/trace/tracehttp"
+)
+
+func TestNewTransport(t *testing.T) {
+	var gotParent trace.Context
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotParent = propagate.HTTPHeader(r.Header).ExtractContext()
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_, _ = io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantURL    string
+		wantStatus trace.StatusCode
+		wantCode   string
+	}{
+		{
+			name:     "ok",
+			path:     "/ok",
+			wantURL:  srv.URL + "/ok",
+			wantCode: "200",
+		},
+		{
+			name:     "redacts query values",
+			path:     "/ok?token=secret&flag&sig=abc",
+			wantURL:  srv.URL + "/ok?token=REDACTED&flag&sig=REDACTED",
+			wantCode: "200",
+		},
+		{
+			name:       "server error",
+			path:       "/fail",
+			wantURL:    srv.URL + "/fail",
+			wantStatus: trace.StatusError,
+			wantCode:   "500",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &spanRecorder{}
+			client := &http.Client{Transport: tracehttp.NewTransport(srv.Client().Transport,
+				tracehttp.WithTracer(trace.NewTracer(rec)))}
+
+			resp, err := client.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			if len(rec.ended()) > 0 {
+				t.Errorf("span should not end before reading the body")
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("read body: %v", err)
+			}
+			_ = resp.Body.Close()
+
+			spans := rec.ended()
+			if len(spans) != 1 {
+				t.Fatalf("want 1 ended span, got %d", len(spans))
+			}
+			span := spans[0]
+			difftest.AssertSame(t, "span name mismatch", "GET", span.Name())
+			difftest.AssertSame(t, "span kind mismatch", "Client", span.Kind().String())
+			difftest.AssertSame(t, "injected span ID mismatch", span.Context().SpanID.String(), gotParent.SpanID.String())
+			difftest.AssertSame(t, "span status mismatch", uint8(tt.wantStatus), uint8(span.Status().Code))
+			attrs := attrMap(span.Attrs())
+			difftest.AssertSame(t, "url.full mismatch", tt.wantURL, attrs["url.full"])
+			difftest.AssertSame(t, "http.request.method mismatch", "GET", attrs["http.request.method"])
+			difftest.AssertSame(t, "http.response.status_code mismatch", tt.wantCode, attrs["http.response.status_code"])
+			difftest.AssertSame(t, "http.response.body.size mismatch", "5", attrs["http.response.body.size"])
+			difftest.AssertSame(t, "body mismatch", "hello", string(body))
+		})
+	}
+}
+
+func TestNewTransport_ChildOfContextSpan(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	rec := &spanRecorder{}
+	tr := trace.NewTracer(rec)
+	client := &http.Client{Transport: tracehttp.NewTransport(srv.Client().Transport, tracehttp.WithTracer(tr))}
+	ctx, parent := tr.Start(t.Context(), "parent")
+	doGet(t, client, ctx, srv.URL)
+	parent.End()
+
+	spans := rec.ended()
+	if len(spans) != 2 {
+		t.Fatalf("want 2 ended spans, got %d", len(spans))
+	}
+	difftest.AssertSame(t, "client parent mismatch", parent.Context().SpanID.String(), spans[0].Parent().SpanID.String())
+}
+
+func TestNewTransport_ResendCount(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/done", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	rec := &spanRecorder{}
+	client := &http.Client{Transport: tracehttp.NewTransport(srv.Client().Transport,
+		tracehttp.WithTracer(trace.NewTracer(rec)))}
+
+	ctx := tracehttp.ContextWithResendCount(t.Context())
+	doGet(t, client, ctx, srv.URL+"/done")     // first attempt
+	doGet(t, client, ctx, srv.URL+"/redirect") // retry and redirect
+
+	var got []string
+	for _, span := range rec.ended() {
+		got = append(got, attrMap(span.Attrs())["http.request.resend_count"])
+	}
+	difftest.AssertSame(t, "resend counts mismatch", []string{"", "1", "2"}, got)
+}
+
+func TestNewTransport_ResendCount_Redirect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/done", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	rec := &spanRecorder{}
+	client := &http.Client{Transport: tracehttp.NewTransport(srv.Client().Transport,
+		tracehttp.WithTracer(trace.NewTracer(rec)))}
+
+	doGet(t, client, t.Context(), srv.URL+"/redirect")
+
+	var got []string
+	for _, span := range rec.ended() {
+		got = append(got, attrMap(span.Attrs())["http.request.resend_count"])
+	}
+	difftest.AssertSame(t, "resend counts mismatch", []string{"", "1"}, got)
+}
+
+func TestNewTransport_Error(t *testing.T) {
+	rec := &spanRecorder{}
+	wantErr := errors.New("dial failed")
+	base := roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, wantErr })
+	client := &http.Client{Transport: tracehttp.NewTransport(base, tracehttp.WithTracer(trace.NewTracer(rec)))}
+
+	_, err := client.Get("http://example.com")
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("want error %v, got %v", wantErr, err)
+	}
+	spans := rec.ended()
+	if len(spans) != 1 {
+		t.Fatalf("want 1 ended span, got %d", len(spans))
+	}
+	difftest.AssertSame(t, "span status mismatch", uint8(trace.StatusError), uint8(spans[0].Status().Code))
+	difftest.AssertSame(t, "span status description mismatch", "dial failed", spans[0].Status().Description)
+}
+
+func TestNewTransport_ClientTrace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	rec := &spanRecorder{}
+	client := &http.Client{Transport: tracehttp.NewTransport(srv.Client().Transport,
+		tracehttp.WithTracer(trace.NewTracer(rec)), tracehttp.WithClientTrace())}
+	doGet(t, client, t.Context(), srv.URL)
+
+	spans := rec.ended()
+	if len(spans) != 1 {
+		t.Fatalf("want 1 ended span, got %d", len(spans))
+	}
+	var names []string
+	for _, ev := range spans[0].Events() {
+		names = append(names, ev.Name)
+	}
+	for _, want := range []string{"http.connect.start", "http.connect.done", "http.conn.got", "http.first_byte"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("missing event %q in %v", want, names)
+		}
+	}
+}
+
+func doGet(t *testing.T, client *http.Client, ctx context.Context, url string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("do request: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// spanRecorder is a span processor that records ended spans.
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*trace.Span
+}
+
+func (r *spanRecorder) OnStart(context.Context, *trace.Span) {}
+
+func (r *spanRecorder) OnEnd(span *trace.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func (r *spanRecorder) ended() []*trace.Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.spans)
+}
+
+func attrMap(attrs []trace.Attr) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		m[attr.Key] = attr.Value.String()
+	}
+	return m
+}
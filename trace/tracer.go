@@ -7,10 +7,29 @@ import (
 	"github.com/jschaf/observe/internal/epoch"
 )
 
-type Tracer struct{}
+// Tracer starts spans. The zero value is a Tracer with no span processors.
+type Tracer struct {
+	processors []SpanProcessor
+}
+
+// NewTracer returns a Tracer that sends started and ended spans to the
+// processors in order.
+func NewTracer(processors ...SpanProcessor) *Tracer {
+	return &Tracer{processors: processors}
+}
+
+// spanProcessors returns the span processors of t. Returns nil if t is nil.
+func (t *Tracer) spanProcessors() []SpanProcessor {
+	if t == nil {
+		return nil
+	}
+	return t.processors
+}
 
 type startConfig struct {
 	startTime epoch.Nanos
+	kind      SpanKind
+	attrs     []Attr
 }
 
 type SpanStartOption func(startConfig) startConfig
@@ -22,7 +41,27 @@ func WithStartTime(t time.Time) SpanStartOption {
 	}
 }
 
+// WithSpanKind sets the kind of the span. Defaults to SpanKindInternal.
+func WithSpanKind(kind SpanKind) SpanStartOption {
+	return func(cfg startConfig) startConfig {
+		cfg.kind = kind
+		return cfg
+	}
+}
+
+// WithAttrs sets attributes on the span when it starts.
+func WithAttrs(attrs ...Attr) SpanStartOption {
+	return func(cfg startConfig) startConfig {
+		cfg.attrs = append(cfg.attrs, attrs...)
+		return cfg
+	}
+}
+
 // Start starts a Span and returns a new context containing the Span.
+//
+// The span is a child of the span in ctx, if any, or else the remote span
+// context in ctx, added with [ContextWithRemote]. Otherwise, the span is the
+// root of a new trace.
 func (t *Tracer) Start(ctx context.Context, name string, opts ...SpanStartOption) (context.Context, Span) {
 	cfg := startConfig{}
 	for _, opt := range opts {
@@ -32,12 +71,33 @@ func (t *Tracer) Start(ctx context.Context, name string, opts ...SpanStartOption
 		cfg.startTime = epoch.NanosNow()
 	}
 
+	parent := SpanFromContext(ctx).Context()
+	if !parent.IsValid() {
+		parent = RemoteFromContext(ctx)
+	}
+	sc := Context{SpanID: genSpanID()}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.State = parent.State
+		sc.Flags = parent.Flags
+	} else {
+		sc.TraceID = genTraceID()
+		sc.Flags = FlagsSampled
+	}
+
 	span := Span{
-		name:      name,
 		tracer:    t,
+		sc:        sc,
+		parent:    parent,
+		kind:      cfg.kind,
 		start:     cfg.startTime,
 		lifecycle: newLifecycle(),
+		data:      &spanData{name: name},
+	}
+	span.SetAttrs(cfg.attrs...)
+	for _, p := range t.spanProcessors() {
+		p.OnStart(ctx, &span)
 	}
 
-	return ctx, span
+	return ContextWithSpan(ctx, &span), span
 }
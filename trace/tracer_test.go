@@ -1,6 +1,7 @@
 package trace_test
 
 import (
+	"context"
 	"strconv"
 	"sync"
 	"testing"
@@ -39,6 +40,106 @@ func TestTracer_Start(t *testing.T) {
 	})
 }
 
+func TestTracer_Start_Parent(t *testing.T) {
+	tr := &trace.Tracer{}
+
+	t.Run("root", func(t *testing.T) {
+		ctx, span := tr.Start(t.Context(), "root")
+		if !span.Context().IsValid() {
+			t.Errorf("root span context should be valid")
+		}
+		if span.Parent().IsValid() {
+			t.Errorf("root span should not have a parent; got %v", span.Parent())
+		}
+		if got := trace.SpanFromContext(ctx); got.Context() != span.Context() {
+			t.Errorf("SpanFromContext() = %v, want %v", got.Context(), span.Context())
+		}
+	})
+
+	t.Run("child", func(t *testing.T) {
+		ctx, parent := tr.Start(t.Context(), "parent")
+		_, child := tr.Start(ctx, "child")
+		difftest.AssertSame(t, "child trace ID mismatch", parent.Context().TraceID.String(), child.Context().TraceID.String())
+		difftest.AssertSame(t, "child parent span ID mismatch", parent.Context().SpanID.String(), child.Parent().SpanID.String())
+		if child.Context().SpanID == parent.Context().SpanID {
+			t.Errorf("child span ID should differ from parent span ID")
+		}
+	})
+
+	t.Run("remote", func(t *testing.T) {
+		traceID, _ := trace.ParseTraceID("4bf92f3577b34da6a3ce929d0e0e4736")
+		spanID, _ := trace.ParseSpanID("00f067aa0ba902b7")
+		remote := trace.Context{TraceID: traceID, SpanID: spanID}
+		ctx := trace.ContextWithRemote(t.Context(), remote)
+		_, span := tr.Start(ctx, "child")
+		difftest.AssertSame(t, "trace ID mismatch", traceID.String(), span.Context().TraceID.String())
+		difftest.AssertSame(t, "parent span ID mismatch", spanID.String(), span.Parent().SpanID.String())
+		difftest.AssertSame(t, "parent remote mismatch", true, span.Parent().Remote)
+		difftest.AssertSame(t, "sampled mismatch", false, span.Context().IsSampled())
+	})
+
+	t.Run("no span in context", func(t *testing.T) {
+		span := trace.SpanFromContext(t.Context())
+		if span != nil {
+			t.Errorf("SpanFromContext() = %v, want nil", span)
+		}
+		if span.IsRecording() {
+			t.Errorf("nil span should not be recording")
+		}
+	})
+}
+
+func TestTracer_Start_Options(t *testing.T) {
+	span := startTestSpan(t,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttrs(trace.String("foo", "bar")),
+	)
+	difftest.AssertSame(t, "kind mismatch", "Server", span.Kind().String())
+	difftest.AssertSame(t, "attrs mismatch", []string{"foo=bar"}, attrStrings(span.Attrs()))
+}
+
+func TestTracer_SpanProcessor(t *testing.T) {
+	rec := &recordingProcessor{}
+	tr := trace.NewTracer(rec)
+	ctx, parent := tr.Start(t.Context(), "parent")
+	_, child := tr.Start(ctx, "child")
+	child.End()
+	child.End() // ignored
+	parent.End()
+
+	difftest.AssertSame(t, "started spans mismatch", []string{"parent", "child"}, rec.started)
+	difftest.AssertSame(t, "ended spans mismatch", []string{"child", "parent"}, rec.ended)
+}
+
+type recordingProcessor struct {
+	mu      sync.Mutex
+	started []string
+	ended   []string
+}
+
+func (r *recordingProcessor) OnStart(_ context.Context, span *trace.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = append(r.started, span.Name())
+}
+
+func (r *recordingProcessor) OnEnd(span *trace.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if span.IsRecording() {
+		panic("span should not be recording in OnEnd")
+	}
+	r.ended = append(r.ended, span.Name())
+}
+
+func attrStrings(attrs []trace.Attr) []string {
+	strs := make([]string, len(attrs))
+	for i, attr := range attrs {
+		strs[i] = attr.String()
+	}
+	return strs
+}
+
 func startTestSpan(t *testing.T, opts ...trace.SpanStartOption) trace.Span {
 	t.Helper()
 	tr := &trace.Tracer{}
@@ -0,0 +1,323 @@
+package tracesql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+
+	"github.com/jschaf/observe/trace"
+)
+
+// conn wraps a driver.Conn. It implements the optional interfaces used by
+// database/sql, falling back to the same behavior as database/sql if the
+// wrapped conn doesn't implement them.
+type conn struct {
+	c   driver.Conn
+	cfg config
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	ctx, span := c.cfg.startSpan(ctx, "sql.prepare", query)
+	var s driver.Stmt
+	var err error
+	if pc, ok := c.c.(driver.ConnPrepareContext); ok {
+		s, err = pc.PrepareContext(ctx, query)
+	} else {
+		s, err = c.c.Prepare(query)
+	}
+	endSpan(&span, err)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // transparent wrapper
+	}
+	return &stmt{s: s, conn: c, query: query, cfg: c.cfg}, nil
+}
+
+func (c *conn) Close() error {
+	return c.c.Close() //nolint:wrapcheck // transparent wrapper
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	// Commit and Rollback don't take a context, so keep the caller's context
+	// to parent their spans.
+	txCtx := ctx
+	ctx, span := c.cfg.startSpan(ctx, "sql.begin", "")
+	var dtx driver.Tx
+	var err error
+	if bc, ok := c.c.(driver.ConnBeginTx); ok {
+		dtx, err = bc.BeginTx(ctx, opts)
+	} else {
+		switch {
+		case opts.Isolation != driver.IsolationLevel(0):
+			err = errors.New("driver does not support non-default isolation level")
+		case opts.ReadOnly:
+			err = errors.New("driver does not support read-only transactions")
+		default:
+			dtx, err = c.c.Begin() //nolint:staticcheck // fallback for old drivers
+		}
+	}
+	endSpan(&span, err)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // transparent wrapper
+	}
+	return &tx{tx: dtx, ctx: txCtx, cfg: c.cfg}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.c.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip // database/sql falls back to Prepare
+	}
+	ctx, span := c.cfg.startSpan(ctx, "sql.exec", query)
+	res, err := ec.ExecContext(ctx, query, args)
+	recordResult(&span, res, err)
+	endSpan(&span, err)
+	return res, err //nolint:wrapcheck // transparent wrapper
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.c.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip // database/sql falls back to Prepare
+	}
+	ctx, span := c.cfg.startSpan(ctx, "sql.query", query)
+	r, err := qc.QueryContext(ctx, query, args)
+	if err != nil {
+		endSpan(&span, err)
+		return nil, err //nolint:wrapcheck // transparent wrapper
+	}
+	return &rows{r: r, span: span}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	p, ok := c.c.(driver.Pinger)
+	if !ok {
+		return nil
+	}
+	ctx, span := c.cfg.startSpan(ctx, "sql.ping", "")
+	err := p.Ping(ctx)
+	endSpan(&span, err)
+	return err //nolint:wrapcheck // transparent wrapper
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if sr, ok := c.c.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx) //nolint:wrapcheck // transparent wrapper
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.c.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.c.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv) //nolint:wrapcheck // transparent wrapper
+	}
+	return driver.ErrSkip // use the default conversion
+}
+
+// stmt wraps a driver.Stmt.
+type stmt struct {
+	s     driver.Stmt
+	conn  *conn // the conn that prepared the stmt
+	query string
+	cfg   config
+}
+
+func (s *stmt) Close() error {
+	return s.s.Close() //nolint:wrapcheck // transparent wrapper
+}
+
+func (s *stmt) NumInput() int { return s.s.NumInput() }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := s.cfg.startSpan(ctx, "sql.exec", s.query)
+	var res driver.Result
+	var err error
+	if ec, ok := s.s.(driver.StmtExecContext); ok {
+		res, err = ec.ExecContext(ctx, args)
+	} else {
+		var vals []driver.Value
+		vals, err = namedValuesToValues(args)
+		if err == nil {
+			res, err = s.s.Exec(vals) //nolint:staticcheck // fallback for old drivers
+		}
+	}
+	recordResult(&span, res, err)
+	endSpan(&span, err)
+	return res, err //nolint:wrapcheck // transparent wrapper
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := s.cfg.startSpan(ctx, "sql.query", s.query)
+	var r driver.Rows
+	var err error
+	if qc, ok := s.s.(driver.StmtQueryContext); ok {
+		r, err = qc.QueryContext(ctx, args)
+	} else {
+		var vals []driver.Value
+		vals, err = namedValuesToValues(args)
+		if err == nil {
+			r, err = s.s.Query(vals) //nolint:staticcheck // fallback for old drivers
+		}
+	}
+	if err != nil {
+		endSpan(&span, err)
+		return nil, err //nolint:wrapcheck // transparent wrapper
+	}
+	return &rows{r: r, span: span}, nil
+}
+
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := s.s.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv) //nolint:wrapcheck // transparent wrapper
+	}
+	// database/sql only checks the conn if the stmt isn't a checker.
+	return s.conn.CheckNamedValue(nv)
+}
+
+func (s *stmt) ColumnConverter(idx int) driver.ValueConverter {
+	if cc, ok := s.s.(driver.ColumnConverter); ok { //nolint:staticcheck // delegate for old drivers
+		return cc.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+// tx wraps a driver.Tx.
+type tx struct {
+	tx  driver.Tx
+	ctx context.Context //nolint:containedctx // Commit and Rollback lack a context
+	cfg config
+}
+
+func (t *tx) Commit() error {
+	_, span := t.cfg.startSpan(t.ctx, "sql.commit", "")
+	err := t.tx.Commit()
+	endSpan(&span, err)
+	return err //nolint:wrapcheck // transparent wrapper
+}
+
+func (t *tx) Rollback() error {
+	_, span := t.cfg.startSpan(t.ctx, "sql.rollback", "")
+	err := t.tx.Rollback()
+	endSpan(&span, err)
+	return err //nolint:wrapcheck // transparent wrapper
+}
+
+// rows wraps driver.Rows to count the rows iterated. The query span ends when
+// the rows close.
+type rows struct {
+	r     driver.Rows
+	span  trace.Span
+	count int64
+	err   error
+}
+
+func (r *rows) Columns() []string { return r.r.Columns() }
+
+func (r *rows) Next(dest []driver.Value) error {
+	err := r.r.Next(dest)
+	switch {
+	case err == nil:
+		r.count++
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+	return err //nolint:wrapcheck // must return io.EOF unwrapped
+}
+
+func (r *rows) Close() error {
+	err := r.r.Close()
+	r.span.SetAttrs(trace.Int64(keyRowsReturned, r.count))
+	endSpan(&r.span, errors.Join(r.err, err))
+	return err //nolint:wrapcheck // transparent wrapper
+}
+
+func (r *rows) HasNextResultSet() bool {
+	if rs, ok := r.r.(driver.RowsNextResultSet); ok {
+		return rs.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *rows) NextResultSet() error {
+	if rs, ok := r.r.(driver.RowsNextResultSet); ok {
+		return rs.NextResultSet() //nolint:wrapcheck // must return io.EOF unwrapped
+	}
+	return io.EOF
+}
+
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	if ct, ok := r.r.(driver.RowsColumnTypeScanType); ok {
+		return ct.ColumnTypeScanType(index)
+	}
+	return reflect.TypeFor[any]()
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if ct, ok := r.r.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return ct.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *rows) ColumnTypeLength(index int) (int64, bool) {
+	if ct, ok := r.r.(driver.RowsColumnTypeLength); ok {
+		return ct.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if ct, ok := r.r.(driver.RowsColumnTypeNullable); ok {
+		return ct.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if ct, ok := r.r.(driver.RowsColumnTypePrecisionScale); ok {
+		return ct.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+// recordResult records the rows affected by an exec on the span.
+func recordResult(span *trace.Span, res driver.Result, err error) {
+	if err != nil || res == nil {
+		return
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		span.SetAttrs(trace.Int64(keyRowsAffected, n))
+	}
+}
+
+func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
@@ -0,0 +1,167 @@
+// Package tracesql instruments database/sql drivers with spans.
+package tracesql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/jschaf/observe/trace"
+)
+
+// Semantic convention attribute keys.
+// https://opentelemetry.io/docs/specs/semconv/database/database-spans/
+const (
+	keySystem       = "db.system"
+	keyStatement    = "db.statement"
+	keyOperation    = "db.operation"
+	keyRowsAffected = "db.rows_affected"
+	keyRowsReturned = "db.rows_returned"
+)
+
+type config struct {
+	tracer *trace.Tracer
+	system string
+}
+
+// Option configures a wrapped driver.
+type Option func(config) config
+
+// WithTracer sets the tracer used to start spans.
+func WithTracer(t *trace.Tracer) Option {
+	return func(cfg config) config {
+		cfg.tracer = t
+		return cfg
+	}
+}
+
+// WithSystem sets the db.system attribute, like "postgresql" or "sqlite".
+// The system also decides how db.statement reads quotes: only "postgresql"
+// and "cockroachdb" keep double-quoted identifiers, since other databases,
+// like MySQL, may use double quotes for string literals.
+func WithSystem(system string) Option {
+	return func(cfg config) config {
+		cfg.system = system
+		return cfg
+	}
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{}
+	for _, opt := range opts {
+		cfg = opt(cfg)
+	}
+	if cfg.tracer == nil {
+		cfg.tracer = &trace.Tracer{}
+	}
+	return cfg
+}
+
+// startSpan starts a client span for a database operation. query is the
+// unsanitized statement text, if any.
+func (cfg config) startSpan(ctx context.Context, name, query string) (context.Context, trace.Span) {
+	attrs := make([]trace.Attr, 0, 3)
+	if cfg.system != "" {
+		attrs = append(attrs, trace.String(keySystem, cfg.system))
+	}
+	if query != "" {
+		attrs = append(attrs, trace.String(keyStatement, sanitize(query, cfg.system)))
+		if op := operation(query); op != "" {
+			attrs = append(attrs, trace.String(keyOperation, op))
+		}
+	}
+	return cfg.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttrs(attrs...))
+}
+
+// endSpan records err on the span and ends it.
+func endSpan(span *trace.Span, err error) {
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		span.SetStatus(trace.StatusError, err.Error())
+	}
+	span.End()
+}
+
+// Driver wraps a driver.Driver to start a span for each database operation.
+// Register it with [database/sql.Register], or use [NewConnector] with
+// [database/sql.OpenDB].
+type Driver struct {
+	d   driver.Driver
+	cfg config
+}
+
+// WrapDriver wraps d to start a span for each database operation.
+func WrapDriver(d driver.Driver, opts ...Option) *Driver {
+	return &Driver{d: d, cfg: newConfig(opts)}
+}
+
+// Open implements driver.Driver.
+func (d *Driver) Open(name string) (driver.Conn, error) {
+	c, err := d.d.Open(name)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // transparent wrapper
+	}
+	return &conn{c: c, cfg: d.cfg}, nil
+}
+
+// OpenConnector implements driver.DriverContext.
+func (d *Driver) OpenConnector(name string) (driver.Connector, error) {
+	dc, ok := d.d.(driver.DriverContext)
+	if !ok {
+		return &connector{c: dsnConnector{name: name, d: d.d}, d: d, cfg: d.cfg}, nil
+	}
+	c, err := dc.OpenConnector(name)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // transparent wrapper
+	}
+	return &connector{c: c, d: d, cfg: d.cfg}, nil
+}
+
+// NewConnector wraps c to start a span for each database operation.
+func NewConnector(c driver.Connector, opts ...Option) driver.Connector {
+	cfg := newConfig(opts)
+	return &connector{c: c, d: &Driver{d: c.Driver(), cfg: cfg}, cfg: cfg}
+}
+
+type connector struct {
+	c   driver.Connector
+	d   *Driver
+	cfg config
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	ctx, span := c.cfg.startSpan(ctx, "sql.connect", "")
+	dc, err := c.c.Connect(ctx)
+	endSpan(&span, err)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // transparent wrapper
+	}
+	return &conn{c: dc, cfg: c.cfg}, nil
+}
+
+func (c *connector) Driver() driver.Driver { return c.d }
+
+// dsnConnector is a driver.Connector for drivers that don't implement
+// driver.DriverContext, like database/sql does internally.
+type dsnConnector struct {
+	name string
+	d    driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.d.Open(c.name) //nolint:wrapcheck // transparent wrapper
+}
+
+func (c dsnConnector) Driver() driver.Driver { return c.d }
+
+// namedValuesToValues converts named values for drivers that don't support
+// named parameters.
+func namedValuesToValues(named []driver.NamedValue) ([]driver.Value, error) {
+	args := make([]driver.Value, len(named))
+	for i, nv := range named {
+		if nv.Name != "" {
+			return nil, errors.New("driver does not support named parameters")
+		}
+		args[i] = nv.Value
+	}
+	return args, nil
+}
@@ -0,0 +1,365 @@
+package tracesql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+	"github.com/jschaf/observe/trace"
+	"github.com/jschaf/observe/trace/tracesql"
+)
+
+func TestConnector(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		name := "context driver"
+		if legacy {
+			name = "legacy driver"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Run("query", func(t *testing.T) {
+				db, rec := openTestDB(t, legacy)
+				rows, err := db.QueryContext(t.Context(), "SELECT id FROM users WHERE name = 'alice' AND age > 30")
+				if err != nil {
+					t.Fatalf("query: %v", err)
+				}
+				n := 0
+				for rows.Next() {
+					n++
+				}
+				if err := rows.Close(); err != nil {
+					t.Fatalf("close rows: %v", err)
+				}
+				difftest.AssertSame(t, "row count mismatch", 3, n)
+
+				span := rec.last(t, "sql.query")
+				attrs := attrMap(span.Attrs())
+				difftest.AssertSame(t, "db.system mismatch", "fakedb", attrs["db.system"])
+				difftest.AssertSame(t, "db.statement mismatch", "SELECT id FROM users WHERE name = ? AND age > ?", attrs["db.statement"])
+				difftest.AssertSame(t, "db.operation mismatch", "SELECT", attrs["db.operation"])
+				difftest.AssertSame(t, "db.rows_returned mismatch", "3", attrs["db.rows_returned"])
+				difftest.AssertSame(t, "span kind mismatch", "Client", span.Kind().String())
+			})
+
+			t.Run("exec", func(t *testing.T) {
+				db, rec := openTestDB(t, legacy)
+				_, err := db.ExecContext(t.Context(), "UPDATE users SET age = $1", 42)
+				if err != nil {
+					t.Fatalf("exec: %v", err)
+				}
+				span := rec.last(t, "sql.exec")
+				attrs := attrMap(span.Attrs())
+				difftest.AssertSame(t, "db.statement mismatch", "UPDATE users SET age = $1", attrs["db.statement"])
+				difftest.AssertSame(t, "db.rows_affected mismatch", "2", attrs["db.rows_affected"])
+				difftest.AssertSame(t, "span status mismatch", uint8(trace.StatusUnset), uint8(span.Status().Code))
+			})
+
+			t.Run("exec error", func(t *testing.T) {
+				db, rec := openTestDB(t, legacy)
+				_, err := db.ExecContext(t.Context(), "UPDATE fail")
+				if !errors.Is(err, errFake) {
+					t.Fatalf("want error %v, got %v", errFake, err)
+				}
+				// Legacy drivers fail when database/sql falls back to Prepare.
+				name := "sql.exec"
+				if legacy {
+					name = "sql.prepare"
+				}
+				span := rec.last(t, name)
+				difftest.AssertSame(t, "span status mismatch", uint8(trace.StatusError), uint8(span.Status().Code))
+			})
+
+			t.Run("prepared statement", func(t *testing.T) {
+				db, rec := openTestDB(t, legacy)
+				stmt, err := db.PrepareContext(t.Context(), "SELECT 1")
+				if err != nil {
+					t.Fatalf("prepare: %v", err)
+				}
+				defer stmt.Close()
+				var got int
+				if err := stmt.QueryRowContext(t.Context()).Scan(&got); err != nil {
+					t.Fatalf("query row: %v", err)
+				}
+				rec.last(t, "sql.prepare")
+				span := rec.last(t, "sql.query")
+				difftest.AssertSame(t, "db.statement mismatch", "SELECT ?", attrMap(span.Attrs())["db.statement"])
+			})
+
+			t.Run("transaction", func(t *testing.T) {
+				db, rec := openTestDB(t, legacy)
+				tr := trace.NewTracer()
+				ctx, parent := tr.Start(t.Context(), "parent")
+				tx, err := db.BeginTx(ctx, nil)
+				if err != nil {
+					t.Fatalf("begin: %v", err)
+				}
+				if _, err := tx.ExecContext(ctx, "DELETE FROM users"); err != nil {
+					t.Fatalf("exec: %v", err)
+				}
+				if err := tx.Commit(); err != nil {
+					t.Fatalf("commit: %v", err)
+				}
+				parent.End()
+
+				for _, name := range []string{"sql.begin", "sql.exec", "sql.commit"} {
+					span := rec.last(t, name)
+					difftest.AssertSame(t, name+" parent mismatch", parent.Context().SpanID.String(), span.Parent().SpanID.String())
+				}
+
+				tx, err = db.BeginTx(t.Context(), nil)
+				if err != nil {
+					t.Fatalf("begin: %v", err)
+				}
+				if err := tx.Rollback(); err != nil {
+					t.Fatalf("rollback: %v", err)
+				}
+				rec.last(t, "sql.rollback")
+			})
+		})
+	}
+}
+
+func TestConnector_PropagatesSpan(t *testing.T) {
+	db, rec := openTestDB(t, false)
+	_, err := db.ExecContext(t.Context(), "UPDATE users SET age = 1")
+	if err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	span := rec.last(t, "sql.exec")
+	difftest.AssertSame(t, "driver span mismatch", span.Context().SpanID.String(), lastDriverSpanID.get())
+}
+
+func TestWrapDriver(t *testing.T) {
+	rec := &spanRecorder{}
+	sql.Register("tracesql-fake", tracesql.WrapDriver(fakeDriver{},
+		tracesql.WithTracer(trace.NewTracer(rec)), tracesql.WithSystem("fakedb")))
+	db, err := sql.Open("tracesql-fake", "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	if err := db.PingContext(t.Context()); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	rec.last(t, "sql.ping")
+}
+
+func TestConnector_ConnNamedValueChecker(t *testing.T) {
+	rec := &spanRecorder{}
+	db := sql.OpenDB(tracesql.NewConnector(checkerConnector{}, tracesql.WithTracer(trace.NewTracer(rec))))
+	defer db.Close()
+
+	stmt, err := db.PrepareContext(t.Context(), "INSERT INTO points VALUES ($1)")
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.ExecContext(t.Context(), point{X: 1, Y: 2}); err != nil {
+		t.Fatalf("exec with custom arg type: %v", err)
+	}
+	rec.last(t, "sql.exec")
+}
+
+func openTestDB(t *testing.T, legacy bool) (*sql.DB, *spanRecorder) {
+	t.Helper()
+	rec := &spanRecorder{}
+	c := tracesql.NewConnector(fakeConnector{legacy: legacy},
+		tracesql.WithTracer(trace.NewTracer(rec)), tracesql.WithSystem("fakedb"))
+	db := sql.OpenDB(c)
+	t.Cleanup(func() { _ = db.Close() })
+	return db, rec
+}
+
+// spanRecorder is a span processor that records ended spans.
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*trace.Span
+}
+
+func (r *spanRecorder) OnStart(context.Context, *trace.Span) {}
+
+func (r *spanRecorder) OnEnd(span *trace.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+// last returns the last ended span with the name.
+func (r *spanRecorder) last(t *testing.T, name string) *trace.Span {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, span := range slices.Backward(r.spans) {
+		if span.Name() == name {
+			return span
+		}
+	}
+	t.Fatalf("no ended span named %q", name)
+	return nil
+}
+
+func attrMap(attrs []trace.Attr) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		m[attr.Key] = attr.Value.String()
+	}
+	return m
+}
+
+var errFake = errors.New("fake error")
+
+// lastDriverSpanID is the span ID in the context most recently passed to the
+// fake driver.
+var lastDriverSpanID = &syncString{}
+
+type syncString struct {
+	mu sync.Mutex
+	s  string
+}
+
+func (s *syncString) get() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.s
+}
+
+func (s *syncString) set(v string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.s = v
+}
+
+// fakeDriver is an in-process driver. Queries return three rows with a single
+// int column. Execs affect two rows. Statements containing "fail" return
+// errFake.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return &fakeCtxConn{}, nil }
+
+type fakeConnector struct {
+	legacy bool // if true, the conn only implements driver.Conn
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	if c.legacy {
+		return &fakeConn{}, nil
+	}
+	return &fakeCtxConn{}, nil
+}
+
+func (fakeConnector) Driver() driver.Driver { return fakeDriver{} }
+
+// fakeConn implements only driver.Conn.
+type fakeConn struct{}
+
+func (*fakeConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.Contains(query, "fail") {
+		return nil, errFake
+	}
+	return &fakeStmt{query: query}, nil
+}
+
+func (*fakeConn) Close() error { return nil }
+
+func (*fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+// fakeCtxConn implements the context interfaces of driver.Conn.
+type fakeCtxConn struct{ fakeConn }
+
+func (c *fakeCtxConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	lastDriverSpanID.set(trace.SpanFromContext(ctx).Context().SpanID.String())
+	s, err := c.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.(*fakeStmt).QueryContext(ctx, args) //nolint:forcetypeassert
+}
+
+func (c *fakeCtxConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	lastDriverSpanID.set(trace.SpanFromContext(ctx).Context().SpanID.String())
+	s, err := c.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.(*fakeStmt).ExecContext(ctx, args) //nolint:forcetypeassert
+}
+
+func (*fakeCtxConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (*fakeCtxConn) Ping(context.Context) error { return nil }
+
+// checkerConnector returns conns that check named values, like pgx, so that
+// prepared statements accept point args.
+type checkerConnector struct{}
+
+func (checkerConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeCheckerConn{}, nil
+}
+
+func (checkerConnector) Driver() driver.Driver { return fakeDriver{} }
+
+// point is an arg type only accepted by fakeCheckerConn.
+type point struct{ X, Y int }
+
+type fakeCheckerConn struct{ fakeCtxConn }
+
+func (*fakeCheckerConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if p, ok := nv.Value.(point); ok {
+		nv.Value = fmt.Sprintf("(%d,%d)", p.X, p.Y)
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+type fakeStmt struct {
+	query string
+}
+
+func (*fakeStmt) Close() error  { return nil }
+func (*fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), nil)
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), nil)
+}
+
+func (s *fakeStmt) ExecContext(context.Context, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(2), nil
+}
+
+func (s *fakeStmt) QueryContext(context.Context, []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{remaining: 3}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	remaining int
+}
+
+func (*fakeRows) Columns() []string { return []string{"id"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.remaining == 0 {
+		return io.EOF
+	}
+	dest[0] = int64(r.remaining)
+	r.remaining--
+	return nil
+}
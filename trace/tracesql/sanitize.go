@@ -0,0 +1,146 @@
+package tracesql
+
+import "strings"
+
+// sanitize replaces string and numeric literals in a SQL statement with "?",
+// and removes comments, so that span attributes don't record sensitive
+// values. String literals include Postgres dollar-quoted strings, like
+// $$it's$$ or $tag$it's$tag$. Keeps placeholders, like $1 or ?, and
+// backtick-quoted identifiers.
+//
+// The system is the db.system of the database, which decides how to read
+// quotes. For Postgres-like systems, keeps double-quoted identifiers and
+// reads backslash escapes only in escape strings, like E'\n'. For other
+// systems, like MySQL, double-quoted text may be a string literal so it's
+// replaced, and backslashes escape quotes in all string literals.
+func sanitize(query, system string) string {
+	postgres := system == "postgresql" || system == "cockroachdb"
+	sb := strings.Builder{}
+	sb.Grow(len(query))
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || (c == '"' && !postgres):
+			// String literal. Quotes are escaped by doubling, like 'it''s', or
+			// by a backslash, like 'it\'s', except in Postgres standard strings.
+			backslash := !postgres || isEscapePrefix(query, i)
+			i = skipQuoted(query, i, c, backslash)
+			sb.WriteByte('?')
+		case c == '"' || c == '`':
+			// Quoted identifier.
+			end := skipQuoted(query, i, c, false)
+			sb.WriteString(query[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			// Line comment, up to the newline.
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return sb.String()
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			// Block comment.
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return sb.String()
+			}
+			i += end + 4
+		case c == '$' && (i == 0 || !isIdentChar(query[i-1])) && dollarTagLen(query[i:]) > 0:
+			// Dollar-quoted string literal, like $tag$it's$tag$.
+			tag := query[i : i+dollarTagLen(query[i:])]
+			i += len(tag)
+			end := strings.Index(query[i:], tag)
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + len(tag)
+			}
+			sb.WriteByte('?')
+		case isDigit(c) && (i == 0 || !isIdentChar(query[i-1])):
+			// Numeric literal, like 42, 3.14, or 1e10.
+			for i < len(query) && (isDigit(query[i]) || query[i] == '.' ||
+				query[i] == 'e' || query[i] == 'E' || query[i] == 'x' ||
+				isHexLetter(query[i])) {
+				i++
+			}
+			sb.WriteByte('?')
+		case isIdentChar(c):
+			// Copy identifiers and placeholders whole, so digits in names like
+			// t1 or $1 aren't mistaken for literals.
+			start := i
+			for i < len(query) && isIdentChar(query[i]) {
+				i++
+			}
+			sb.WriteString(query[start:i])
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// skipQuoted returns the index after the text quoted by q starting at i, or
+// len(query) if unterminated. A doubled quote is an escaped quote and, if
+// backslash is true, so is a quote after a backslash.
+func skipQuoted(query string, i int, q byte, backslash bool) int {
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case q:
+			if i+1 < len(query) && query[i+1] == q {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// isEscapePrefix reports whether the string literal at i has the Postgres
+// escape string prefix, like E'it\'s'.
+func isEscapePrefix(query string, i int) bool {
+	return i > 0 && (query[i-1] == 'e' || query[i-1] == 'E') &&
+		(i == 1 || !isIdentChar(query[i-2]))
+}
+
+// dollarTagLen returns the length of the opening tag of a dollar-quoted
+// string at the start of s, like "$$" or "$tag$", or 0 if none. Tags follow
+// the rules for identifiers, so placeholders like $1 aren't tags.
+func dollarTagLen(s string) int {
+	if len(s) < 2 || s[0] != '$' || isDigit(s[1]) {
+		return 0
+	}
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return i + 1
+		case !isLetter(c) && !isDigit(c) && c != '_' && c < 0x80:
+			return 0
+		}
+	}
+	return 0
+}
+
+// operation returns the first keyword of a SQL statement in upper case, like
+// "SELECT". Returns an empty string if there is no keyword.
+func operation(query string) string {
+	query = strings.TrimLeft(query, " \t\r\n(")
+	end := 0
+	for end < len(query) && isLetter(query[end]) {
+		end++
+	}
+	return strings.ToUpper(query[:end])
+}
+
+func isDigit(c byte) bool     { return c >= '0' && c <= '9' }
+func isLetter(c byte) bool    { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isHexLetter(c byte) bool { return (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') }
+
+func isIdentChar(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '_' || c == '$' || c == '@' || c == ':' || c >= 0x80
+}
@@ -0,0 +1,71 @@
+package tracesql
+
+import (
+	"testing"
+
+	"github.com/jschaf/observe/internal/difftest"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name   string
+		system string
+		in     string
+		want   string
+	}{
+		{name: "empty", in: "", want: ""},
+		{name: "no literals", in: "SELECT id FROM users", want: "SELECT id FROM users"},
+		{name: "string literal", in: "SELECT * FROM users WHERE name = 'alice'", want: "SELECT * FROM users WHERE name = ?"},
+		{name: "escaped quote", in: "SELECT 'it''s', 'b'", want: "SELECT ?, ?"},
+		{name: "unterminated string", in: "SELECT 'abc", want: "SELECT ?"},
+		{name: "integer", in: "SELECT * FROM t WHERE id = 42", want: "SELECT * FROM t WHERE id = ?"},
+		{name: "negative float", in: "UPDATE t SET x = -3.14e10", want: "UPDATE t SET x = -?"},
+		{name: "hex", in: "SELECT 0xDEADbeef", want: "SELECT ?"},
+		{name: "identifiers with digits", in: "SELECT t1.col2 FROM t1", want: "SELECT t1.col2 FROM t1"},
+		{name: "dollar placeholders", in: "INSERT INTO t VALUES ($1, $2, 3)", want: "INSERT INTO t VALUES ($1, $2, ?)"},
+		{name: "question placeholders", in: "SELECT * FROM t WHERE a = ? AND b = 7", want: "SELECT * FROM t WHERE a = ? AND b = ?"},
+		{name: "named placeholders", in: "SELECT * FROM t WHERE a = :p1 AND b = @p2", want: "SELECT * FROM t WHERE a = :p1 AND b = @p2"},
+		{name: "backslash escaped quote", system: "mysql", in: `SELECT 'a\'b secret', 'c'`, want: "SELECT ?, ?"},
+		{name: "escaped backslash", system: "mysql", in: `SELECT 'a\\', 2`, want: "SELECT ?, ?"},
+		{name: "postgres escape string", system: "postgresql", in: `SELECT E'a\'b secret', 'c'`, want: "SELECT E?, ?"},
+		{name: "postgres standard string", system: "postgresql", in: `SELECT 'C:\', 'x'`, want: "SELECT ?, ?"},
+		{name: "quoted identifier", system: "postgresql", in: `SELECT "col 1" FROM "t'1"`, want: `SELECT "col 1" FROM "t'1"`},
+		{name: "double-quoted string", system: "mysql", in: `SELECT * FROM t WHERE name = "alice"`, want: "SELECT * FROM t WHERE name = ?"},
+		{name: "double-quoted string escape", system: "mysql", in: `SELECT "a\"b secret", "c"`, want: "SELECT ?, ?"},
+		{name: "backtick identifier", in: "SELECT `col1` FROM `t`", want: "SELECT `col1` FROM `t`"},
+		{name: "dollar-quoted string", in: "SELECT $$it's secret$$", want: "SELECT ?"},
+		{name: "tagged dollar-quoted string", in: "SELECT $tag$a $$ b$tag$, $1", want: "SELECT ?, $1"},
+		{name: "unterminated dollar-quoted string", in: "SELECT $$secret", want: "SELECT ?"},
+		{name: "dollar in identifier", in: "SELECT a$b$ FROM t$1", want: "SELECT a$b$ FROM t$1"},
+		{name: "line comment", in: "SELECT 1 -- secret\nFROM t", want: "SELECT ? \nFROM t"},
+		{name: "unterminated line comment", in: "SELECT 1 -- secret", want: "SELECT ? "},
+		{name: "block comment", in: "SELECT /* secret */ 1", want: "SELECT  ?"},
+		{name: "unterminated block comment", in: "SELECT 1 /* secret", want: "SELECT ? "},
+		{name: "comment markers in string", in: "SELECT '--', '/*'", want: "SELECT ?, ?"},
+		{name: "IN list", in: "SELECT * FROM t WHERE id IN (1, 2, 3)", want: "SELECT * FROM t WHERE id IN (?, ?, ?)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitize(tt.in, tt.system)
+			difftest.AssertSame(t, "sanitize mismatch", tt.want, got)
+		})
+	}
+}
+
+func TestOperation(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: ""},
+		{in: "select 1", want: "SELECT"},
+		{in: "  \n INSERT INTO t VALUES (1)", want: "INSERT"},
+		{in: "(SELECT 1) UNION (SELECT 2)", want: "SELECT"},
+		{in: "-- comment", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			difftest.AssertSame(t, "operation mismatch", tt.want, operation(tt.in))
+		})
+	}
+}